@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/Karzoug/loyalty_program/internal/accrual"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type buildLoggerConfig interface {
+	IsDebugMode() bool
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	cfg, err := accrual.ReadConfig()
+	if err != nil {
+		log.Fatalf("Read config error: %s", err)
+	}
+
+	logger, err := buildLogger(cfg)
+	if err != nil {
+		log.Fatalf("Create logger error: %s", err)
+	}
+	defer logger.Sync()
+
+	server := accrual.New(cfg, logger)
+	if err := server.Run(ctx); err != nil {
+		logger.Error("Server shutdown failed", zap.Error(err))
+	}
+}
+
+func buildLogger(cfg buildLoggerConfig) (*zap.Logger, error) {
+	if cfg.IsDebugMode() {
+		config := zap.NewDevelopmentConfig()
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		return config.Build()
+	}
+	return zap.NewProduction()
+}
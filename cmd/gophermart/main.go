@@ -5,17 +5,24 @@ import (
 	"log"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Karzoug/loyalty_program/internal/config"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc"
 	"github.com/Karzoug/loyalty_program/internal/delivery/rest"
+	"github.com/Karzoug/loyalty_program/internal/metrics"
 	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage/postgresql"
 	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/Karzoug/loyalty_program/internal/tracing"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
 )
 
+// tracingShutdownTimeout is a time to export the remaining trace spans on exit.
+const tracingShutdownTimeout = 5 * time.Second
+
 type buildLoggerConfig interface {
 	IsDebugMode() bool
 }
@@ -35,14 +42,26 @@ func main() {
 	}
 	defer logger.Sync()
 
-	storages, err := postgresql.NewStorages(ctx, cfg)
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		logger.Fatal("Tracing setup error", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Tracing shutdown error", zap.Error(err))
+		}
+	}()
+
+	storages, err := postgresql.NewStorages(ctx, cfg, metrics.Registry)
 	if err != nil {
 		logger.Fatal("Database error", zap.Error(err))
 	}
 
 	proc := accrual.NewOrderProcessor(cfg, logger)
 
-	service := service.New(storages, proc, logger)
+	service := service.New(cfg, storages, proc, logger)
 
 	g, _ := errgroup.WithContext(ctx)
 
@@ -55,6 +74,26 @@ func main() {
 		return err
 	})
 
+	grpcServer := grpc.New(cfg, service, logger)
+	g.Go(func() error {
+		err := grpcServer.Run(ctx)
+		if err != nil {
+			logger.Error("gRPC server shutdown failed", zap.Error(err))
+		}
+		return err
+	})
+
+	if cfg.MetricsAddress() != "" {
+		metricsServer := metrics.NewServer(cfg.MetricsAddress(), logger)
+		g.Go(func() error {
+			err := metricsServer.Run(ctx)
+			if err != nil {
+				logger.Error("Metrics server shutdown failed", zap.Error(err))
+			}
+			return err
+		})
+	}
+
 	g.Go(func() error {
 		err := service.Run(ctx)
 		if err != nil {
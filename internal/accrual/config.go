@@ -0,0 +1,131 @@
+package accrual
+
+import (
+	"errors"
+	"flag"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/pkg/e"
+)
+
+const (
+	defaultRunAddress      = "localhost:8080"
+	defaultDatabaseURI     = ""
+	defaultDebug           = false
+	defaultRateLimit       = 0
+	defaultProcessingDelay = time.Second
+)
+
+type config struct {
+	runAddress      string
+	databaseURI     string
+	debug           bool
+	rateLimit       int
+	processingDelay time.Duration
+}
+
+// ReadConfig reads config values from (in order of priority): environment values, flags, defaults values.
+func ReadConfig() (*config, error) {
+	var c config
+
+	c.readFlags()
+	if err := c.readEnvs(); err != nil {
+		return nil, e.Wrap("read environment values", err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, e.Wrap("no valid config values", err)
+	}
+
+	return &c, nil
+}
+
+// RunAddress is a rest server address (host:port).
+func (c config) RunAddress() string {
+	return c.runAddress
+}
+
+// DatabaseURI is a database connection string. The stand-in keeps its data in memory,
+// the value is accepted only to be compatible with the original accrual system command line.
+func (c config) DatabaseURI() string {
+	return c.databaseURI
+}
+
+// IsDebugMode indicates whether the service is running in debug mode.
+func (c config) IsDebugMode() bool {
+	return c.debug
+}
+
+// RateLimit is a number of order requests per minute allowed (0 - no limit).
+func (c config) RateLimit() int {
+	return c.rateLimit
+}
+
+// ProcessingDelay is a duration the accrual calculation takes after an order is registered.
+func (c config) ProcessingDelay() time.Duration {
+	return c.processingDelay
+}
+
+func (c *config) readFlags() {
+	if flag.Parsed() {
+		return
+	}
+	flag.StringVar(&c.runAddress, "a", defaultRunAddress, "rest server host and port")
+	flag.StringVar(&c.databaseURI, "d", defaultDatabaseURI, "database connection string (not used, data is kept in memory)")
+	flag.BoolVar(&c.debug, "debug", defaultDebug, "debug mode")
+	flag.IntVar(&c.rateLimit, "l", defaultRateLimit, "order requests per minute allowed (0 - no limit)")
+	flag.DurationVar(&c.processingDelay, "delay", defaultProcessingDelay, "duration of the accrual calculation")
+
+	flag.Parse()
+}
+
+func (c *config) readEnvs() error {
+	if runAddressString, ok := os.LookupEnv("RUN_ADDRESS"); ok {
+		c.runAddress = runAddressString
+	}
+	if databaseURIString, ok := os.LookupEnv("DATABASE_URI"); ok {
+		c.databaseURI = databaseURIString
+	}
+	if debugString, ok := os.LookupEnv("DEBUG"); ok {
+		debugBool, err := strconv.ParseBool(debugString)
+		if err != nil {
+			return e.Wrap("parse variable 'DEBUG' error", err)
+		}
+		c.debug = debugBool
+	}
+	if rateLimitString, ok := os.LookupEnv("RATE_LIMIT"); ok {
+		rateLimit, err := strconv.Atoi(rateLimitString)
+		if err != nil {
+			return e.Wrap("parse variable 'RATE_LIMIT' error", err)
+		}
+		c.rateLimit = rateLimit
+	}
+	if processingDelayString, ok := os.LookupEnv("PROCESSING_DELAY"); ok {
+		processingDelay, err := time.ParseDuration(processingDelayString)
+		if err != nil {
+			return e.Wrap("parse variable 'PROCESSING_DELAY' error", err)
+		}
+		c.processingDelay = processingDelay
+	}
+
+	return nil
+}
+
+func (c *config) validate() error {
+	_, _, err := net.SplitHostPort(c.runAddress)
+	if err != nil {
+		return errors.New("rest server host and port have wrong format")
+	}
+
+	if c.rateLimit < 0 {
+		return errors.New("rate limit must be non negative")
+	}
+
+	if c.processingDelay < 0 {
+		return errors.New("processing delay must be non negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package accrual
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type orderRequest struct {
+	Order string  `json:"order"`
+	Goods []Goods `json:"goods"`
+}
+
+type orderResponse struct {
+	Order  string `json:"order"`
+	Status Status `json:"status"`
+	// Accrual is a raw decimal number to keep the exact value
+	// (encoding/json is used here as go-json does not omit an empty raw message).
+	Accrual json.RawMessage `json:"accrual,omitempty"`
+}
+
+func (s *server) registerRewardHandler(w http.ResponseWriter, r *http.Request) {
+	var req Reward
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch err := s.storage.AddReward(req); err {
+	case nil:
+		w.WriteHeader(http.StatusOK)
+	case ErrMatchAlreadyExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		s.logger.Error("Register reward handler: add reward error", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) registerOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var req orderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !isDigits(req.Order) {
+		http.Error(w, "order number must contain only digits", http.StatusBadRequest)
+		return
+	}
+	for _, g := range req.Goods {
+		if err := g.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch err := s.storage.AddOrder(req.Order, req.Goods); err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case ErrOrderAlreadyExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		s.logger.Error("Register order handler: add order error", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) getOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o, err := s.storage.GetOrder(chi.URLParam(r, "number"))
+	if err != nil {
+		if err == ErrOrderNotFound {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.logger.Error("Get order handler: get order error", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	resp := orderResponse{
+		Order:  o.Number,
+		Status: o.Status,
+	}
+	if o.Accrual.IsPositive() {
+		resp.Accrual = json.RawMessage(o.Accrual.String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Error("Get order handler: encode json response error", zap.Error(err))
+	}
+}
+
+func decodeJSON(r *http.Request, value any) error {
+	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		return errors.New("not valid content-type")
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(value); err != nil {
+		return errors.New("request body contains badly-formed JSON")
+	}
+
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package accrual
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const accrualScale = 2
+
+var (
+	ErrInvalidRewardType = errors.New("invalid reward type")
+	ErrInvalidReward     = errors.New("invalid reward")
+	ErrEmptyMatch        = errors.New("empty match string")
+	ErrInvalidGoods      = errors.New("invalid goods")
+)
+
+// RewardType is a way the reward is calculated.
+type RewardType string
+
+const (
+	// RewardTypePercent is a reward in percent of goods price.
+	RewardTypePercent RewardType = "%"
+	// RewardTypePoints is a fixed reward in points for each goods.
+	RewardTypePoints RewardType = "pt"
+)
+
+// Reward is a rule of accrual calculation for goods whose description contains the match string.
+type Reward struct {
+	Match      string          `json:"match"`
+	Reward     decimal.Decimal `json:"reward"`
+	RewardType RewardType      `json:"reward_type"`
+}
+
+// Validate checks the reward rule is correct.
+func (r Reward) Validate() error {
+	if r.Match == "" {
+		return ErrEmptyMatch
+	}
+	if !r.Reward.IsPositive() {
+		return ErrInvalidReward
+	}
+	switch r.RewardType {
+	case RewardTypePercent:
+		if r.Reward.GreaterThan(decimal.NewFromInt(100)) {
+			return ErrInvalidReward
+		}
+	case RewardTypePoints:
+	default:
+		return ErrInvalidRewardType
+	}
+	return nil
+}
+
+// Goods is an item of an order.
+type Goods struct {
+	Description string          `json:"description"`
+	Price       decimal.Decimal `json:"price"`
+}
+
+// Validate checks the goods item is correct.
+func (g Goods) Validate() error {
+	if g.Description == "" || g.Price.IsNegative() {
+		return ErrInvalidGoods
+	}
+	return nil
+}
+
+// calculateAccrual sums rewards for the goods: each goods item gets the reward of the first
+// (in order of registration) rule whose match string is contained in the goods description.
+func calculateAccrual(goods []Goods, rewards []Reward) decimal.Decimal {
+	accrual := decimal.Zero
+	for _, g := range goods {
+		for _, r := range rewards {
+			if !strings.Contains(g.Description, r.Match) {
+				continue
+			}
+			switch r.RewardType {
+			case RewardTypePercent:
+				accrual = accrual.Add(g.Price.Mul(r.Reward).Div(decimal.NewFromInt(100)))
+			case RewardTypePoints:
+				accrual = accrual.Add(r.Reward)
+			}
+			break
+		}
+	}
+	return accrual.Round(accrualScale)
+}
@@ -0,0 +1,149 @@
+package accrual
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/middleware"
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+type serverConfig interface {
+	RunAddress() string
+	RateLimit() int
+	ProcessingDelay() time.Duration
+}
+
+type server struct {
+	cfg     serverConfig
+	logger  *zap.Logger
+	storage *memoryStorage
+	limiter *windowLimiter
+
+	server *http.Server
+}
+
+func New(cfg serverConfig, logger *zap.Logger) *server {
+	return &server{
+		cfg:     cfg,
+		logger:  logger,
+		storage: newMemoryStorage(cfg.ProcessingDelay()),
+		limiter: newWindowLimiter(cfg.RateLimit(), time.Minute),
+
+		server: &http.Server{Addr: cfg.RunAddress()},
+	}
+}
+
+func (s *server) Run(ctx context.Context) error {
+	s.logger.Info("Running accrual http server", zap.String("address", s.cfg.RunAddress()))
+
+	s.server.Handler = s.newRouter()
+
+	go func() {
+		if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+			s.logger.Fatal("Server listen and serve error", zap.Error(err))
+		}
+	}()
+
+	<-ctx.Done()
+	s.logger.Info("Shutting down accrual http server")
+
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.server.Shutdown(ctxShutdown)
+}
+
+func (s *server) newRouter() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(s.logRequests)
+	r.Use(middleware.Recoverer(s.logger))
+
+	r.Post("/api/goods", s.registerRewardHandler)
+	r.Post("/api/orders", s.registerOrderHandler)
+	r.With(s.rateLimit).Get("/api/orders/{number}", s.getOrderHandler)
+
+	return r
+}
+
+// logRequests is a middleware that logs served requests. Unlike the gophermart logger it records no metrics,
+// so the stand-in running in the same process does not mix its traffic into the gophermart metrics.
+func (s *server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		t1 := time.Now()
+		defer func() {
+			s.logger.Info("served",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", ww.Status()),
+				zap.String("remoteAddr", r.RemoteAddr),
+				zap.Duration("latency", time.Since(t1)),
+				zap.Int("size", ww.BytesWritten()))
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// rateLimit is a middleware that rejects requests over the limit in the same way
+// the original accrual system does.
+func (s *server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := s.limiter.Allow(); !ok {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, "No more than %d requests per minute allowed", s.limiter.limit)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// windowLimiter allows no more than limit requests in a fixed time window (limit 0 - no limit).
+type windowLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow reports whether a request may happen now, if not it returns the duration
+// until the current window ends.
+func (l *windowLimiter) Allow() (time.Duration, bool) {
+	if l.limit == 0 {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.count = 0
+	}
+	if l.count >= l.limit {
+		return l.window - now.Sub(l.windowStart), false
+	}
+	l.count++
+
+	return 0, true
+}
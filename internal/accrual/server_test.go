@@ -0,0 +1,156 @@
+package accrual
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type mockConfig struct {
+	rateLimit       int
+	processingDelay time.Duration
+}
+
+func (c mockConfig) RunAddress() string {
+	return "localhost:0"
+}
+
+func (c mockConfig) RateLimit() int {
+	return c.rateLimit
+}
+
+func (c mockConfig) ProcessingDelay() time.Duration {
+	return c.processingDelay
+}
+
+func newTestServer(t *testing.T, cfg mockConfig) *httptest.Server {
+	s := New(cfg, zap.NewNop())
+	ts := httptest.NewServer(s.newRouter())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doRequest(t *testing.T, method, url, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(b)
+}
+
+func Test_calculateAccrual(t *testing.T) {
+	rewards := []Reward{
+		{Match: "Bork", Reward: decimal.NewFromInt(10), RewardType: RewardTypePercent},
+		{Match: "Kettle", Reward: decimal.NewFromInt(15), RewardType: RewardTypePoints},
+	}
+	tests := []struct {
+		name  string
+		goods []Goods
+		want  string
+	}{
+		{
+			name:  "no goods",
+			goods: nil,
+			want:  "0",
+		},
+		{
+			name:  "percent reward",
+			goods: []Goods{{Description: "Bork toaster", Price: decimal.RequireFromString("729.98")}},
+			want:  "73",
+		},
+		{
+			name: "fixed reward and unmatched goods",
+			goods: []Goods{
+				{Description: "Kettle", Price: decimal.NewFromInt(1000)},
+				{Description: "Spoon", Price: decimal.NewFromInt(100)},
+			},
+			want: "15",
+		},
+		{
+			name:  "first matched rule wins",
+			goods: []Goods{{Description: "Bork Kettle", Price: decimal.RequireFromString("0.55")}},
+			want:  "0.06",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calculateAccrual(tt.goods, rewards).String())
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Run("order lifecycle", func(t *testing.T) {
+		t.Parallel()
+
+		ts := newTestServer(t, mockConfig{processingDelay: 200 * time.Millisecond})
+
+		resp, _ := doRequest(t, http.MethodPost, ts.URL+"/api/goods", `{"match":"Bork","reward":10,"reward_type":"%"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = doRequest(t, http.MethodPost, ts.URL+"/api/goods", `{"match":"Bork","reward":5,"reward_type":"pt"}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp, _ = doRequest(t, http.MethodGet, ts.URL+"/api/orders/12345678903", "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp, _ = doRequest(t, http.MethodPost, ts.URL+"/api/orders", `{"order":"12345678903","goods":[{"description":"Bork","price":7000.5}]}`)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		resp, _ = doRequest(t, http.MethodPost, ts.URL+"/api/orders", `{"order":"12345678903","goods":[]}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp, body := doRequest(t, http.MethodGet, ts.URL+"/api/orders/12345678903", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"order":"12345678903","status":"REGISTERED"}`, body)
+
+		time.Sleep(250 * time.Millisecond)
+		resp, body = doRequest(t, http.MethodGet, ts.URL+"/api/orders/12345678903", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"order":"12345678903","status":"PROCESSED","accrual":700.05}`, body)
+	})
+
+	t.Run("invalid order", func(t *testing.T) {
+		t.Parallel()
+
+		ts := newTestServer(t, mockConfig{})
+
+		resp, _ := doRequest(t, http.MethodPost, ts.URL+"/api/orders", `{"order":"12a","goods":[]}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = doRequest(t, http.MethodPost, ts.URL+"/api/orders", `{"order":"12345678901","goods":[]}`)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		resp, body := doRequest(t, http.MethodGet, ts.URL+"/api/orders/12345678901", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"order":"12345678901","status":"INVALID"}`, body)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		t.Parallel()
+
+		ts := newTestServer(t, mockConfig{rateLimit: 2})
+
+		for i := 0; i < 2; i++ {
+			resp, _ := doRequest(t, http.MethodGet, ts.URL+"/api/orders/12345678903", "")
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		}
+
+		resp, body := doRequest(t, http.MethodGet, ts.URL+"/api/orders/12345678903", "")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+		assert.Equal(t, "No more than 2 requests per minute allowed", body)
+	})
+}
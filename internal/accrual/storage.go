@@ -0,0 +1,132 @@
+package accrual
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/pkg/luhn"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrOrderAlreadyExists = errors.New("order already exists")
+	ErrMatchAlreadyExists = errors.New("reward match already exists")
+)
+
+// Status is an accrual calculation status of an order.
+type Status string
+
+const (
+	StatusRegistered Status = "REGISTERED"
+	StatusProcessing Status = "PROCESSING"
+	StatusInvalid    Status = "INVALID"
+	StatusProcessed  Status = "PROCESSED"
+)
+
+// Order is an accrual calculation state of an order.
+type Order struct {
+	Number  string
+	Status  Status
+	Accrual decimal.Decimal
+}
+
+type storedOrder struct {
+	Order
+	goods        []Goods
+	registeredAt time.Time
+}
+
+// memoryStorage keeps reward rules and orders in memory.
+// The order status is moved forward lazily: the order is REGISTERED for the first half of
+// the processing delay, PROCESSING for the second one and then gets the final status.
+type memoryStorage struct {
+	processingDelay time.Duration
+
+	mu      sync.Mutex
+	rewards []Reward
+	orders  map[string]*storedOrder
+}
+
+func newMemoryStorage(processingDelay time.Duration) *memoryStorage {
+	return &memoryStorage{
+		processingDelay: processingDelay,
+		orders:          make(map[string]*storedOrder),
+	}
+}
+
+func (s *memoryStorage) AddReward(r Reward) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.rewards {
+		if existing.Match == r.Match {
+			return ErrMatchAlreadyExists
+		}
+	}
+	s.rewards = append(s.rewards, r)
+
+	return nil
+}
+
+func (s *memoryStorage) AddOrder(number string, goods []Goods) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.orders[number]; exists {
+		return ErrOrderAlreadyExists
+	}
+	s.orders[number] = &storedOrder{
+		Order: Order{
+			Number: number,
+			Status: StatusRegistered,
+		},
+		goods:        goods,
+		registeredAt: time.Now(),
+	}
+
+	return nil
+}
+
+func (s *memoryStorage) GetOrder(number string) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, exists := s.orders[number]
+	if !exists {
+		return Order{}, ErrOrderNotFound
+	}
+
+	if o.Status == StatusInvalid || o.Status == StatusProcessed {
+		return o.Order, nil
+	}
+
+	elapsed := time.Since(o.registeredAt)
+	switch {
+	case elapsed < s.processingDelay/2:
+	case elapsed < s.processingDelay:
+		o.Status = StatusProcessing
+	default:
+		// the rewards are applied at the end of the calculation,
+		// so rules registered after the order still count
+		if isValidNumber(o.Number) {
+			o.Status = StatusProcessed
+			o.Accrual = calculateAccrual(o.goods, s.rewards)
+		} else {
+			o.Status = StatusInvalid
+		}
+		o.goods = nil
+	}
+
+	return o.Order, nil
+}
+
+func isValidNumber(number string) bool {
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return false
+	}
+	return luhn.Valid(n)
+}
@@ -0,0 +1,121 @@
+// Package auth issues access tokens and reads their claims.
+// It does not depend on a transport: the rest and gRPC APIs share it, so they accept the same tokens.
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/Karzoug/loyalty_program/pkg/e"
+	"github.com/Karzoug/loyalty_program/pkg/jwtkeys"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+const (
+	// SessionIDClaim is a private JWT claim with the ID of the session the token is issued for.
+	SessionIDClaim = "sid"
+	// RolesClaim is a private JWT claim with the roles of the user the token is issued for.
+	RolesClaim = "roles"
+)
+
+// NewAccessToken issues an access token with the user role for the session.
+func NewAccessToken(keys *jwtkeys.KeySet, ss session.Session, role user.Role, lifetime time.Duration) (string, error) {
+	now := time.Now()
+
+	claims := make(map[string]interface{})
+	claims[jwt.SubjectKey] = string(ss.UserLogin)
+	claims[jwt.JwtIDKey] = uuid.NewString()
+	claims[jwt.IssuedAtKey] = now.Unix()
+	claims[jwt.ExpirationKey] = now.Add(lifetime).Unix()
+	claims[SessionIDClaim] = ss.ID
+	claims[RolesClaim] = []string{string(role)}
+
+	_, tokenString, err := keys.Encode(claims)
+	if err != nil {
+		return "", e.Wrap("encode JWT", err)
+	}
+	return tokenString, nil
+}
+
+// Token is a valid access token of a request.
+type Token struct {
+	ID        string
+	Login     user.Login
+	SessionID string
+	Roles     []user.Role
+	ExpiresAt time.Time
+}
+
+// ParseToken validates the decoded token and reads its claims.
+// Tokens without the ID and session claims are issued before sessions were introduced: their revocation
+// cannot be checked, so they are rejected and the users have to log in again.
+func ParseToken(token jwt.Token) (*Token, error) {
+	if token == nil || jwt.Validate(token) != nil {
+		return nil, service.ErrInvalidAuthData
+	}
+
+	sessionID, _ := privateClaim(token, SessionIDClaim).(string)
+	if token.Subject() == "" || token.JwtID() == "" || sessionID == "" {
+		return nil, service.ErrInvalidAuthData
+	}
+
+	values, _ := privateClaim(token, RolesClaim).([]interface{})
+	roles := make([]user.Role, 0, len(values))
+	for _, value := range values {
+		if name, ok := value.(string); ok {
+			roles = append(roles, user.Role(name))
+		}
+	}
+
+	return &Token{
+		ID:        token.JwtID(),
+		Login:     user.Login(token.Subject()),
+		SessionID: sessionID,
+		Roles:     roles,
+		ExpiresAt: token.Expiration(),
+	}, nil
+}
+
+func privateClaim(token jwt.Token, name string) interface{} {
+	value, _ := token.Get(name)
+	return value
+}
+
+// HasRole reports whether the token is issued for a user with the role.
+func (t Token) HasRole(role user.Role) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of the context with the access token of the request.
+func NewContext(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, ctxKey{}, token)
+}
+
+// FromContext returns the access token of the request, ErrInvalidAuthData if there is none.
+func FromContext(ctx context.Context) (*Token, error) {
+	token, ok := ctx.Value(ctxKey{}).(*Token)
+	if !ok || token == nil {
+		return nil, service.ErrInvalidAuthData
+	}
+	return token, nil
+}
+
+// LoginFromContext returns the login of the user the access token of the request is issued for.
+func LoginFromContext(ctx context.Context) (*user.Login, error) {
+	token, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &token.Login, nil
+}
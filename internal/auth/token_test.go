@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/Karzoug/loyalty_program/pkg/jwtkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseToken(t *testing.T) {
+	t.Parallel()
+
+	keys, err := jwtkeys.NewHMAC([]byte("secret"))
+	require.NoError(t, err)
+	ss := session.Session{ID: "session-id", UserLogin: "login"}
+
+	t.Run("issued token", func(t *testing.T) {
+		t.Parallel()
+
+		tokenString, err := NewAccessToken(keys, ss, user.RoleAdmin, time.Minute)
+		require.NoError(t, err)
+		jwtToken, err := keys.Decode(tokenString)
+		require.NoError(t, err)
+
+		token, err := ParseToken(jwtToken)
+		require.NoError(t, err)
+		assert.NotEmpty(t, token.ID)
+		assert.Equal(t, ss.UserLogin, token.Login)
+		assert.Equal(t, ss.ID, token.SessionID)
+		assert.True(t, token.HasRole(user.RoleAdmin))
+		assert.WithinDuration(t, time.Now().Add(time.Minute), token.ExpiresAt, 5*time.Second)
+
+		fromCtx, err := FromContext(NewContext(context.Background(), token))
+		require.NoError(t, err)
+		assert.Equal(t, token, fromCtx)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		t.Parallel()
+
+		tokenString, err := NewAccessToken(keys, ss, user.RoleUser, -time.Minute)
+		require.NoError(t, err)
+		jwtToken, err := keys.Decode(tokenString)
+		require.NoError(t, err)
+
+		_, err = ParseToken(jwtToken)
+		assert.ErrorIs(t, err, service.ErrInvalidAuthData)
+	})
+
+	t.Run("token without session", func(t *testing.T) {
+		t.Parallel()
+
+		jwtToken, _, err := keys.Encode(map[string]interface{}{"sub": "login", "jti": "id"})
+		require.NoError(t, err)
+
+		_, err = ParseToken(jwtToken)
+		assert.ErrorIs(t, err, service.ErrInvalidAuthData)
+	})
+
+	t.Run("no token in context", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := FromContext(context.Background())
+		assert.ErrorIs(t, err, service.ErrInvalidAuthData)
+	})
+}
@@ -3,29 +3,109 @@ package config
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"math"
 	"net"
+	"net/netip"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
+	"github.com/Karzoug/loyalty_program/internal/model/money"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/tracing"
 	"github.com/Karzoug/loyalty_program/pkg/e"
+	"github.com/Karzoug/loyalty_program/pkg/jwtkeys"
+	"github.com/Karzoug/loyalty_program/pkg/realip"
 )
 
 const (
-	defaultRunAddress           = "localhost:8081"
-	defaultAccrualSystemAddress = "http://localhost:8080"
-	defaultDatabaseURI          = ""
-	defaultSecretKey            = ""
-	defaultDebug                = false
+	defaultRunAddress            = "localhost:8081"
+	defaultGRPCAddress           = "localhost:3200"
+	defaultMetricsAddress        = "localhost:9464"
+	defaultAccrualSystemAddress  = "http://localhost:8080"
+	defaultDatabaseURI           = ""
+	defaultSecretKey             = ""
+	defaultDebug                 = false
+	defaultAdminLogins           = ""
+	defaultDeadLetterMaxAttempts = 20
+	defaultDeadLetterMaxAge      = 72 * time.Hour
+	defaultBreakerFailures       = 5
+	defaultBreakerOpenTimeout    = 30 * time.Second
+	defaultBreakerHalfOpenLimit  = 1
+	defaultMoneyScale            = 2
+	defaultMoneyRounding         = "half-up"
+	defaultAmountsAsStrings      = false
+	defaultAccessTokenLifetime   = 15 * time.Minute
+	defaultRefreshTokenLifetime  = 30 * 24 * time.Hour
+	defaultJWTAlgorithm          = jwtkeys.AlgHS256
+	defaultJWTKeyDir             = ""
+	defaultJWTSigningKeyID       = ""
+	defaultLoginMaxAttempts      = 5
+	defaultLoginIPMaxAttempts    = 50
+	defaultLoginLockoutBase      = time.Second
+	defaultLoginLockoutMax       = 15 * time.Minute
+	defaultPasswordHash          = user.HashBcrypt
+	defaultBcryptCost            = 10
+	defaultArgon2Time            = 2
+	defaultArgon2Memory          = 19 * 1024
+	defaultArgon2Threads         = 1
+	defaultShutdownDelay         = 5 * time.Second
+	defaultTrustedProxies        = ""
+	defaultOpenAPIValidation     = false
+	defaultTraceExporter         = tracing.ExporterNone
+	defaultTraceOTLPEndpoint     = "localhost:4318"
+	defaultTraceOTLPInsecure     = true
 )
 
 type config struct {
 	runAddress                 string
+	grpcAddress                string
+	metricsAddress             string
 	accrualSystemAddressString string
 	accrualSystemAddressURL    url.URL
 	databaseURI                string
 	secretKey                  string
 	debug                      bool
+	adminLoginsString          string
+	adminLogins                []string
+	deadLetterMaxAttempts      int
+	deadLetterMaxAge           time.Duration
+	breakerFailures            int
+	breakerOpenTimeout         time.Duration
+	breakerHalfOpenLimit       int
+	moneyScale                 int
+	moneyRoundingString        string
+	moneyPolicy                money.Policy
+	amountsAsStrings           bool
+	accessTokenLifetime        time.Duration
+	refreshTokenLifetime       time.Duration
+	jwtAlgorithm               string
+	jwtKeyDir                  string
+	jwtSigningKeyID            string
+	jwtKeys                    *jwtkeys.KeySet
+	loginMaxAttempts           int
+	loginIPMaxAttempts         int
+	loginLockoutBase           time.Duration
+	loginLockoutMax            time.Duration
+	loginLockoutPolicy         lockout.Policy
+	loginIPLockoutPolicy       lockout.Policy
+	passwordHash               string
+	bcryptCost                 int
+	argon2Time                 uint
+	argon2Memory               uint
+	argon2Threads              uint
+	passwordPolicy             user.PasswordPolicy
+	shutdownDelay              time.Duration
+	trustedProxiesString       string
+	trustedProxies             []netip.Prefix
+	openAPIValidation          bool
+	traceExporter              string
+	traceOTLPEndpoint          string
+	traceOTLPInsecure          bool
 }
 
 // Read reads config values from (in order of priority): environment values, flags, defaults values.
@@ -48,6 +128,17 @@ func (c config) RunAddress() string {
 	return c.runAddress
 }
 
+// GRPCAddress is a gRPC server address (host:port).
+func (c config) GRPCAddress() string {
+	return c.grpcAddress
+}
+
+// MetricsAddress is a Prometheus metrics server address (host:port), empty - the metrics are not served.
+// The metrics are served apart from the public API listeners.
+func (c config) MetricsAddress() string {
+	return c.metricsAddress
+}
+
 // AccrualSystemAddress is an accrual system address URL.
 func (c config) AccrualSystemAddress() url.URL {
 	return c.accrualSystemAddressURL
@@ -58,25 +149,165 @@ func (c config) DatabaseURI() string {
 	return c.databaseURI
 }
 
-// SecretKey is a key to create a JWT signature.
+// SecretKey is a key to create a JWT signature with HS256 algorithm.
 func (c config) SecretKey() string {
 	return c.secretKey
 }
 
+// JWTKeys are keys to sign and verify JWTs: the HS256 secret key
+// or RS256/EdDSA keys loaded from the key directory.
+func (c config) JWTKeys() *jwtkeys.KeySet {
+	return c.jwtKeys
+}
+
 // IsDebugMode indicates whether the service is running in debug mode.
 func (c config) IsDebugMode() bool {
 	return c.debug
 }
 
+// AdminLogins are logins of users granted the admin role when tokens are issued on login or refresh,
+// the role is revoked from the users whose logins are removed.
+func (c config) AdminLogins() []string {
+	return c.adminLogins
+}
+
+// DeadLetterMaxAttempts is a number of attempts to process an order not registered in accrual system
+// before the order is dead-lettered (0 - no limit).
+func (c config) DeadLetterMaxAttempts() int {
+	return c.deadLetterMaxAttempts
+}
+
+// DeadLetterMaxAge is a max age of an order not registered in accrual system
+// before the order is dead-lettered (0 - no limit).
+func (c config) DeadLetterMaxAge() time.Duration {
+	return c.deadLetterMaxAge
+}
+
+// AccrualBreakerFailureThreshold is a number of consecutive failed requests to accrual system
+// after which the circuit breaker opens.
+func (c config) AccrualBreakerFailureThreshold() int {
+	return c.breakerFailures
+}
+
+// AccrualBreakerOpenTimeout is a duration the circuit breaker stays open before trial requests.
+func (c config) AccrualBreakerOpenTimeout() time.Duration {
+	return c.breakerOpenTimeout
+}
+
+// AccrualBreakerHalfOpenRequests is a number of trial requests that must succeed to close the circuit breaker.
+func (c config) AccrualBreakerHalfOpenRequests() int {
+	return c.breakerHalfOpenLimit
+}
+
+// MoneyPolicy is a scale and a rounding mode of points amounts.
+func (c config) MoneyPolicy() money.Policy {
+	return c.moneyPolicy
+}
+
+// AmountsAsStrings indicates whether the rest server returns points amounts as decimal strings instead of numbers.
+func (c config) AmountsAsStrings() bool {
+	return c.amountsAsStrings
+}
+
+// AccessTokenLifetime is a lifetime of a JWT used to access the API.
+func (c config) AccessTokenLifetime() time.Duration {
+	return c.accessTokenLifetime
+}
+
+// RefreshTokenLifetime is a lifetime of a refresh token used to get a new access token,
+// the lifetime starts over on each refresh.
+func (c config) RefreshTokenLifetime() time.Duration {
+	return c.refreshTokenLifetime
+}
+
+// LoginLockoutPolicy is a lockout of failed attempts to log in as a user.
+func (c config) LoginLockoutPolicy() lockout.Policy {
+	return c.loginLockoutPolicy
+}
+
+// LoginIPLockoutPolicy is a lockout of failed login attempts from a client IP.
+func (c config) LoginIPLockoutPolicy() lockout.Policy {
+	return c.loginIPLockoutPolicy
+}
+
+// PasswordPolicy is an algorithm and parameters of new password hashes.
+func (c config) PasswordPolicy() user.PasswordPolicy {
+	return c.passwordPolicy
+}
+
+// OpenAPIValidation indicates whether the rest server rejects requests not matching the OpenAPI specification,
+// in debug mode responses are validated too.
+func (c config) OpenAPIValidation() bool {
+	return c.openAPIValidation
+}
+
+// ShutdownDelay is a time between the server reports it is not ready and it stops accepting connections,
+// so the orchestrator stops routing requests to the server before they are refused.
+func (c config) ShutdownDelay() time.Duration {
+	return c.shutdownDelay
+}
+
+// TrustedProxies are networks of proxies the client IP is taken from the X-Forwarded-For header behind
+// (empty - the peer address is the client IP).
+func (c config) TrustedProxies() []netip.Prefix {
+	return c.trustedProxies
+}
+
+// TraceExporter is an exporter of trace spans: none, stdout or otlp.
+func (c config) TraceExporter() string {
+	return c.traceExporter
+}
+
+// TraceOTLPEndpoint is an OTLP/HTTP collector address (host:port).
+func (c config) TraceOTLPEndpoint() string {
+	return c.traceOTLPEndpoint
+}
+
+// TraceOTLPInsecure reports whether spans are sent to the OTLP collector without TLS.
+func (c config) TraceOTLPInsecure() bool {
+	return c.traceOTLPInsecure
+}
+
 func (c *config) readFlags() {
 	if flag.Parsed() {
 		return
 	}
 	flag.StringVar(&c.runAddress, "a", defaultRunAddress, "rest server host and port")
+	flag.StringVar(&c.grpcAddress, "g", defaultGRPCAddress, "gRPC server host and port")
+	flag.StringVar(&c.metricsAddress, "metrics-address", defaultMetricsAddress, "Prometheus metrics server host and port (empty - metrics are not served)")
 	flag.StringVar(&c.accrualSystemAddressString, "r", defaultAccrualSystemAddress, "accrual system address (incl.scheme)")
 	flag.StringVar(&c.databaseURI, "d", defaultDatabaseURI, "database connection string")
-	flag.StringVar(&c.secretKey, "k", defaultSecretKey, "key to create a JWT signature")
+	flag.StringVar(&c.secretKey, "k", defaultSecretKey, "key to create a JWT signature (HS256 only)")
+	flag.StringVar(&c.jwtAlgorithm, "jwt-alg", defaultJWTAlgorithm, "JWT signing algorithm (HS256, RS256, EdDSA)")
+	flag.StringVar(&c.jwtKeyDir, "jwt-keys", defaultJWTKeyDir, "directory of JWT signing and verification PEM keys named <kid>.pem (RS256, EdDSA only)")
+	flag.StringVar(&c.jwtSigningKeyID, "jwt-kid", defaultJWTSigningKeyID, "id of the key signing JWTs (empty - the private key with the greatest id, digits compared as numbers)")
 	flag.BoolVar(&c.debug, "debug", defaultDebug, "debug mode")
+	flag.StringVar(&c.adminLoginsString, "admins", defaultAdminLogins, "comma separated logins of users granted the admin role on login")
+	flag.IntVar(&c.deadLetterMaxAttempts, "dl-attempts", defaultDeadLetterMaxAttempts, "attempts to process an unregistered order before it is dead-lettered (0 - no limit)")
+	flag.DurationVar(&c.deadLetterMaxAge, "dl-age", defaultDeadLetterMaxAge, "max age of an unregistered order before it is dead-lettered (0 - no limit)")
+	flag.IntVar(&c.breakerFailures, "breaker-failures", defaultBreakerFailures, "consecutive failed accrual system requests to open the circuit breaker")
+	flag.DurationVar(&c.breakerOpenTimeout, "breaker-timeout", defaultBreakerOpenTimeout, "duration the accrual system circuit breaker stays open")
+	flag.IntVar(&c.breakerHalfOpenLimit, "breaker-half-open", defaultBreakerHalfOpenLimit, "trial accrual system requests to close the circuit breaker")
+	flag.IntVar(&c.moneyScale, "money-scale", defaultMoneyScale, "decimal places of points amounts")
+	flag.StringVar(&c.moneyRoundingString, "money-rounding", defaultMoneyRounding, "rounding mode of points amounts (half-up, half-even, down)")
+	flag.BoolVar(&c.amountsAsStrings, "amounts-as-strings", defaultAmountsAsStrings, "return points amounts as decimal strings")
+	flag.DurationVar(&c.accessTokenLifetime, "access-ttl", defaultAccessTokenLifetime, "lifetime of an access token")
+	flag.DurationVar(&c.refreshTokenLifetime, "refresh-ttl", defaultRefreshTokenLifetime, "lifetime of a refresh token")
+	flag.IntVar(&c.loginMaxAttempts, "login-attempts", defaultLoginMaxAttempts, "failed attempts to log in as a user before it is locked out")
+	flag.IntVar(&c.loginIPMaxAttempts, "login-ip-attempts", defaultLoginIPMaxAttempts, "failed login attempts from a client IP before it is locked out")
+	flag.DurationVar(&c.loginLockoutBase, "login-lockout-base", defaultLoginLockoutBase, "first login lockout duration, it doubles with each next failure")
+	flag.DurationVar(&c.loginLockoutMax, "login-lockout-max", defaultLoginLockoutMax, "max login lockout duration")
+	flag.StringVar(&c.passwordHash, "password-hash", defaultPasswordHash, "password hashing algorithm (bcrypt, argon2id)")
+	flag.IntVar(&c.bcryptCost, "bcrypt-cost", defaultBcryptCost, "bcrypt cost of password hashes")
+	flag.UintVar(&c.argon2Time, "argon2-time", defaultArgon2Time, "argon2id iterations of password hashes")
+	flag.UintVar(&c.argon2Memory, "argon2-memory", defaultArgon2Memory, "argon2id memory of password hashes in KiB")
+	flag.UintVar(&c.argon2Threads, "argon2-threads", defaultArgon2Threads, "argon2id parallelism of password hashes")
+	flag.DurationVar(&c.shutdownDelay, "shutdown-delay", defaultShutdownDelay, "time the server reports it is not ready before it stops accepting connections")
+	flag.StringVar(&c.trustedProxiesString, "trusted-proxies", defaultTrustedProxies, "comma separated IPs and CIDRs of proxies trusted to set X-Forwarded-For")
+	flag.BoolVar(&c.openAPIValidation, "openapi-validation", defaultOpenAPIValidation, "validate requests (and responses in debug mode) against the OpenAPI specification")
+	flag.StringVar(&c.traceExporter, "trace-exporter", defaultTraceExporter, "exporter of trace spans (none, stdout, otlp)")
+	flag.StringVar(&c.traceOTLPEndpoint, "trace-otlp-endpoint", defaultTraceOTLPEndpoint, "OTLP/HTTP trace collector host and port")
+	flag.BoolVar(&c.traceOTLPInsecure, "trace-otlp-insecure", defaultTraceOTLPInsecure, "send trace spans to the OTLP collector without TLS")
 
 	flag.Parse()
 }
@@ -85,6 +316,12 @@ func (c *config) readEnvs() error {
 	if runAddressString, ok := os.LookupEnv("RUN_ADDRESS"); ok {
 		c.runAddress = runAddressString
 	}
+	if grpcAddressString, ok := os.LookupEnv("GRPC_ADDRESS"); ok {
+		c.grpcAddress = grpcAddressString
+	}
+	if metricsAddressString, ok := os.LookupEnv("METRICS_ADDRESS"); ok {
+		c.metricsAddress = metricsAddressString
+	}
 	if accrualSystemAddressString, ok := os.LookupEnv("ACCRUAL_SYSTEM_ADDRESS"); ok {
 		c.accrualSystemAddressString = accrualSystemAddressString
 	}
@@ -94,6 +331,15 @@ func (c *config) readEnvs() error {
 	if secretKeyString, ok := os.LookupEnv("SECRET_KEY"); ok {
 		c.secretKey = secretKeyString
 	}
+	if jwtAlgorithmString, ok := os.LookupEnv("JWT_ALGORITHM"); ok {
+		c.jwtAlgorithm = jwtAlgorithmString
+	}
+	if jwtKeyDirString, ok := os.LookupEnv("JWT_KEY_DIR"); ok {
+		c.jwtKeyDir = jwtKeyDirString
+	}
+	if jwtSigningKeyIDString, ok := os.LookupEnv("JWT_SIGNING_KEY_ID"); ok {
+		c.jwtSigningKeyID = jwtSigningKeyIDString
+	}
 	if debugString, ok := os.LookupEnv("DEBUG"); ok {
 		debugBool, err := strconv.ParseBool(debugString)
 		if err != nil {
@@ -101,6 +347,164 @@ func (c *config) readEnvs() error {
 		}
 		c.debug = debugBool
 	}
+	if adminLoginsString, ok := os.LookupEnv("ADMIN_LOGINS"); ok {
+		c.adminLoginsString = adminLoginsString
+	}
+	if deadLetterMaxAttemptsString, ok := os.LookupEnv("DEAD_LETTER_MAX_ATTEMPTS"); ok {
+		deadLetterMaxAttempts, err := strconv.Atoi(deadLetterMaxAttemptsString)
+		if err != nil {
+			return e.Wrap("parse variable 'DEAD_LETTER_MAX_ATTEMPTS' error", err)
+		}
+		c.deadLetterMaxAttempts = deadLetterMaxAttempts
+	}
+	if deadLetterMaxAgeString, ok := os.LookupEnv("DEAD_LETTER_MAX_AGE"); ok {
+		deadLetterMaxAge, err := time.ParseDuration(deadLetterMaxAgeString)
+		if err != nil {
+			return e.Wrap("parse variable 'DEAD_LETTER_MAX_AGE' error", err)
+		}
+		c.deadLetterMaxAge = deadLetterMaxAge
+	}
+	if breakerFailuresString, ok := os.LookupEnv("ACCRUAL_BREAKER_FAILURE_THRESHOLD"); ok {
+		breakerFailures, err := strconv.Atoi(breakerFailuresString)
+		if err != nil {
+			return e.Wrap("parse variable 'ACCRUAL_BREAKER_FAILURE_THRESHOLD' error", err)
+		}
+		c.breakerFailures = breakerFailures
+	}
+	if breakerOpenTimeoutString, ok := os.LookupEnv("ACCRUAL_BREAKER_OPEN_TIMEOUT"); ok {
+		breakerOpenTimeout, err := time.ParseDuration(breakerOpenTimeoutString)
+		if err != nil {
+			return e.Wrap("parse variable 'ACCRUAL_BREAKER_OPEN_TIMEOUT' error", err)
+		}
+		c.breakerOpenTimeout = breakerOpenTimeout
+	}
+	if breakerHalfOpenLimitString, ok := os.LookupEnv("ACCRUAL_BREAKER_HALF_OPEN_REQUESTS"); ok {
+		breakerHalfOpenLimit, err := strconv.Atoi(breakerHalfOpenLimitString)
+		if err != nil {
+			return e.Wrap("parse variable 'ACCRUAL_BREAKER_HALF_OPEN_REQUESTS' error", err)
+		}
+		c.breakerHalfOpenLimit = breakerHalfOpenLimit
+	}
+	if moneyScaleString, ok := os.LookupEnv("MONEY_SCALE"); ok {
+		moneyScale, err := strconv.Atoi(moneyScaleString)
+		if err != nil {
+			return e.Wrap("parse variable 'MONEY_SCALE' error", err)
+		}
+		c.moneyScale = moneyScale
+	}
+	if moneyRoundingString, ok := os.LookupEnv("MONEY_ROUNDING"); ok {
+		c.moneyRoundingString = moneyRoundingString
+	}
+	if amountsAsStringsString, ok := os.LookupEnv("AMOUNTS_AS_STRINGS"); ok {
+		amountsAsStrings, err := strconv.ParseBool(amountsAsStringsString)
+		if err != nil {
+			return e.Wrap("parse variable 'AMOUNTS_AS_STRINGS' error", err)
+		}
+		c.amountsAsStrings = amountsAsStrings
+	}
+	if accessTokenLifetimeString, ok := os.LookupEnv("ACCESS_TOKEN_LIFETIME"); ok {
+		accessTokenLifetime, err := time.ParseDuration(accessTokenLifetimeString)
+		if err != nil {
+			return e.Wrap("parse variable 'ACCESS_TOKEN_LIFETIME' error", err)
+		}
+		c.accessTokenLifetime = accessTokenLifetime
+	}
+	if refreshTokenLifetimeString, ok := os.LookupEnv("REFRESH_TOKEN_LIFETIME"); ok {
+		refreshTokenLifetime, err := time.ParseDuration(refreshTokenLifetimeString)
+		if err != nil {
+			return e.Wrap("parse variable 'REFRESH_TOKEN_LIFETIME' error", err)
+		}
+		c.refreshTokenLifetime = refreshTokenLifetime
+	}
+	if loginMaxAttemptsString, ok := os.LookupEnv("LOGIN_MAX_ATTEMPTS"); ok {
+		loginMaxAttempts, err := strconv.Atoi(loginMaxAttemptsString)
+		if err != nil {
+			return e.Wrap("parse variable 'LOGIN_MAX_ATTEMPTS' error", err)
+		}
+		c.loginMaxAttempts = loginMaxAttempts
+	}
+	if loginIPMaxAttemptsString, ok := os.LookupEnv("LOGIN_IP_MAX_ATTEMPTS"); ok {
+		loginIPMaxAttempts, err := strconv.Atoi(loginIPMaxAttemptsString)
+		if err != nil {
+			return e.Wrap("parse variable 'LOGIN_IP_MAX_ATTEMPTS' error", err)
+		}
+		c.loginIPMaxAttempts = loginIPMaxAttempts
+	}
+	if loginLockoutBaseString, ok := os.LookupEnv("LOGIN_LOCKOUT_BASE"); ok {
+		loginLockoutBase, err := time.ParseDuration(loginLockoutBaseString)
+		if err != nil {
+			return e.Wrap("parse variable 'LOGIN_LOCKOUT_BASE' error", err)
+		}
+		c.loginLockoutBase = loginLockoutBase
+	}
+	if loginLockoutMaxString, ok := os.LookupEnv("LOGIN_LOCKOUT_MAX"); ok {
+		loginLockoutMax, err := time.ParseDuration(loginLockoutMaxString)
+		if err != nil {
+			return e.Wrap("parse variable 'LOGIN_LOCKOUT_MAX' error", err)
+		}
+		c.loginLockoutMax = loginLockoutMax
+	}
+	if passwordHash, ok := os.LookupEnv("PASSWORD_HASH"); ok {
+		c.passwordHash = passwordHash
+	}
+	if bcryptCostString, ok := os.LookupEnv("BCRYPT_COST"); ok {
+		bcryptCost, err := strconv.Atoi(bcryptCostString)
+		if err != nil {
+			return e.Wrap("parse variable 'BCRYPT_COST' error", err)
+		}
+		c.bcryptCost = bcryptCost
+	}
+	if argon2TimeString, ok := os.LookupEnv("ARGON2_TIME"); ok {
+		argon2Time, err := strconv.ParseUint(argon2TimeString, 10, 32)
+		if err != nil {
+			return e.Wrap("parse variable 'ARGON2_TIME' error", err)
+		}
+		c.argon2Time = uint(argon2Time)
+	}
+	if argon2MemoryString, ok := os.LookupEnv("ARGON2_MEMORY"); ok {
+		argon2Memory, err := strconv.ParseUint(argon2MemoryString, 10, 32)
+		if err != nil {
+			return e.Wrap("parse variable 'ARGON2_MEMORY' error", err)
+		}
+		c.argon2Memory = uint(argon2Memory)
+	}
+	if argon2ThreadsString, ok := os.LookupEnv("ARGON2_THREADS"); ok {
+		argon2Threads, err := strconv.ParseUint(argon2ThreadsString, 10, 8)
+		if err != nil {
+			return e.Wrap("parse variable 'ARGON2_THREADS' error", err)
+		}
+		c.argon2Threads = uint(argon2Threads)
+	}
+	if trustedProxiesString, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		c.trustedProxiesString = trustedProxiesString
+	}
+	if shutdownDelayString, ok := os.LookupEnv("SHUTDOWN_DELAY"); ok {
+		shutdownDelay, err := time.ParseDuration(shutdownDelayString)
+		if err != nil {
+			return e.Wrap("parse variable 'SHUTDOWN_DELAY' error", err)
+		}
+		c.shutdownDelay = shutdownDelay
+	}
+	if openAPIValidationString, ok := os.LookupEnv("OPENAPI_VALIDATION"); ok {
+		openAPIValidation, err := strconv.ParseBool(openAPIValidationString)
+		if err != nil {
+			return e.Wrap("parse variable 'OPENAPI_VALIDATION' error", err)
+		}
+		c.openAPIValidation = openAPIValidation
+	}
+	if traceExporter, ok := os.LookupEnv("TRACE_EXPORTER"); ok {
+		c.traceExporter = traceExporter
+	}
+	if traceOTLPEndpoint, ok := os.LookupEnv("TRACE_OTLP_ENDPOINT"); ok {
+		c.traceOTLPEndpoint = traceOTLPEndpoint
+	}
+	if traceOTLPInsecureString, ok := os.LookupEnv("TRACE_OTLP_INSECURE"); ok {
+		traceOTLPInsecure, err := strconv.ParseBool(traceOTLPInsecureString)
+		if err != nil {
+			return e.Wrap("parse variable 'TRACE_OTLP_INSECURE' error", err)
+		}
+		c.traceOTLPInsecure = traceOTLPInsecure
+	}
 
 	return nil
 }
@@ -110,6 +514,16 @@ func (c *config) validate() error {
 	if err != nil {
 		return errors.New("rest server host and port have wrong format")
 	}
+	_, _, err = net.SplitHostPort(c.grpcAddress)
+	if err != nil {
+		return errors.New("gRPC server host and port have wrong format")
+	}
+	if c.metricsAddress != "" {
+		_, _, err = net.SplitHostPort(c.metricsAddress)
+		if err != nil {
+			return errors.New("metrics server host and port have wrong format")
+		}
+	}
 
 	u, err := url.ParseRequestURI(c.accrualSystemAddressString)
 	if err != nil || u.Scheme == "" || u.Host == "" {
@@ -121,9 +535,91 @@ func (c *config) validate() error {
 		return errors.New("database connection string must be non empty")
 	}
 
-	if c.secretKey == "" {
-		return errors.New("secret key must be non empty")
+	switch c.jwtAlgorithm {
+	case jwtkeys.AlgHS256:
+		if c.secretKey == "" {
+			return errors.New("secret key must be non empty")
+		}
+		c.jwtKeys, err = jwtkeys.NewHMAC([]byte(c.secretKey))
+	case jwtkeys.AlgRS256, jwtkeys.AlgEdDSA:
+		if c.jwtKeyDir == "" {
+			return fmt.Errorf("JWT key directory must be non empty for %s", c.jwtAlgorithm)
+		}
+		c.jwtKeys, err = jwtkeys.LoadDir(c.jwtAlgorithm, c.jwtKeyDir, c.jwtSigningKeyID)
+	default:
+		return fmt.Errorf("JWT signing algorithm must be one of %s, %s, %s", jwtkeys.AlgHS256, jwtkeys.AlgRS256, jwtkeys.AlgEdDSA)
+	}
+	if err != nil {
+		return e.Wrap("load JWT keys", err)
+	}
+
+	c.adminLogins = c.adminLogins[:0]
+	for _, login := range strings.Split(c.adminLoginsString, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			c.adminLogins = append(c.adminLogins, login)
+		}
+	}
+
+	if c.deadLetterMaxAttempts < 0 || c.deadLetterMaxAge < 0 {
+		return errors.New("dead letter max attempts and max age must be non negative")
+	}
+
+	if c.breakerFailures <= 0 || c.breakerOpenTimeout <= 0 || c.breakerHalfOpenLimit <= 0 {
+		return errors.New("accrual system circuit breaker thresholds must be positive")
+	}
+
+	if c.accessTokenLifetime <= 0 || c.refreshTokenLifetime <= 0 {
+		return errors.New("access and refresh token lifetimes must be positive")
+	}
+
+	c.loginLockoutPolicy, err = lockout.NewPolicy(c.loginMaxAttempts, c.loginLockoutBase, c.loginLockoutMax)
+	if err != nil {
+		return e.Wrap("login lockout", err)
+	}
+	c.loginIPLockoutPolicy, err = lockout.NewPolicy(c.loginIPMaxAttempts, c.loginLockoutBase, c.loginLockoutMax)
+	if err != nil {
+		return e.Wrap("login IP lockout", err)
+	}
+
+	if c.argon2Time > math.MaxUint32 || c.argon2Memory > math.MaxUint32 || c.argon2Threads > math.MaxUint8 {
+		return errors.New("argon2id parameters are out of range")
+	}
+	c.passwordPolicy, err = user.NewPasswordPolicy(c.passwordHash, c.bcryptCost, user.Argon2Params{
+		Time:    uint32(c.argon2Time),
+		Memory:  uint32(c.argon2Memory),
+		Threads: uint8(c.argon2Threads),
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.shutdownDelay < 0 {
+		return errors.New("shutdown delay must be non negative")
+	}
+
+	c.trustedProxies, err = realip.ParseTrusted(c.trustedProxiesString)
+	if err != nil {
+		return e.Wrap("trusted proxies have wrong format", err)
+	}
+
+	switch c.traceExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterOTLP:
+		if _, _, err := net.SplitHostPort(c.traceOTLPEndpoint); err != nil {
+			return errors.New("OTLP trace collector host and port have wrong format")
+		}
+	default:
+		return tracing.ErrUnknownExporter
+	}
+
+	rounding, err := money.ParseRounding(c.moneyRoundingString)
+	if err != nil {
+		return e.Wrap("money rounding mode has wrong format", err)
+	}
+	if c.moneyScale < 0 || c.moneyScale > money.MaxScale {
+		return fmt.Errorf("money scale must be in [0; %d]", money.MaxScale)
 	}
+	c.moneyPolicy, _ = money.NewPolicy(int32(c.moneyScale), rounding)
 
 	return nil
 }
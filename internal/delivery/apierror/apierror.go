@@ -0,0 +1,130 @@
+// Package apierror classifies the errors the APIs report to clients. It is the single place the errors
+// are classified: each known error has a stable name and a kind, the rest and gRPC servers map the kinds
+// to their response codes.
+package apierror
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/service"
+)
+
+// Kind is a transport independent kind of an error.
+type Kind int
+
+const (
+	// KindInternal is a kind of unknown errors, they are not described to clients.
+	KindInternal Kind = iota
+	KindInvalidArgument
+	// KindUnprocessable is a kind of well-formed request values rejected by the business rules.
+	KindUnprocessable
+	KindUnauthenticated
+	KindPermissionDenied
+	KindNotFound
+	KindAlreadyExists
+	KindFailedPrecondition
+	KindInsufficientFunds
+	// KindAborted is a kind of requests conflicted with a concurrent change, they may be retried.
+	KindAborted
+	KindResourceExhausted
+)
+
+// Request validation errors of the APIs.
+var (
+	ErrEmptyLogin        = errors.New("login must be non empty")
+	ErrEmptyPassword     = errors.New("password must be non empty")
+	ErrEmptyOldPassword  = errors.New("old password must be non empty")
+	ErrEmptyNewPassword  = errors.New("new password must be non empty")
+	ErrEmptyRefreshToken = errors.New("refresh token must be non empty")
+	ErrInvalidTokenID    = errors.New("invalid token id")
+	ErrEmptyOrderNumber  = errors.New("order number empty")
+)
+
+// Class is the classification of an error.
+type Class struct {
+	// Name is the stable name of the error: clients may rely on it, so an existing name must not be changed.
+	Name string
+	Kind Kind
+	// Message describes the error to the client.
+	Message string
+}
+
+type errorClass struct {
+	err  error
+	name string
+	kind Kind
+}
+
+// errorClasses classifies service and request validation errors. Each error has its own name.
+var errorClasses = []errorClass{
+	// auth
+	{service.ErrInvalidAuthData, "invalid-auth-data", KindUnauthenticated},
+	{service.ErrTooManyLoginAttempts, "too-many-login-attempts", KindResourceExhausted},
+	{service.ErrUserBlocked, "user-blocked", KindPermissionDenied},
+	{service.ErrLoginAlreadyExists, "login-already-exists", KindAlreadyExists},
+	{service.ErrInvalidLoginFormat, "invalid-login-format", KindInvalidArgument},
+	{service.ErrInvalidPasswordFormat, "invalid-password-format", KindInvalidArgument},
+	{ErrEmptyLogin, "empty-login", KindInvalidArgument},
+	{ErrEmptyPassword, "empty-password", KindInvalidArgument},
+	{ErrEmptyOldPassword, "empty-old-password", KindInvalidArgument},
+	{ErrEmptyNewPassword, "empty-new-password", KindInvalidArgument},
+	{ErrEmptyRefreshToken, "empty-refresh-token", KindInvalidArgument},
+	{ErrInvalidTokenID, "invalid-token-id", KindInvalidArgument},
+
+	// orders
+	{service.ErrAnotherUserOrderNumber, "another-user-order-number", KindAlreadyExists},
+	{service.ErrInvalidOrderNumber, "invalid-order-number", KindUnprocessable},
+	{ErrEmptyOrderNumber, "empty-order-number", KindInvalidArgument},
+	{service.ErrOrderNotFound, "order-not-found", KindNotFound},
+	{service.ErrOrderNotDeadLettered, "order-not-dead-lettered", KindNotFound},
+	{service.ErrOrderAlreadyProcessed, "order-already-processed", KindFailedPrecondition},
+
+	// balance
+	{service.ErrInsufficientBalance, "insufficient-balance", KindInsufficientFunds},
+	{service.ErrInvalidSum, "invalid-sum", KindInvalidArgument},
+	{service.ErrTooPreciseSum, "too-precise-sum", KindInvalidArgument},
+	{service.ErrReAttemptWithdraw, "re-attempt-withdraw", KindAlreadyExists},
+	{service.ErrInvalidAdjustment, "invalid-adjustment", KindInvalidArgument},
+	{service.ErrEmptyAdjustmentReason, "empty-adjustment-reason", KindInvalidArgument},
+
+	// users
+	{service.ErrUserNotFound, "user-not-found", KindNotFound},
+	{service.ErrEmptyBlockReason, "empty-block-reason", KindInvalidArgument},
+}
+
+// storageClasses classifies storage errors not translated by the service. The messages of the wrapped errors
+// are internal, so only the storage error itself is described.
+var storageClasses = []errorClass{
+	{storage.ErrRecordNotFound, "not-found", KindNotFound},
+	{storage.ErrRecordAlreadyExists, "already-exists", KindAlreadyExists},
+	{storage.ErrRecordConflict, "conflict", KindAborted},
+}
+
+// Classify returns the class of the error, ok is false if the error is unknown.
+func Classify(err error) (c Class, ok bool) {
+	for _, ec := range errorClasses {
+		if errors.Is(err, ec.err) {
+			return Class{Name: ec.name, Kind: ec.kind, Message: err.Error()}, true
+		}
+	}
+	for _, ec := range storageClasses {
+		if errors.Is(err, ec.err) {
+			return Class{Name: ec.name, Kind: ec.kind, Message: ec.err.Error()}, true
+		}
+	}
+
+	return Class{}, false
+}
+
+// RetryAfter returns the whole seconds rounded up the client should wait before the next attempt,
+// ok is false if the error does not tell when to retry.
+func RetryAfter(err error) (seconds string, ok bool) {
+	var lockErr *service.LoginLockedError
+	if !errors.As(err, &lockErr) {
+		return "", false
+	}
+	return strconv.FormatInt(int64((lockErr.RetryAfter+time.Second-1)/time.Second), 10), true
+}
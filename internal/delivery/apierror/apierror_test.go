@@ -0,0 +1,66 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_errorClasses_uniqueNames(t *testing.T) {
+	t.Parallel()
+
+	names := make(map[string]error)
+	for _, ec := range append(errorClasses, storageClasses...) {
+		other, ok := names[ec.name]
+		assert.False(t, ok, "name %q of %q is the name of %q", ec.name, ec.err, other)
+		names[ec.name] = ec.err
+		assert.NotEqual(t, KindInternal, ec.kind, "error %q has no kind", ec.err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	t.Run("service error", func(t *testing.T) {
+		t.Parallel()
+
+		err := fmt.Errorf("create withdraw: %w", service.ErrInsufficientBalance)
+		c, ok := Classify(err)
+		require.True(t, ok)
+		assert.Equal(t, Class{Name: "insufficient-balance", Kind: KindInsufficientFunds, Message: err.Error()}, c)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		t.Parallel()
+
+		// the message of the wrapped storage error is not described
+		err := fmt.Errorf("user storage: select users where login = 'x': %w", storage.ErrRecordNotFound)
+		c, ok := Classify(err)
+		require.True(t, ok)
+		assert.Equal(t, storage.ErrRecordNotFound.Error(), c.Message)
+	})
+
+	t.Run("unknown error", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok := Classify(errors.New("connection refused"))
+		assert.False(t, ok)
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	seconds, ok := RetryAfter(fmt.Errorf("login: %w", &service.LoginLockedError{RetryAfter: 1500 * time.Millisecond}))
+	require.True(t, ok)
+	assert.Equal(t, "2", seconds)
+
+	_, ok = RetryAfter(service.ErrInvalidAuthData)
+	assert.False(t, ok)
+}
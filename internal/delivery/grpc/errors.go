@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// retryAfterMetadataKey is a header metadata key of the seconds to wait before the next login attempt.
+const retryAfterMetadataKey = "retry-after"
+
+// kindCodes maps the kinds of errors to status codes.
+var kindCodes = map[apierror.Kind]codes.Code{
+	apierror.KindInvalidArgument:    codes.InvalidArgument,
+	apierror.KindUnprocessable:      codes.InvalidArgument,
+	apierror.KindUnauthenticated:    codes.Unauthenticated,
+	apierror.KindPermissionDenied:   codes.PermissionDenied,
+	apierror.KindNotFound:           codes.NotFound,
+	apierror.KindAlreadyExists:      codes.AlreadyExists,
+	apierror.KindFailedPrecondition: codes.FailedPrecondition,
+	apierror.KindInsufficientFunds:  codes.FailedPrecondition,
+	apierror.KindAborted:            codes.Aborted,
+	apierror.KindResourceExhausted:  codes.ResourceExhausted,
+}
+
+// statusError returns the error as a status. Unknown errors are logged with the message
+// and returned as an internal error without details.
+func (s *server) statusError(ctx context.Context, err error, logMsg string) error {
+	if seconds, ok := apierror.RetryAfter(err); ok {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadataKey, seconds)); err != nil {
+			s.log(ctx).Debug("Set retry after header error", zap.Error(err))
+		}
+	}
+
+	if c, ok := apierror.Classify(err); ok {
+		if code, ok := kindCodes[c.Kind]; ok {
+			return status.Error(code, c.Message)
+		}
+	}
+
+	s.log(ctx).Error(logMsg, zap.Error(err))
+	return status.Error(codes.Internal, codes.Internal.String())
+}
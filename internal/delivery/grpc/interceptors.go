@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/metrics"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// authMetadataKey is a metadata key of the access token: "Bearer <token>".
+	authMetadataKey = "authorization"
+	bearerPrefix    = "bearer "
+)
+
+// publicMethods are called without an access token.
+var publicMethods = map[string]bool{
+	pb.Gophermart_Register_FullMethodName: true,
+	pb.Gophermart_Login_FullMethodName:    true,
+	pb.Gophermart_Refresh_FullMethodName:  true,
+}
+
+// requestIDInterceptor is like the rest RequestID middleware: it takes the request ID from the metadata
+// or generates a new one, sends it back in the header and keeps it in the context with a logger writing it.
+func requestIDInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	key := strings.ToLower(requestid.Header)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(key); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(key, id)); err != nil {
+			logger.Debug("Request ID interceptor: set header error", zap.Error(err))
+		}
+
+		ctx = requestid.NewContext(ctx, id)
+		ctx = logctx.NewContext(ctx, logger.With(zap.String("request id", id)))
+		return handler(ctx, req)
+	}
+}
+
+// loggerInterceptor logs each call served.
+func loggerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		t1 := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Info("served",
+			zap.String("request id", requestid.FromContext(ctx)),
+			zap.String("method", info.FullMethod),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("latency", time.Since(t1)))
+		return resp, err
+	}
+}
+
+// metricsInterceptor records the served call metrics like the rest Logger middleware does for requests.
+func metricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	t1 := time.Now()
+	resp, err := handler(ctx, req)
+	metrics.GRPCRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	metrics.GRPCRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(t1).Seconds())
+	return resp, err
+}
+
+// recovererInterceptor turns a panic of the call into an internal error.
+func recovererInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("recovering from panic", zap.Any("error", r), zap.Stack("stacktrace"))
+				err = status.Error(codes.Internal, codes.Internal.String())
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// authInterceptor rejects calls of not public methods without a valid and not revoked access token.
+// The claims of the token are put into the context the way the rest server does.
+func (s *server) authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	tokenString := tokenFromMetadata(ctx)
+	if tokenString == "" {
+		return nil, s.statusError(ctx, service.ErrInvalidAuthData, "Auth interceptor: no token found")
+	}
+	jwtToken, err := s.tokenAuth.Decode(tokenString)
+	if err != nil {
+		return nil, s.statusError(ctx, service.ErrInvalidAuthData, "Auth interceptor: decode token error")
+	}
+	tokenInfo, err := auth.ParseToken(jwtToken)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Auth interceptor: validate token error")
+	}
+	ctx = auth.NewContext(ctx, tokenInfo)
+
+	checkCtx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	revoked, err := s.service.IsTokenRevoked(checkCtx, tokenInfo.ID, tokenInfo.SessionID)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Auth interceptor: check token service error")
+	}
+	if revoked {
+		return nil, s.statusError(ctx, service.ErrInvalidAuthData, "Auth interceptor: token revoked")
+	}
+
+	return handler(ctx, req)
+}
+
+// tokenFromMetadata returns the bearer token of the call metadata (empty - there is no token).
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(authMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values[0]) > len(bearerPrefix) && strings.EqualFold(values[0][:len(bearerPrefix)], bearerPrefix) {
+		return values[0][len(bearerPrefix):]
+	}
+	return ""
+}
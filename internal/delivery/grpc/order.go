@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// orderStatuses maps order statuses to the statuses of the API.
+var orderStatuses = map[order.Status]pb.OrderStatus{
+	order.StatusNew:        pb.OrderStatus_ORDER_STATUS_NEW,
+	order.StatusProcessing: pb.OrderStatus_ORDER_STATUS_PROCESSING,
+	order.StatusInvalid:    pb.OrderStatus_ORDER_STATUS_INVALID,
+	order.StatusProcessed:  pb.OrderStatus_ORDER_STATUS_PROCESSED,
+}
+
+func (s *server) UploadOrder(ctx context.Context, req *pb.UploadOrderRequest) (*pb.UploadOrderResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	login, err := auth.LoginFromContext(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Upload order: get login from context error")
+	}
+
+	if req.GetNumber() <= 0 {
+		return nil, s.statusError(ctx, service.ErrInvalidOrderNumber, "Upload order: validate request error")
+	}
+
+	_, isExistedOrder, err := s.service.CreateOrder(ctx, *login, order.Number(req.GetNumber()))
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Upload order: create order service error")
+	}
+
+	return &pb.UploadOrderResponse{AlreadyUploaded: isExistedOrder}, nil
+}
+
+func (s *server) ListOrders(ctx context.Context, _ *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	login, err := auth.LoginFromContext(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "List orders: get login from context error")
+	}
+
+	orders, err := s.service.ListUserOrders(ctx, *login)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "List orders: list user orders service error")
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].UploadedAt.Before(orders[j].UploadedAt)
+	})
+
+	resp := &pb.ListOrdersResponse{Orders: make([]*pb.Order, 0, len(orders))}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, &pb.Order{
+			Number:     int64(o.Number),
+			Status:     orderStatuses[o.Status],
+			Accrual:    o.Accrual.String(),
+			UploadedAt: timestamppb.New(o.UploadedAt),
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,101 @@
+// Package grpc is the gRPC API of the loyalty program alongside the rest one:
+// it serves the same users with the same access tokens through the same service.
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/Karzoug/loyalty_program/pkg/e"
+	"github.com/Karzoug/loyalty_program/pkg/jwtkeys"
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	handlerTimeout  = 5 * time.Second
+	shutdownTimeout = 5 * time.Second
+)
+
+type serverConfig interface {
+	GRPCAddress() string
+	JWTKeys() *jwtkeys.KeySet
+	AccessTokenLifetime() time.Duration
+	TrustedProxies() []netip.Prefix
+}
+
+type server struct {
+	pb.UnimplementedGophermartServer
+
+	cfg     serverConfig
+	logger  *zap.Logger
+	service *service.Service
+
+	tokenAuth *jwtkeys.KeySet
+	server    *grpc.Server
+}
+
+func New(cfg serverConfig, service *service.Service, logger *zap.Logger) *server {
+	s := &server{
+		cfg:     cfg,
+		logger:  logger,
+		service: service,
+
+		tokenAuth: cfg.JWTKeys(),
+	}
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		// the span of the call continues the trace of the W3C trace context metadata
+		otelgrpc.UnaryServerInterceptor(),
+		requestIDInterceptor(logger),
+		loggerInterceptor(logger),
+		metricsInterceptor,
+		recovererInterceptor(logger),
+		s.authInterceptor,
+	))
+	pb.RegisterGophermartServer(s.server, s)
+
+	return s
+}
+
+// log returns the logger of the call, that writes the request ID.
+func (s *server) log(ctx context.Context) *zap.Logger {
+	return logctx.FromContext(ctx, s.logger)
+}
+
+func (s *server) Run(ctx context.Context) error {
+	s.logger.Info("Running grpc server", zap.String("address", s.cfg.GRPCAddress()))
+
+	lis, err := net.Listen("tcp", s.cfg.GRPCAddress())
+	if err != nil {
+		return e.Wrap("listen", err)
+	}
+
+	go func() {
+		if err := s.server.Serve(lis); err != nil {
+			s.logger.Fatal("Server serve error", zap.Error(err))
+		}
+	}()
+
+	<-ctx.Done()
+	s.logger.Info("Shutting down grpc server")
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		// calls in progress are cancelled
+		s.server.Stop()
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+)
+
+func (s *server) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	if req.GetRefreshToken() == "" {
+		return nil, s.statusError(ctx, apierror.ErrEmptyRefreshToken, "Refresh: validate request error")
+	}
+
+	ss, refreshToken, err := s.service.RefreshSession(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Refresh: refresh session service error")
+	}
+
+	// the role may be changed since the session is created
+	u, err := s.service.RefreshUser(ctx, ss.UserLogin)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Refresh: refresh user service error")
+	}
+
+	resp, err := s.newAuthResponse(*ss, refreshToken, u.Role)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Refresh: issue access token error")
+	}
+	return resp, nil
+}
+
+func (s *server) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	token, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Logout: get token from context error")
+	}
+
+	if req.GetAll() {
+		err = s.service.RevokeUserSessions(ctx, token.Login)
+		if err == nil {
+			err = s.service.RevokeToken(ctx, token.ID, token.ExpiresAt)
+		}
+	} else {
+		err = s.service.Logout(ctx, token.Login, token.SessionID, token.ID, token.ExpiresAt)
+	}
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Logout: logout service error")
+	}
+
+	return &pb.LogoutResponse{}, nil
+}
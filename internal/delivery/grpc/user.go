@@ -0,0 +1,127 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/pkg/realip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const tokenType = "Bearer"
+
+func validateAuthRequest(req *pb.AuthRequest) error {
+	if req.GetLogin() == "" {
+		return apierror.ErrEmptyLogin
+	}
+	if req.GetPassword() == "" {
+		return apierror.ErrEmptyPassword
+	}
+	return nil
+}
+
+func (s *server) Register(ctx context.Context, req *pb.AuthRequest) (*pb.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	if err := validateAuthRequest(req); err != nil {
+		return nil, s.statusError(ctx, err, "Register: validate request error")
+	}
+
+	u, err := s.service.RegisterUser(ctx, user.Login(req.GetLogin()), req.GetPassword())
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Register: user register service error")
+	}
+
+	ss, refreshToken, err := s.service.CreateSession(ctx, u.Login)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Register: create session service error")
+	}
+
+	resp, err := s.newAuthResponse(*ss, refreshToken, u.Role)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Register: issue access token error")
+	}
+	return resp, nil
+}
+
+func (s *server) Login(ctx context.Context, req *pb.AuthRequest) (*pb.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	if err := validateAuthRequest(req); err != nil {
+		return nil, s.statusError(ctx, err, "Login: validate request error")
+	}
+
+	u, err := s.service.LoginUser(ctx, user.Login(req.GetLogin()), req.GetPassword(), s.clientIP(ctx))
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Login: user login service error")
+	}
+
+	ss, refreshToken, err := s.service.CreateSession(ctx, u.Login)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Login: create session service error")
+	}
+
+	resp, err := s.newAuthResponse(*ss, refreshToken, u.Role)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Login: issue access token error")
+	}
+	return resp, nil
+}
+
+// newAuthResponse issues an access token with the user role for the session,
+// the tokens are the same the rest server issues.
+func (s *server) newAuthResponse(ss session.Session, refreshToken string, role user.Role) (*pb.AuthResponse, error) {
+	tokenString, err := auth.NewAccessToken(s.tokenAuth, ss, role, s.cfg.AccessTokenLifetime())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.AuthResponse{
+		AccessToken:  tokenString,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(s.cfg.AccessTokenLifetime().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// clientIP returns the IP of the client, it is taken from the x-forwarded-for metadata behind trusted proxies.
+func (s *server) clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return realip.ClientIP(p.Addr.String(), md.Get(realip.ForwardedForHeader), s.cfg.TrustedProxies())
+}
+
+func (s *server) GetBalance(ctx context.Context, _ *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	login, err := auth.LoginFromContext(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Get balance: get login from context error")
+	}
+
+	balance, err := s.service.GetUserBalance(ctx, *login)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Get balance: user balance service error")
+	}
+
+	sum, err := s.service.SumUserWithdrawals(ctx, *login)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Get balance: withdrawals sum service error")
+	}
+
+	return &pb.GetBalanceResponse{
+		Current:   balance.String(),
+		Withdrawn: sum.String(),
+	}, nil
+}
@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxAmountExponent limits the exponent of a requested amount
+// so that rounding it does not require huge computations.
+const maxAmountExponent = 32
+
+// parseSum parses a positive decimal sum of the request.
+func parseSum(s string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil || d.Exponent() < -maxAmountExponent || d.Exponent() > maxAmountExponent || !d.IsPositive() {
+		return decimal.Decimal{}, service.ErrInvalidSum
+	}
+	return d, nil
+}
+
+func (s *server) Withdraw(ctx context.Context, req *pb.WithdrawRequest) (*pb.WithdrawResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	login, err := auth.LoginFromContext(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Withdraw: get login from context error")
+	}
+
+	sum, err := parseSum(req.GetSum())
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Withdraw: validate request error")
+	}
+	if req.GetOrder() <= 0 {
+		return nil, s.statusError(ctx, service.ErrInvalidOrderNumber, "Withdraw: validate request error")
+	}
+
+	_, err = s.service.CreateWithdraw(ctx, *login, order.Number(req.GetOrder()), sum)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "Withdraw: create withdraw service error")
+	}
+
+	return &pb.WithdrawResponse{}, nil
+}
+
+func (s *server) ListWithdrawals(ctx context.Context, _ *pb.ListWithdrawalsRequest) (*pb.ListWithdrawalsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	login, err := auth.LoginFromContext(ctx)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "List withdrawals: get login from context error")
+	}
+
+	ws, err := s.service.ListUserWithdrawals(ctx, *login)
+	if err != nil {
+		return nil, s.statusError(ctx, err, "List withdrawals: list withdrawals service error")
+	}
+	sort.SliceStable(ws, func(i, j int) bool {
+		return ws[i].ProcessedAt.Before(ws[j].ProcessedAt)
+	})
+
+	resp := &pb.ListWithdrawalsResponse{Withdrawals: make([]*pb.Withdrawal, 0, len(ws))}
+	for _, w := range ws {
+		resp.Withdrawals = append(resp.Withdrawals, &pb.Withdrawal{
+			Order:       int64(w.OrderNumber),
+			Sum:         w.Sum.String(),
+			ProcessedAt: timestamppb.New(w.ProcessedAt),
+		})
+	}
+
+	return resp, nil
+}
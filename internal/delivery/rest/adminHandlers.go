@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const forbiddenProblem = "forbidden"
+
+// adminOnly is a middleware that lets through only requests with tokens of users with the admin role.
+func (s *server) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.FromContext(r.Context())
+		if err != nil {
+			s.writeError(w, r, err, "Admin only middleware: get token from context error")
+			return
+		}
+
+		if token.HasRole(user.RoleAdmin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		helper.WriteProblem(w, r, helper.NewProblem(forbiddenProblem, "admin role required", http.StatusForbidden), s.logger)
+	})
+}
+
+type deadLetterResponse struct {
+	Order          string    `json:"order"`
+	Attempts       int       `json:"attempts"`
+	Reason         string    `json:"reason"`
+	CreatedAt      time.Time `json:"created_at"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+func (s *server) listDeadLetterOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	dls, err := s.service.ListDeadLetterOrders(ctx)
+	if err != nil {
+		s.writeError(w, r, err, "List dead letter orders handler: list dead letter orders service error")
+		return
+	}
+
+	if len(dls) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	dlsResp := make([]deadLetterResponse, 0, len(dls))
+	for _, dl := range dls {
+		dlsResp = append(dlsResp, deadLetterResponse{
+			Order:          strconv.FormatInt(int64(dl.OrderNumber), 10),
+			Attempts:       dl.Attempts,
+			Reason:         dl.Reason,
+			CreatedAt:      dl.CreatedAt,
+			DeadLetteredAt: dl.DeadLetteredAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dlsResp); err != nil {
+		s.log(r).Error("List dead letter orders handler: encode json response error", zap.Error(err))
+		return
+	}
+}
+
+func (s *server) requeueDeadLetterOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	number, err := strconv.ParseInt(chi.URLParam(r, "number"), 10, 64)
+	if err != nil {
+		s.writeError(w, r, service.ErrInvalidOrderNumber, "Requeue dead letter order handler: parse order number error")
+		return
+	}
+
+	err = s.service.RequeueDeadLetterOrder(ctx, order.Number(number))
+	if err != nil {
+		s.writeError(w, r, err, "Requeue dead letter order handler: requeue dead letter order service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// revokeUserSessionsHandler logs out all sessions of the user.
+func (s *server) revokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	login := user.Login(chi.URLParam(r, "login"))
+	if !login.Valid() {
+		s.writeError(w, r, service.ErrInvalidLoginFormat, "Revoke user sessions handler: validate login error")
+		return
+	}
+
+	err := s.service.RevokeUserSessions(ctx, login)
+	if err != nil {
+		s.writeError(w, r, err, "Revoke user sessions handler: revoke user sessions service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// revokeTokenHandler rejects the access token with the ID (e.g. a stolen one) until it expires.
+func (s *server) revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	jti := chi.URLParam(r, "jti")
+	if _, err := uuid.Parse(jti); err != nil {
+		s.writeError(w, r, apierror.ErrInvalidTokenID, "Revoke token handler: validate token id error")
+		return
+	}
+
+	// the token expiry is unknown, so it is kept revoked for the longest access token lifetime
+	err := s.service.RevokeToken(ctx, jti, s.tokenExpiresAt())
+	if err != nil {
+		s.writeError(w, r, err, "Revoke token handler: revoke token service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,253 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// userFromURL returns the existing user by the {login} URL parameter,
+// errors are written to the response and nil is returned.
+func (s *server) userFromURL(ctx context.Context, w http.ResponseWriter, r *http.Request, handlerName string) *user.User {
+	login := user.Login(chi.URLParam(r, "login"))
+	if !login.Valid() {
+		s.writeError(w, r, service.ErrInvalidLoginFormat, handlerName+": validate login error")
+		return nil
+	}
+
+	u, err := s.service.GetUser(ctx, login)
+	if err != nil {
+		s.writeError(w, r, err, handlerName+": get user service error")
+		return nil
+	}
+
+	return u
+}
+
+type adminUserResponse struct {
+	Login       string `json:"login"`
+	Role        string `json:"role"`
+	Balance     amount `json:"current"`
+	Withdrawn   amount `json:"withdrawn"`
+	Blocked     bool   `json:"blocked"`
+	BlockedAt   string `json:"blocked_at,omitempty"`
+	BlockReason string `json:"block_reason,omitempty"`
+}
+
+func (s *server) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	u := s.userFromURL(ctx, w, r, "Get user handler")
+	if u == nil {
+		return
+	}
+
+	sum, err := s.service.SumUserWithdrawals(ctx, u.Login)
+	if err != nil {
+		s.writeError(w, r, err, "Get user handler: withdrawals sum service error")
+		return
+	}
+
+	userResp := adminUserResponse{
+		Login:     string(u.Login),
+		Role:      string(u.Role),
+		Balance:   s.newAmount(u.Balance),
+		Withdrawn: s.newAmount(*sum),
+		Blocked:   u.Blocked(),
+	}
+	if u.Blocked() {
+		userResp.BlockedAt = u.BlockedAt.Format(time.RFC3339)
+		userResp.BlockReason = u.BlockReason
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(userResp); err != nil {
+		s.log(r).Error("Get user handler: encode json response error", zap.Error(err))
+		return
+	}
+}
+
+// listOrdersOfUserHandler lists orders of any user like the user lists its own ones.
+func (s *server) listOrdersOfUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	u := s.userFromURL(ctx, w, r, "List orders of user handler")
+	if u == nil {
+		return
+	}
+
+	s.writeUserOrders(ctx, w, r, u.Login)
+}
+
+// listWithdrawalsOfUserHandler lists withdrawals of any user like the user lists its own ones.
+func (s *server) listWithdrawalsOfUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	u := s.userFromURL(ctx, w, r, "List withdrawals of user handler")
+	if u == nil {
+		return
+	}
+
+	s.writeUserWithdrawals(ctx, w, r, u.Login)
+}
+
+type adjustmentRequest struct {
+	Sum    amount `json:"sum"`
+	Reason string `json:"reason"`
+}
+
+func (r adjustmentRequest) validate() error {
+	if r.Sum.value.IsZero() {
+		return service.ErrInvalidAdjustment
+	}
+	if r.Reason == "" {
+		return service.ErrEmptyAdjustmentReason
+	}
+	return nil
+}
+
+type adjustmentResponse struct {
+	ID        string    `json:"id"`
+	Sum       amount    `json:"sum"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// adjustUserBalanceHandler changes the user balance by a signed sum, the reason is mandatory.
+func (s *server) adjustUserBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	admin, err := auth.LoginFromContext(ctx)
+	if err != nil {
+		s.writeError(w, r, err, "Adjust user balance handler: get login from context error")
+		return
+	}
+
+	login := user.Login(chi.URLParam(r, "login"))
+	if !login.Valid() {
+		s.writeError(w, r, service.ErrInvalidLoginFormat, "Adjust user balance handler: validate login error")
+		return
+	}
+
+	var adjustmentReq adjustmentRequest
+	err = helper.DecodeJSON(r, &adjustmentReq)
+	if err != nil {
+		s.writeError(w, r, err, "Adjust user balance handler: decode request from JSON error")
+		return
+	}
+
+	if err := adjustmentReq.validate(); err != nil {
+		s.writeError(w, r, err, "Adjust user balance handler: validate request error")
+		return
+	}
+
+	entry, err := s.service.AdjustUserBalance(ctx, login, adjustmentReq.Sum.value, adjustmentReq.Reason, *admin)
+	if err != nil {
+		s.writeError(w, r, err, "Adjust user balance handler: adjust user balance service error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(adjustmentResponse{
+		ID:        entry.ID,
+		Sum:       s.newAmount(entry.Amount),
+		Reason:    entry.Reason,
+		CreatedBy: string(entry.CreatedBy),
+		CreatedAt: entry.CreatedAt,
+	}); err != nil {
+		s.log(r).Error("Adjust user balance handler: encode json response error", zap.Error(err))
+		return
+	}
+}
+
+// reprocessOrderHandler forces the order to be sent to the accrual system again.
+func (s *server) reprocessOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	number, err := strconv.ParseInt(chi.URLParam(r, "number"), 10, 64)
+	if err != nil {
+		s.writeError(w, r, service.ErrInvalidOrderNumber, "Reprocess order handler: parse order number error")
+		return
+	}
+
+	err = s.service.ReprocessOrder(ctx, order.Number(number))
+	if err != nil {
+		s.writeError(w, r, err, "Reprocess order handler: reprocess order service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type blockRequest struct {
+	Reason string `json:"reason"`
+}
+
+// blockUserHandler blocks the user for the mandatory reason.
+func (s *server) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	login := user.Login(chi.URLParam(r, "login"))
+	if !login.Valid() {
+		s.writeError(w, r, service.ErrInvalidLoginFormat, "Block user handler: validate login error")
+		return
+	}
+
+	var blockReq blockRequest
+	err := helper.DecodeJSON(r, &blockReq)
+	if err != nil {
+		s.writeError(w, r, err, "Block user handler: decode request from JSON error")
+		return
+	}
+	if blockReq.Reason == "" {
+		s.writeError(w, r, service.ErrEmptyBlockReason, "Block user handler: validate request error")
+		return
+	}
+
+	err = s.service.BlockUser(ctx, login, blockReq.Reason)
+	if err != nil {
+		s.writeError(w, r, err, "Block user handler: block user service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// unblockUserHandler unblocks the user, the accruals held while the user was blocked are credited.
+func (s *server) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	login := user.Login(chi.URLParam(r, "login"))
+	if !login.Valid() {
+		s.writeError(w, r, service.ErrInvalidLoginFormat, "Unblock user handler: validate login error")
+		return
+	}
+
+	err := s.service.UnblockUser(ctx, login)
+	if err != nil {
+		s.writeError(w, r, err, "Unblock user handler: unblock user service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
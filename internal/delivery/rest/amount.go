@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/shopspring/decimal"
+)
+
+// maxAmountExponent limits the exponent of a requested amount
+// so that rounding it does not require huge computations.
+const maxAmountExponent = 32
+
+// amount is an exact amount of points in requests and responses.
+// It is encoded as a JSON number or, in the decimal strings mode, as a JSON string.
+type amount struct {
+	value    decimal.Decimal
+	asString bool
+}
+
+func (s *server) newAmount(d decimal.Decimal) amount {
+	return amount{
+		value:    d,
+		asString: s.cfg.AmountsAsStrings(),
+	}
+}
+
+func (a amount) MarshalJSON() ([]byte, error) {
+	if a.asString {
+		return []byte(`"` + a.value.String() + `"`), nil
+	}
+	return []byte(a.value.String()), nil
+}
+
+// UnmarshalJSON decodes an amount from a JSON number or a string containing a decimal number
+// without going through float64.
+func (a *amount) UnmarshalJSON(b []byte) error {
+	invalidAmountErr := &helper.HandlerError{
+		Type:    helper.InvalidRequestBodyProblem,
+		Message: "request body contains an invalid amount",
+		Code:    http.StatusBadRequest,
+	}
+
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		return invalidAmountErr
+	}
+	if b[0] == '"' {
+		if len(b) < 2 || b[len(b)-1] != '"' {
+			return invalidAmountErr
+		}
+		b = b[1 : len(b)-1]
+	}
+
+	d, err := decimal.NewFromString(string(b))
+	if err != nil || d.Exponent() < -maxAmountExponent || d.Exponent() > maxAmountExponent {
+		return invalidAmountErr
+	}
+	a.value = d
+
+	return nil
+}
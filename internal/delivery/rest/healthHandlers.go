@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+const (
+	statusOK       = "ok"
+	statusReady    = "ready"
+	statusNotReady = "not ready"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type componentResponse struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Details  string `json:"details,omitempty"`
+}
+
+type readinessResponse struct {
+	Status     string              `json:"status"`
+	Components []componentResponse `json:"components"`
+}
+
+// livenessHandler reports the server is alive: it responds while the process is able to serve requests.
+func (s *server) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeHealthJSON(w, http.StatusOK, healthResponse{Status: statusOK})
+}
+
+// readinessHandler reports whether the server is ready to serve requests with the state of each component,
+// it responds with 503 if a critical component is down or the server is shutting down.
+func (s *server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	resp := readinessResponse{Status: statusReady}
+
+	serverComponent := service.Component{Name: "http server", Status: service.ComponentUp, Critical: true}
+	if !s.ready.Load() {
+		serverComponent.Status = service.ComponentDown
+		serverComponent.Details = "shutting down"
+	}
+
+	for _, c := range append([]service.Component{serverComponent}, s.service.CheckReadiness(ctx)...) {
+		if !c.Ready() {
+			resp.Status = statusNotReady
+		}
+		resp.Components = append(resp.Components, componentResponse{
+			Name:     c.Name,
+			Status:   c.Status,
+			Critical: c.Critical,
+			Details:  c.Details,
+		})
+	}
+
+	code := http.StatusOK
+	if resp.Status != statusReady {
+		code = http.StatusServiceUnavailable
+	}
+	s.writeHealthJSON(w, code, resp)
+}
+
+func (s *server) writeHealthJSON(w http.ResponseWriter, code int, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Error("Health handler: encode json response error", zap.Error(err))
+	}
+}
@@ -10,11 +10,19 @@ import (
 	"github.com/goccy/go-json"
 )
 
+const (
+	// UnsupportedContentTypeProblem is the problem type of requests with a body not in JSON format.
+	UnsupportedContentTypeProblem = "unsupported-content-type"
+	// InvalidRequestBodyProblem is the problem type of requests with a body not decodable to the value.
+	InvalidRequestBodyProblem = "invalid-request-body"
+)
+
 // DecodeJSON is a method that in the case of response header/body valid for JSON format,
 // attempts to decode the content into the given value.
 func DecodeJSON(r *http.Request, value any) error {
 	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
 		return &HandlerError{
+			Type:    UnsupportedContentTypeProblem,
 			Message: "not valid content-type",
 			Code:    http.StatusBadRequest,
 		}
@@ -47,6 +55,7 @@ func DecodeJSON(r *http.Request, value any) error {
 		}
 
 		return &HandlerError{
+			Type:    InvalidRequestBodyProblem,
 			Message: msg,
 			Code:    code,
 		}
@@ -55,6 +64,7 @@ func DecodeJSON(r *http.Request, value any) error {
 	err = dec.Decode(&struct{}{})
 	if err != io.EOF {
 		return &HandlerError{
+			Type:    InvalidRequestBodyProblem,
 			Message: "request body must only contain a single JSON object/array",
 			Code:    http.StatusBadRequest,
 		}
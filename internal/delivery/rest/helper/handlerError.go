@@ -1,7 +1,9 @@
 package helper
 
-// HandlerError is an error with a message and a response code that can be sent to the user.
+// HandlerError is an error with a problem type, a message and a response code that can be sent to the user.
+// An empty type stands for the generic problem of the response code.
 type HandlerError struct {
+	Type    string
 	Message string
 	Code    int
 }
@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+const (
+	// ProblemContentType is the media type of RFC 7807 problem details.
+	ProblemContentType = "application/problem+json"
+	// ProblemTypePrefix is the prefix of the machine-readable problem type URIs.
+	ProblemTypePrefix = "/problems/"
+
+	blankProblemType = "about:blank"
+)
+
+// Problem is an RFC 7807 problem details object with the request ID extension member.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// NewProblem returns the problem of the type with the detail message and the response code,
+// an empty type is the generic problem of the response code (about:blank) titled by the status text.
+func NewProblem(typ, detail string, code int) Problem {
+	if typ == "" {
+		return Problem{
+			Type:   blankProblemType,
+			Title:  http.StatusText(code),
+			Status: code,
+			Detail: detail,
+		}
+	}
+
+	title := strings.ReplaceAll(typ, "-", " ")
+	return Problem{
+		Type:   ProblemTypePrefix + typ,
+		Title:  strings.ToUpper(title[:1]) + title[1:],
+		Status: code,
+		Detail: detail,
+	}
+}
+
+// WriteProblem writes the problem of the request with the request ID echoed from the response header.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem, logger *zap.Logger) {
+	p.Instance = r.URL.Path
+	p.RequestID = w.Header().Get(requestid.Header)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	b, _ := json.Marshal(p)
+	_, err := w.Write(b)
+	if err != nil {
+		logger.Error("Write the data to response error", zap.Error(err))
+	}
+}
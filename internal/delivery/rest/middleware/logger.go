@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/metrics"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 )
@@ -16,6 +20,10 @@ var (
 	sugaredLogFormat = `[%s] "%s %s %s" from %s - %s %dB in %s`
 )
 
+// unmatchedRoute is a route label of requests not matched any route,
+// it keeps unknown paths out of the metrics labels.
+const unmatchedRoute = "unmatched"
+
 // Logger is a middleware that logs each request recieved using the provided Zap logger.
 func Logger(l interface{}) func(next http.Handler) http.Handler {
 	switch logger := l.(type) {
@@ -25,7 +33,9 @@ func Logger(l interface{}) func(next http.Handler) http.Handler {
 				ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
 				t1 := time.Now()
 				defer func() {
+					observe(r, ww.Status(), time.Since(t1))
 					logger.Info("served",
+						zap.String("request id", requestid.FromContext(r.Context())),
 						zap.String("method", r.Method),
 						zap.String("path", r.URL.Path),
 						zap.Int("status", ww.Status()),
@@ -44,6 +54,7 @@ func Logger(l interface{}) func(next http.Handler) http.Handler {
 				ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
 				t1 := time.Now()
 				defer func() {
+					observe(r, ww.Status(), time.Since(t1))
 					logger.Infof(sugaredLogFormat,
 						r.Method,
 						r.URL.Path,
@@ -63,6 +74,25 @@ func Logger(l interface{}) func(next http.Handler) http.Handler {
 	return nil
 }
 
+// observe records the served request metrics by its chi route pattern,
+// so requests with different URL parameters are counted together.
+func observe(r *http.Request, status int, latency time.Duration) {
+	route := unmatchedRoute
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			route = pattern
+		}
+	}
+
+	if status == 0 {
+		// nothing was written, the server responds with 200
+		status = http.StatusOK
+	}
+
+	metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(latency.Seconds())
+}
+
 func statusLabel(status int) string {
 	switch {
 	case status >= 100 && status < 300:
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// invalidRequestProblem is the problem type of requests not matching the API specification.
+const invalidRequestProblem = "invalid-request"
+
+// OpenAPIValidator is a middleware that rejects requests not matching the OpenAPI specification
+// with a problem details response. Requests of routes unknown to the specification are let through.
+// If validateResponses is set, responses are checked too: a mismatch is a bug of the server,
+// so it is only logged and the response is sent as is.
+func OpenAPIValidator(doc *openapi3.T, validateResponses bool, logger *zap.Logger) (func(next http.Handler) http.Handler, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &openapi3filter.Options{
+		// authentication is done by the handlers
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults:   true,
+		IncludeResponseStatus: true,
+	}
+	opts.WithCustomSchemaErrorFunc(schemaErrorMessage)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqInput := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    opts,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), reqInput); err != nil {
+				helper.WriteProblem(w, r, helper.NewProblem(invalidRequestProblem, err.Error(), http.StatusBadRequest), logger)
+				return
+			}
+
+			if !validateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var body bytes.Buffer
+			ww.Tee(&body)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			respInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: reqInput,
+				Status:                 status,
+				Header:                 ww.Header(),
+				Body:                   io.NopCloser(&body),
+				Options:                opts,
+			}
+			if err := openapi3filter.ValidateResponse(r.Context(), respInput); err != nil {
+				logger.Error("OpenAPI validator middleware: response does not match the specification",
+					zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Int("status", status), zap.Error(err))
+			}
+		})
+	}, nil
+}
+
+// schemaErrorMessage returns the reason of the schema error with the path to the invalid value,
+// but without the schema dump.
+func schemaErrorMessage(err *openapi3.SchemaError) string {
+	if pointer := err.JSONPointer(); len(pointer) > 0 {
+		return "/" + strings.Join(pointer, "/") + ": " + err.Reason
+	}
+	return err.Reason
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
 	"go.uber.org/zap"
 )
 
@@ -17,7 +18,7 @@ func Recoverer(logger *zap.Logger) func(h http.Handler) http.Handler {
 					}
 
 					logger.Error("recovering from panic", zap.Any("error", err), zap.Stack("stacktrace"))
-					w.WriteHeader(http.StatusInternalServerError)
+					helper.WriteProblem(w, r, helper.NewProblem("", "", http.StatusInternalServerError), logger)
 				}
 			}()
 
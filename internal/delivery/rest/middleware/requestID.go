@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const requestIDAttributeKey = attribute.Key("http.request_id")
+
+// RequestID is a middleware that takes the request ID from the X-Request-ID header or generates a new one
+// if the header is missing or not valid. The ID is echoed in the response header and kept in the request context
+// with a logger writing it, so all the logs caused by the request can be correlated.
+func RequestID(logger *zap.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if !requestid.Valid(id) {
+				id = requestid.New()
+			}
+			w.Header().Set(requestid.Header, id)
+			trace.SpanFromContext(r.Context()).SetAttributes(requestIDAttributeKey.String(id))
+
+			ctx := requestid.NewContext(r.Context(), id)
+			ctx = logctx.NewContext(ctx, logger.With(zap.String("request id", id)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+// openAPISpec is the OpenAPI specification of the API, it must document every route of the router.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// loadOpenAPI parses and validates the embedded OpenAPI specification.
+func loadOpenAPI(ctx context.Context) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// openAPIHandler publishes the OpenAPI specification of the API.
+func (s *server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(openAPISpec); err != nil {
+		s.log(r).Error("OpenAPI handler: write response error", zap.Error(err))
+	}
+}
@@ -0,0 +1,193 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/middleware"
+	"github.com/Karzoug/loyalty_program/pkg/jwtkeys"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type mockConfig struct {
+	jwtKeys *jwtkeys.KeySet
+}
+
+func (c mockConfig) RunAddress() string                 { return "localhost:0" }
+func (c mockConfig) JWTKeys() *jwtkeys.KeySet           { return c.jwtKeys }
+func (c mockConfig) AmountsAsStrings() bool             { return false }
+func (c mockConfig) AccessTokenLifetime() time.Duration { return time.Minute }
+func (c mockConfig) ShutdownDelay() time.Duration       { return 0 }
+func (c mockConfig) OpenAPIValidation() bool            { return false }
+func (c mockConfig) IsDebugMode() bool                  { return false }
+func (c mockConfig) TrustedProxies() []netip.Prefix     { return nil }
+
+func loadTestOpenAPI(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	doc, err := loadOpenAPI(context.Background())
+	require.NoError(t, err)
+	return doc
+}
+
+func TestOpenAPI_routes(t *testing.T) {
+	doc := loadTestOpenAPI(t)
+
+	keys, err := jwtkeys.NewHMAC([]byte("secret"))
+	require.NoError(t, err)
+	s := New(mockConfig{jwtKeys: keys}, nil, zap.NewNop())
+	router, err := s.newRouter(context.Background())
+	require.NoError(t, err)
+
+	var routes []string
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+
+	assert.ElementsMatch(t, routes, documented, "every route must be documented in openapi.json and vice versa")
+}
+
+// TestOpenAPI_schemas fails when a request or response struct drifts from its schema:
+// the JSON fields must match the schema properties, fields without omitempty are required,
+// and an encoded value must be valid against the schema.
+func TestOpenAPI_schemas(t *testing.T) {
+	doc := loadTestOpenAPI(t)
+
+	now := time.Now().UTC()
+	sum := amount{value: decimal.RequireFromString("12.5")}
+	sumString := amount{value: decimal.RequireFromString("12.5"), asString: true}
+
+	tests := []struct {
+		schema string
+		value  any
+	}{
+		{schema: "AuthRequest", value: authRequest{Login: "user", Password: "password"}},
+		{schema: "AuthResponse", value: authResponse{AccessToken: "a", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "r"}},
+		{schema: "RefreshTokenRequest", value: refreshTokenRequest{RefreshToken: "r"}},
+		{schema: "ChangePasswordRequest", value: changePasswordRequest{OldPassword: "old", NewPassword: "new"}},
+		{schema: "OrderResponse", value: orderResponse{Number: "12345678903", Status: "PROCESSED", Accrual: sum, UploadedAt: now}},
+		{schema: "OrderResponse", value: orderResponse{Number: "12345678903", Status: "NEW", Accrual: sumString, UploadedAt: now}},
+		{schema: "BalanceResponse", value: balanceResponse{Balance: sum, Withdrawn: sum}},
+		{schema: "WithdrawRequest", value: withdrawRequest{Order: "2377225624", Sum: sum}},
+		{schema: "WithdrawResponse", value: withdrawResponse{Order: "2377225624", Sum: sumString, ProcessedAt: now}},
+		{schema: "DeadLetterResponse", value: deadLetterResponse{Order: "2377225624", Attempts: 3, Reason: "r", CreatedAt: now, DeadLetteredAt: now}},
+		{schema: "AdminUserResponse", value: adminUserResponse{Login: "user", Role: "user", Balance: sum, Withdrawn: sum}},
+		{schema: "AdminUserResponse", value: adminUserResponse{Login: "user", Role: "admin", Balance: sum, Withdrawn: sum,
+			Blocked: true, BlockedAt: now.Format(time.RFC3339), BlockReason: "r"}},
+		{schema: "AdjustmentRequest", value: adjustmentRequest{Sum: sum, Reason: "r"}},
+		{schema: "AdjustmentResponse", value: adjustmentResponse{ID: "id", Sum: sum, Reason: "r", CreatedBy: "admin", CreatedAt: now}},
+		{schema: "BlockRequest", value: blockRequest{Reason: "r"}},
+		{schema: "HealthResponse", value: healthResponse{Status: statusOK}},
+		{schema: "ComponentResponse", value: componentResponse{Name: "database", Status: "up", Critical: true}},
+		{schema: "ReadinessResponse", value: readinessResponse{Status: statusNotReady,
+			Components: []componentResponse{{Name: "database", Status: "down", Critical: true, Details: "d"}}}},
+		{schema: "Problem", value: helper.NewProblem("insufficient-balance", "insufficient balance", http.StatusPaymentRequired)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			schemaRef, ok := doc.Components.Schemas[tt.schema]
+			require.True(t, ok, "schema is not defined")
+			schema := schemaRef.Value
+
+			fields, required := jsonFields(reflect.TypeOf(tt.value))
+			properties := make([]string, 0, len(schema.Properties))
+			for name := range schema.Properties {
+				properties = append(properties, name)
+			}
+			assert.ElementsMatch(t, fields, properties, "fields")
+			assert.ElementsMatch(t, required, schema.Required, "required fields")
+
+			b, err := json.Marshal(tt.value)
+			require.NoError(t, err)
+			var value any
+			require.NoError(t, json.Unmarshal(b, &value))
+			assert.NoError(t, schema.VisitJSON(value), string(b))
+		})
+	}
+}
+
+// jsonFields returns names of the JSON fields of the struct type and the ones without omitempty.
+func jsonFields(typ reflect.Type) (fields, required []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		tag, ok := typ.Field(i).Tag.Lookup("json")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fields = append(fields, name)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return fields, required
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	doc := loadTestOpenAPI(t)
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	validator, err := middleware.OpenAPIValidator(doc, true, zap.New(core))
+	require.NoError(t, err)
+
+	handler := validator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"current":1,"withdrawn":"not an amount"}`))
+	}))
+
+	t.Run("invalid request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(`{"login":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, helper.ProblemContentType, rec.Header().Get("Content-Type"))
+		var problem helper.Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, helper.ProblemTypePrefix+"invalid-request", problem.Type)
+		assert.Contains(t, problem.Detail, "login")
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/unknown", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		// the response is sent as is, the mismatch is logged
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"current":1,"withdrawn":"not an amount"}`, rec.Body.String())
+		assert.Equal(t, 1, logs.FilterMessageSnippet("response does not match").Len())
+	})
+}
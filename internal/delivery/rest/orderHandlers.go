@@ -2,15 +2,18 @@ package rest
 
 import (
 	"context"
-	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"time"
 
-	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"github.com/Karzoug/loyalty_program/internal/service"
 	"github.com/goccy/go-json"
 	"go.uber.org/zap"
@@ -20,46 +23,31 @@ func (s *server) createOrderHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var hErr *helper.HandlerError
-	login, err := helper.GetLoginFromJWTInContext(ctx, s.logger)
+	login, err := auth.LoginFromContext(ctx)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			http.Error(w, hErr.Message, hErr.Code)
-		} else {
-			s.logger.Error("Create order handler: get login from context error", zap.Error(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		}
+		s.writeError(w, r, err, "Create order handler: get login from context error")
 		return
 	}
 
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.logger.Error("Create order handler: read request body error", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		s.writeError(w, r, err, "Create order handler: read request body error")
 		return
 	}
 	if len(data) == 0 {
-		http.Error(w, "order number empty", http.StatusBadRequest)
+		s.writeError(w, r, apierror.ErrEmptyOrderNumber, "Create order handler: validate request error")
 		return
 	}
 	number, err := strconv.ParseInt(string(data), 10, 64)
 	if err != nil {
-		http.Error(w, service.ErrInvalidOrderNumber.Error(), http.StatusUnprocessableEntity)
+		s.writeError(w, r, service.ErrInvalidOrderNumber, "Create order handler: parse order number error")
 		return
 	}
 	orderNumber := order.Number(number)
 
 	_, isExistedOrder, err := s.service.CreateOrder(ctx, *login, orderNumber)
 	if err != nil {
-		switch err {
-		case service.ErrInvalidOrderNumber:
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		case service.ErrAnotherUserOrderNumber:
-			http.Error(w, err.Error(), http.StatusConflict)
-		default:
-			s.logger.Error("Create order handler: create order service error", zap.Error(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		}
+		s.writeError(w, r, err, "Create order handler: create order service error")
 		return
 	}
 
@@ -73,7 +61,7 @@ func (s *server) createOrderHandler(w http.ResponseWriter, r *http.Request) {
 type orderResponse struct {
 	Number     string    `json:"number"`
 	Status     string    `json:"status"`
-	Accrual    float64   `json:"accrual"`
+	Accrual    amount    `json:"accrual"`
 	UploadedAt time.Time `json:"uploaded_at"`
 }
 
@@ -81,25 +69,44 @@ func (s *server) listUserOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var hErr *helper.HandlerError
-	login, err := helper.GetLoginFromJWTInContext(ctx, s.logger)
+	login, err := auth.LoginFromContext(ctx)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("List user orders handler: get login from context error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "List user orders handler: get login from context error")
 		return
 	}
 
-	orders, err := s.service.ListUserOrders(ctx, *login)
-	if err != nil {
-		s.logger.Error("List user orders handler: list user orders service error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		return
+	s.writeUserOrders(ctx, w, r, *login)
+}
+
+// writeUserOrders writes the user orders: the whole history or a page of it if the query asks for.
+func (s *server) writeUserOrders(ctx context.Context, w http.ResponseWriter, r *http.Request, login user.Login) {
+	var (
+		orders []order.Order
+		next   *storage.Cursor
+		err    error
+	)
+	if query := r.URL.Query(); isPageQuery(query) {
+		filter, err := parseOrderFilter(query)
+		if err != nil {
+			s.writeError(w, r, err, "List user orders handler: parse query error")
+			return
+		}
+		orders, next, err = s.service.ListUserOrdersPage(ctx, login, *filter)
+		if err != nil {
+			s.writeError(w, r, err, "List user orders handler: list user orders page service error")
+			return
+		}
+	} else {
+		orders, err = s.service.ListUserOrders(ctx, login)
+		if err != nil {
+			s.writeError(w, r, err, "List user orders handler: list user orders service error")
+			return
+		}
 	}
 
+	if next != nil {
+		w.Header().Set(nextCursorHeader, encodeCursor(*next))
+	}
 	if len(orders) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -110,19 +117,37 @@ func (s *server) listUserOrdersHandler(w http.ResponseWriter, r *http.Request) {
 		ordersResp = append(ordersResp, orderResponse{
 			Number:     strconv.FormatInt(int64(o.Number), 10),
 			Status:     o.Status.String(),
-			Accrual:    o.Accrual.InexactFloat64(),
+			Accrual:    s.newAmount(o.Accrual),
 			UploadedAt: o.UploadedAt,
 		})
 	}
-	sort.Slice(ordersResp, func(i, j int) bool {
+	sort.SliceStable(ordersResp, func(i, j int) bool {
 		return ordersResp[i].UploadedAt.Before(ordersResp[j].UploadedAt)
 	})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(ordersResp); err != nil {
-		s.logger.Error("List user orders handler: encode json response error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
+		s.log(r).Error("List user orders handler: encode json response error", zap.Error(err))
 		return
 	}
 }
+
+func parseOrderFilter(query url.Values) (*storage.OrderFilter, error) {
+	page, err := parsePageQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := parseQueryStatuses(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storage.OrderFilter{
+		Statuses: statuses,
+		From:     page.from,
+		To:       page.to,
+		After:    page.after,
+		Limit:    page.limit,
+	}, nil
+}
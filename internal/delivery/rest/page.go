@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+	// nextCursorHeader carries the cursor of the next page, it is absent on the last page.
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageQuery is a parsed ?limit=&cursor=&from=&to= query of a history list.
+type pageQuery struct {
+	limit    int
+	after    *storage.Cursor
+	from, to time.Time
+}
+
+// pageQueryParams are the query parameters that turn a history list into the paginated mode.
+var pageQueryParams = []string{"limit", "cursor", "status", "from", "to"}
+
+// isPageQuery reports whether the request asks for a paginated list,
+// otherwise the whole history is listed as before.
+func isPageQuery(query url.Values) bool {
+	for _, param := range pageQueryParams {
+		if query.Has(param) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePageQuery(query url.Values) (*pageQuery, error) {
+	page := pageQuery{limit: defaultPageLimit}
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return nil, badRequest("invalid limit: must be an integer in [1; " + strconv.Itoa(maxPageLimit) + "]")
+		}
+		page.limit = limit
+	}
+
+	if s := query.Get("cursor"); s != "" {
+		cursor, err := decodeCursor(s)
+		if err != nil {
+			return nil, badRequest(errInvalidCursor.Error())
+		}
+		page.after = cursor
+	}
+
+	var err error
+	if page.from, err = parseQueryTime(query, "from"); err != nil {
+		return nil, err
+	}
+	if page.to, err = parseQueryTime(query, "to"); err != nil {
+		return nil, err
+	}
+	if !page.from.IsZero() && !page.to.IsZero() && !page.from.Before(page.to) {
+		return nil, badRequest("invalid time range: from must be before to")
+	}
+
+	return &page, nil
+}
+
+// parseQueryStatuses parses ?status= given as a comma-separated list or repeated.
+func parseQueryStatuses(query url.Values) ([]order.Status, error) {
+	var statuses []order.Status
+	for _, value := range query["status"] {
+		for _, name := range strings.Split(value, ",") {
+			status, err := order.ParseStatus(strings.ToUpper(strings.TrimSpace(name)))
+			if err != nil {
+				return nil, badRequest(err.Error() + ": " + name)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+func parseQueryTime(query url.Values, param string) (time.Time, error) {
+	s := query.Get(param)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, badRequest("invalid " + param + ": must be RFC 3339 time")
+	}
+	return t.UTC(), nil
+}
+
+// encodeCursor returns an opaque representation of the cursor for clients.
+func encodeCursor(cursor storage.Cursor) string {
+	s := cursor.Time.UTC().Format(time.RFC3339Nano) + "_" + strconv.FormatInt(int64(cursor.Number), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeCursor(s string) (*storage.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	timePart, numberPart, ok := strings.Cut(string(data), "_")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, timePart)
+	if err != nil {
+		return nil, err
+	}
+	number, err := strconv.ParseInt(numberPart, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storage.Cursor{Time: t.UTC(), Number: order.Number(number)}, nil
+}
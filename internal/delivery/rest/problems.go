@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"go.uber.org/zap"
+)
+
+const invalidQueryProblem = "invalid-query"
+
+// kindCodes maps the kinds of errors to response codes, the names of the errors are the problem types.
+var kindCodes = map[apierror.Kind]int{
+	apierror.KindInvalidArgument:    http.StatusBadRequest,
+	apierror.KindUnprocessable:      http.StatusUnprocessableEntity,
+	apierror.KindUnauthenticated:    http.StatusUnauthorized,
+	apierror.KindPermissionDenied:   http.StatusForbidden,
+	apierror.KindNotFound:           http.StatusNotFound,
+	apierror.KindAlreadyExists:      http.StatusConflict,
+	apierror.KindFailedPrecondition: http.StatusConflict,
+	apierror.KindInsufficientFunds:  http.StatusPaymentRequired,
+	apierror.KindAborted:            http.StatusConflict,
+	apierror.KindResourceExhausted:  http.StatusTooManyRequests,
+}
+
+// problemOf returns the problem of the error, ok is false if the error is unknown.
+func problemOf(err error) (p helper.Problem, ok bool) {
+	var hErr *helper.HandlerError
+	if errors.As(err, &hErr) {
+		return helper.NewProblem(hErr.Type, hErr.Message, hErr.Code), true
+	}
+
+	c, ok := apierror.Classify(err)
+	if !ok {
+		return helper.Problem{}, false
+	}
+	code, ok := kindCodes[c.Kind]
+	if !ok {
+		return helper.Problem{}, false
+	}
+	return helper.NewProblem(c.Name, c.Message, code), true
+}
+
+// writeError writes the error as problem details. Unknown errors are logged with the message
+// and written as an internal server error without details.
+func (s *server) writeError(w http.ResponseWriter, r *http.Request, err error, logMsg string) {
+	p, ok := problemOf(err)
+	if !ok {
+		s.log(r).Error(logMsg, zap.Error(err))
+		p = helper.NewProblem("", "", http.StatusInternalServerError)
+	}
+
+	if seconds, ok := apierror.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", seconds)
+	}
+
+	helper.WriteProblem(w, r, p, s.logger)
+}
+
+func badRequest(message string) *helper.HandlerError {
+	return &helper.HandlerError{Type: invalidQueryProblem, Message: message, Code: http.StatusBadRequest}
+}
@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_kindCodes(t *testing.T) {
+	t.Parallel()
+
+	for kind := apierror.KindInternal + 1; kind <= apierror.KindResourceExhausted; kind++ {
+		_, ok := kindCodes[kind]
+		assert.True(t, ok, "kind %d has no response code", kind)
+	}
+}
+
+func Test_problemOf(t *testing.T) {
+	t.Parallel()
+
+	p, ok := problemOf(service.ErrInsufficientBalance)
+	require.True(t, ok)
+	assert.Equal(t, helper.ProblemTypePrefix+"insufficient-balance", p.Type)
+	assert.Equal(t, http.StatusPaymentRequired, p.Status)
+}
@@ -3,11 +3,16 @@ package rest
 import (
 	"context"
 	"net/http"
+	"net/netip"
+	"sync/atomic"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
 	"github.com/Karzoug/loyalty_program/internal/delivery/rest/middleware"
 	"github.com/Karzoug/loyalty_program/internal/service"
-
+	"github.com/Karzoug/loyalty_program/pkg/e"
+	"github.com/Karzoug/loyalty_program/pkg/jwtkeys"
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth"
 	"go.uber.org/zap"
@@ -19,7 +24,13 @@ const (
 
 type serverConfig interface {
 	RunAddress() string
-	SecretKey() string
+	JWTKeys() *jwtkeys.KeySet
+	AmountsAsStrings() bool
+	AccessTokenLifetime() time.Duration
+	ShutdownDelay() time.Duration
+	TrustedProxies() []netip.Prefix
+	OpenAPIValidation() bool
+	IsDebugMode() bool
 }
 
 type server struct {
@@ -27,7 +38,10 @@ type server struct {
 	logger  *zap.Logger
 	service *service.Service
 
-	server *http.Server
+	tokenAuth *jwtkeys.KeySet
+	server    *http.Server
+	// ready reports whether the server accepts requests, it is reset as soon as the shutdown starts
+	ready *atomic.Bool
 }
 
 func New(cfg serverConfig, service *service.Service, logger *zap.Logger) server {
@@ -36,14 +50,25 @@ func New(cfg serverConfig, service *service.Service, logger *zap.Logger) server
 		logger:  logger,
 		service: service,
 
-		server: &http.Server{Addr: cfg.RunAddress()},
+		tokenAuth: cfg.JWTKeys(),
+		server:    &http.Server{Addr: cfg.RunAddress()},
+		ready:     &atomic.Bool{},
 	}
 }
 
+// log returns the logger of the request, that writes the request ID.
+func (s *server) log(r *http.Request) *zap.Logger {
+	return logctx.FromContext(r.Context(), s.logger)
+}
+
 func (s *server) Run(ctx context.Context) error {
 	s.logger.Info("Running http server", zap.String("address", s.cfg.RunAddress()))
 
-	s.server.Handler = s.newRouter()
+	router, err := s.newRouter(ctx)
+	if err != nil {
+		return e.Wrap("create router", err)
+	}
+	s.server.Handler = router
 
 	go func() {
 		if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
@@ -51,8 +76,13 @@ func (s *server) Run(ctx context.Context) error {
 		}
 	}()
 
+	s.ready.Store(true)
+
 	<-ctx.Done()
 	s.logger.Info("Shutting down http server")
+	// report not ready before connections are drained, so no new requests are routed to the server
+	s.ready.Store(false)
+	time.Sleep(s.cfg.ShutdownDelay())
 
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -60,18 +90,47 @@ func (s *server) Run(ctx context.Context) error {
 	return s.server.Shutdown(ctxShutdown)
 }
 
-func (s *server) newRouter() chi.Router {
+func (s *server) newRouter(ctx context.Context) (chi.Router, error) {
 	r := chi.NewRouter()
 
+	r.Use(middleware.Tracer)
+	r.Use(middleware.RequestID(s.logger))
 	r.Use(middleware.Logger(s.logger))
 	r.Use(middleware.Recoverer(s.logger))
 
+	if s.cfg.OpenAPIValidation() {
+		doc, err := loadOpenAPI(ctx)
+		if err != nil {
+			return nil, e.Wrap("load OpenAPI specification", err)
+		}
+		validator, err := middleware.OpenAPIValidator(doc, s.cfg.IsDebugMode(), s.logger)
+		if err != nil {
+			return nil, e.Wrap("create OpenAPI validator", err)
+		}
+		r.Use(validator)
+	}
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		helper.WriteProblem(w, r, helper.NewProblem("", "", http.StatusNotFound), s.logger)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		helper.WriteProblem(w, r, helper.NewProblem("", "", http.StatusMethodNotAllowed), s.logger)
+	})
+
 	r.Post("/api/user/register", s.registerUserHandler)
 	r.Post("/api/user/login", s.loginUserHandler)
+	r.Post("/api/user/token/refresh", s.refreshTokenHandler)
+	r.Get("/.well-known/jwks.json", s.jwksHandler)
+	r.Get("/healthz", s.livenessHandler)
+	r.Get("/readyz", s.readinessHandler)
+	r.Get("/api/openapi.json", s.openAPIHandler)
 
 	r.Group(func(r chi.Router) {
-		r.Use(jwtauth.Verify(jwtauth.New("HS256", []byte(s.cfg.SecretKey()), nil), jwtauth.TokenFromHeader))
-		r.Use(jwtauth.Authenticator)
+		r.Use(s.tokenAuth.Verify(jwtauth.TokenFromHeader))
+		r.Use(s.authenticator)
+		r.Use(s.rejectRevokedTokens)
+		r.Post("/api/user/logout", s.logoutHandler)
+		r.Post("/api/user/password", s.changePasswordHandler)
 		r.Post("/api/user/orders", s.createOrderHandler)
 		r.Get("/api/user/orders", s.listUserOrdersHandler)
 		r.Get("/api/user/balance", s.getUserBalanceHandler)
@@ -79,5 +138,23 @@ func (s *server) newRouter() chi.Router {
 		r.Get("/api/user/withdrawals", s.listUserWithdrawalsHandler)
 	})
 
-	return r
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(s.tokenAuth.Verify(jwtauth.TokenFromHeader))
+		r.Use(s.authenticator)
+		r.Use(s.rejectRevokedTokens)
+		r.Use(s.adminOnly)
+		r.Get("/orders/dead-letters", s.listDeadLetterOrdersHandler)
+		r.Post("/orders/dead-letters/{number}/requeue", s.requeueDeadLetterOrderHandler)
+		r.Get("/users/{login}", s.getUserHandler)
+		r.Get("/users/{login}/orders", s.listOrdersOfUserHandler)
+		r.Get("/users/{login}/withdrawals", s.listWithdrawalsOfUserHandler)
+		r.Post("/users/{login}/balance/adjustments", s.adjustUserBalanceHandler)
+		r.Post("/users/{login}/block", s.blockUserHandler)
+		r.Post("/users/{login}/unblock", s.unblockUserHandler)
+		r.Post("/users/{login}/logout", s.revokeUserSessionsHandler)
+		r.Post("/orders/{number}/reprocess", s.reprocessOrderHandler)
+		r.Post("/tokens/{jti}/revoke", s.revokeTokenHandler)
+	})
+
+	return r, nil
 }
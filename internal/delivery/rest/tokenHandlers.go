@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/go-chi/jwtauth"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+type authResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// writeAuthTokens issues an access token with the user role for the session and writes it to the Authorization header,
+// both access and refresh tokens are written to the response body.
+func (s *server) writeAuthTokens(w http.ResponseWriter, ss session.Session, refreshToken string, role user.Role) error {
+	tokenString, err := auth.NewAccessToken(s.tokenAuth, ss, role, s.cfg.AccessTokenLifetime())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add(authHeaderKey, fmt.Sprintf(authHeaderValueFmt, tokenString))
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(authResponse{
+		AccessToken:  tokenString,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.cfg.AccessTokenLifetime().Seconds()),
+		RefreshToken: refreshToken,
+	})
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *server) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	var refreshReq refreshTokenRequest
+	err := helper.DecodeJSON(r, &refreshReq)
+	if err != nil {
+		s.writeError(w, r, err, "Refresh token handler: decode request from JSON error")
+		return
+	}
+	if refreshReq.RefreshToken == "" {
+		s.writeError(w, r, apierror.ErrEmptyRefreshToken, "Refresh token handler: validate request error")
+		return
+	}
+
+	ss, refreshToken, err := s.service.RefreshSession(ctx, refreshReq.RefreshToken)
+	if err != nil {
+		s.writeError(w, r, err, "Refresh token handler: refresh session service error")
+		return
+	}
+
+	// the role may be changed since the session is created
+	u, err := s.service.RefreshUser(ctx, ss.UserLogin)
+	if err != nil {
+		s.writeError(w, r, err, "Refresh token handler: refresh user service error")
+		return
+	}
+
+	if err := s.writeAuthTokens(w, *ss, refreshToken, u.Role); err != nil {
+		s.writeError(w, r, err, "Refresh token handler: write tokens to response error")
+		return
+	}
+}
+
+// logoutHandler revokes the session of the request token,
+// or all sessions of the user with ?all=true.
+func (s *server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	token, err := auth.FromContext(ctx)
+	if err != nil {
+		s.writeError(w, r, err, "Logout handler: get token from context error")
+		return
+	}
+
+	var all bool
+	if allString := r.URL.Query().Get("all"); allString != "" {
+		if all, err = strconv.ParseBool(allString); err != nil {
+			s.writeError(w, r, badRequest("invalid all: must be a boolean"), "Logout handler: parse query error")
+			return
+		}
+	}
+
+	if all {
+		err = s.service.RevokeUserSessions(ctx, token.Login)
+		if err == nil {
+			err = s.service.RevokeToken(ctx, token.ID, token.ExpiresAt)
+		}
+	} else {
+		err = s.service.Logout(ctx, token.Login, token.SessionID, token.ID, token.ExpiresAt)
+	}
+	if err != nil {
+		s.writeError(w, r, err, "Logout handler: logout service error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticator is a middleware like jwtauth.Authenticator: it rejects requests without a valid token,
+// but with the problem details response. The claims of the token are put into the context for the handlers.
+func (s *server) authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwtToken, _, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			s.writeError(w, r, service.ErrInvalidAuthData, "Authenticator middleware: verify token error")
+			return
+		}
+		token, err := auth.ParseToken(jwtToken)
+		if err != nil {
+			s.writeError(w, r, err, "Authenticator middleware: validate token error")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), token)))
+	})
+}
+
+// rejectRevokedTokens is a middleware that rejects access tokens revoked by themselves or by logout of their session.
+func (s *server) rejectRevokedTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.FromContext(r.Context())
+		if err != nil {
+			s.writeError(w, r, err, "Reject revoked tokens middleware: get token from context error")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		revoked, err := s.service.IsTokenRevoked(ctx, token.ID, token.SessionID)
+		if err != nil {
+			s.writeError(w, r, err, "Reject revoked tokens middleware: check token service error")
+			return
+		}
+		if revoked {
+			s.writeError(w, r, service.ErrInvalidAuthData, "Reject revoked tokens middleware: token revoked")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenExpiresAt is the latest expiry time of an access token issued now.
+func (s *server) tokenExpiresAt() time.Time {
+	return time.Now().UTC().Add(s.cfg.AccessTokenLifetime())
+}
+
+// jwksHandler publishes the public keys verifying access tokens, so other services can validate them.
+func (s *server) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(s.tokenAuth.JWKS()); err != nil {
+		s.log(r).Error("JWKS handler: write response error", zap.Error(err))
+	}
+}
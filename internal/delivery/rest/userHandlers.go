@@ -2,31 +2,22 @@ package rest
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"net/http"
-	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/auth"
+	"github.com/Karzoug/loyalty_program/internal/delivery/apierror"
 	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
-	"github.com/Karzoug/loyalty_program/internal/service"
-	"github.com/Karzoug/loyalty_program/pkg/e"
-	"github.com/go-chi/jwtauth"
+	"github.com/Karzoug/loyalty_program/pkg/realip"
 	"github.com/goccy/go-json"
 	"go.uber.org/zap"
 )
 
 const (
-	tokenLifetime      = 24 * time.Hour
 	authHeaderKey      = "Authorization"
 	authHeaderValueFmt = "BEARER %s"
 )
 
-var (
-	ErrEmptyLogin    = errors.New("login must be non empty")
-	ErrEmptyPassword = errors.New("password must be non empty")
-)
-
 type authRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
@@ -34,10 +25,10 @@ type authRequest struct {
 
 func (a authRequest) validate() error {
 	if a.Login == "" {
-		return ErrEmptyLogin
+		return apierror.ErrEmptyLogin
 	}
 	if a.Password == "" {
-		return ErrEmptyPassword
+		return apierror.ErrEmptyPassword
 	}
 	return nil
 }
@@ -46,43 +37,32 @@ func (s *server) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var (
-		authReq authRequest
-		hErr    *helper.HandlerError
-	)
+	var authReq authRequest
 	err := helper.DecodeJSON(r, &authReq)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("Register user handler: decode request from JSON error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Register user handler: decode request from JSON error")
 		return
 	}
 
 	if err := authReq.validate(); err != nil {
-		helper.WriteJSONError(w, err.Error(), http.StatusBadRequest, s.logger)
+		s.writeError(w, r, err, "Register user handler: validate request error")
 		return
 	}
 
 	u, err := s.service.RegisterUser(ctx, user.Login(authReq.Login), authReq.Password)
 	if err != nil {
-		switch err {
-		case service.ErrLoginAlreadyExists:
-			helper.WriteJSONError(w, err.Error(), http.StatusConflict, s.logger)
-		case service.ErrInvalidPasswordFormat, service.ErrInvalidLoginFormat:
-			helper.WriteJSONError(w, err.Error(), http.StatusBadRequest, s.logger)
-		default:
-			s.logger.Error("Register user handler: user register service error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Register user handler: user register service error")
 		return
 	}
 
-	if err := writeAuthToken(w, u.Login, s.cfg.SecretKey()); err != nil {
-		s.logger.Error("Register user handler: write token to response error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
+	ss, refreshToken, err := s.service.CreateSession(ctx, u.Login)
+	if err != nil {
+		s.writeError(w, r, err, "Register user handler: create session service error")
+		return
+	}
+
+	if err := s.writeAuthTokens(w, *ss, refreshToken, u.Role); err != nil {
+		s.writeError(w, r, err, "Register user handler: write tokens to response error")
 		return
 	}
 }
@@ -91,113 +71,123 @@ func (s *server) loginUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var (
-		authReq authRequest
-		hErr    *helper.HandlerError
-	)
+	var authReq authRequest
 	err := helper.DecodeJSON(r, &authReq)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("Login user handler: decode auth request from JSON error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Login user handler: decode auth request from JSON error")
 		return
 	}
 
 	if err := authReq.validate(); err != nil {
-		helper.WriteJSONError(w, err.Error(), http.StatusBadRequest, s.logger)
+		s.writeError(w, r, err, "Login user handler: validate request error")
 		return
 	}
 
-	u, err := s.service.LoginUser(ctx, user.Login(authReq.Login), authReq.Password)
+	u, err := s.service.LoginUser(ctx, user.Login(authReq.Login), authReq.Password, s.clientIP(r))
 	if err != nil {
-		switch err {
-		case service.ErrInvalidAuthData:
-			helper.WriteJSONError(w, err.Error(), http.StatusUnauthorized, s.logger)
-		default:
-			s.logger.Error("Login user handler: user login service error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Login user handler: user login service error")
 		return
 	}
 
-	if err := writeAuthToken(w, u.Login, s.cfg.SecretKey()); err != nil {
-		s.logger.Error("Login user handler: write token to response error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
+	ss, refreshToken, err := s.service.CreateSession(ctx, u.Login)
+	if err != nil {
+		s.writeError(w, r, err, "Login user handler: create session service error")
 		return
 	}
+
+	if err := s.writeAuthTokens(w, *ss, refreshToken, u.Role); err != nil {
+		s.writeError(w, r, err, "Login user handler: write tokens to response error")
+		return
+	}
+}
+
+// clientIP returns the IP of the client, it is taken from X-Forwarded-For behind trusted proxies.
+func (s *server) clientIP(r *http.Request) string {
+	return realip.ClientIP(r.RemoteAddr, r.Header.Values(realip.ForwardedForHeader), s.cfg.TrustedProxies())
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func (c changePasswordRequest) validate() error {
+	if c.OldPassword == "" {
+		return apierror.ErrEmptyOldPassword
+	}
+	if c.NewPassword == "" {
+		return apierror.ErrEmptyNewPassword
+	}
+	return nil
 }
 
-func writeAuthToken(w http.ResponseWriter, login user.Login, secretKey string) error {
-	tokenAuth := jwtauth.New("HS256", []byte(secretKey), nil)
+func (s *server) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
 
-	claims := make(map[string]interface{})
-	claims["sub"] = string(login)
-	jwtauth.SetExpiryIn(claims, tokenLifetime)
+	var changeReq changePasswordRequest
+	token, err := auth.FromContext(ctx)
+	if err != nil {
+		s.writeError(w, r, err, "Change password handler: get token from context error")
+		return
+	}
 
-	_, tokenString, err := tokenAuth.Encode(claims)
+	err = helper.DecodeJSON(r, &changeReq)
 	if err != nil {
-		return e.Wrap("encode JWT", err)
+		s.writeError(w, r, err, "Change password handler: decode request from JSON error")
+		return
+	}
+
+	if err := changeReq.validate(); err != nil {
+		s.writeError(w, r, err, "Change password handler: validate request error")
+		return
+	}
+
+	err = s.service.ChangePassword(ctx, token.Login, token.SessionID, changeReq.OldPassword, changeReq.NewPassword, s.clientIP(r))
+	if err != nil {
+		s.writeError(w, r, err, "Change password handler: change password service error")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Add(authHeaderKey, fmt.Sprintf(authHeaderValueFmt, tokenString))
 	w.WriteHeader(http.StatusOK)
-	return nil
 }
 
 type balanceResponse struct {
-	Balance   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Balance   amount `json:"current"`
+	Withdrawn amount `json:"withdrawn"`
 }
 
 func (s *server) getUserBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var hErr *helper.HandlerError
-	login, err := helper.GetLoginFromJWTInContext(ctx, s.logger)
+	login, err := auth.LoginFromContext(ctx)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("Create order handler: get login from context error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Create order handler: get login from context error")
 		return
 	}
 
 	balance, err := s.service.GetUserBalance(ctx, *login)
 	if err != nil {
-		switch err {
-		case service.ErrInvalidAuthData:
-			helper.WriteJSONError(w, err.Error(), http.StatusUnauthorized, s.logger)
-		default:
-			s.logger.Error("Get user balance handler: user balance service error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Get user balance handler: user balance service error")
 		return
 	}
 
 	sum, err := s.service.SumUserWithdrawals(ctx, *login)
 	if err != nil {
-		s.logger.Error("Get user balance handler: withdrawals sum service error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
+		s.writeError(w, r, err, "Get user balance handler: withdrawals sum service error")
 		return
 	}
 
 	balanceResp := balanceResponse{
-		Balance:   balance.InexactFloat64(),
-		Withdrawn: sum.InexactFloat64(),
+		Balance:   s.newAmount(*balance),
+		Withdrawn: s.newAmount(*sum),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(balanceResp); err != nil {
-		s.logger.Error("Get user balance handler: encode json response error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
+		s.log(r).Error("Get user balance handler: encode json response error", zap.Error(err))
 		return
 	}
 }
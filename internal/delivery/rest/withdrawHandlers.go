@@ -2,27 +2,26 @@ package rest
 
 import (
 	"context"
-	"errors"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/auth"
 	"github.com/Karzoug/loyalty_program/internal/delivery/rest/helper"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/model/withdraw"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"github.com/Karzoug/loyalty_program/internal/service"
 	"github.com/goccy/go-json"
-	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
-var (
-	ErrInvalidSum = errors.New("invalid sum")
-)
-
 type withdrawResponse struct {
 	Order       string    `json:"order"`
-	Sum         float64   `json:"sum"`
+	Sum         amount    `json:"sum"`
 	ProcessedAt time.Time `json:"processed_at"`
 }
 
@@ -30,25 +29,44 @@ func (s *server) listUserWithdrawalsHandler(w http.ResponseWriter, r *http.Reque
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var hErr *helper.HandlerError
-	login, err := helper.GetLoginFromJWTInContext(ctx, s.logger)
+	login, err := auth.LoginFromContext(ctx)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("List user withdrawals handler: get login from context error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "List user withdrawals handler: get login from context error")
 		return
 	}
 
-	ws, err := s.service.ListUserWithdrawals(ctx, *login)
-	if err != nil {
-		s.logger.Error("List user withdrawals handler: list withdrawals service error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		return
+	s.writeUserWithdrawals(ctx, w, r, *login)
+}
+
+// writeUserWithdrawals writes the user withdrawals: the whole history or a page of it if the query asks for.
+func (s *server) writeUserWithdrawals(ctx context.Context, w http.ResponseWriter, r *http.Request, login user.Login) {
+	var (
+		ws   []withdraw.Withdraw
+		next *storage.Cursor
+		err  error
+	)
+	if query := r.URL.Query(); isPageQuery(query) {
+		filter, err := parseWithdrawFilter(query)
+		if err != nil {
+			s.writeError(w, r, err, "List user withdrawals handler: parse query error")
+			return
+		}
+		ws, next, err = s.service.ListUserWithdrawalsPage(ctx, login, *filter)
+		if err != nil {
+			s.writeError(w, r, err, "List user withdrawals handler: list withdrawals page service error")
+			return
+		}
+	} else {
+		ws, err = s.service.ListUserWithdrawals(ctx, login)
+		if err != nil {
+			s.writeError(w, r, err, "List user withdrawals handler: list withdrawals service error")
+			return
+		}
 	}
 
+	if next != nil {
+		w.Header().Set(nextCursorHeader, encodeCursor(*next))
+	}
 	if len(ws) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -58,31 +76,47 @@ func (s *server) listUserWithdrawalsHandler(w http.ResponseWriter, r *http.Reque
 	for _, w := range ws {
 		withdrawalsResp = append(withdrawalsResp, withdrawResponse{
 			Order:       strconv.FormatInt(int64(w.OrderNumber), 10),
-			Sum:         w.Sum.InexactFloat64(),
+			Sum:         s.newAmount(w.Sum),
 			ProcessedAt: w.ProcessedAt,
 		})
 	}
-	sort.Slice(withdrawalsResp, func(i, j int) bool {
+	sort.SliceStable(withdrawalsResp, func(i, j int) bool {
 		return withdrawalsResp[i].ProcessedAt.Before(withdrawalsResp[j].ProcessedAt)
 	})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(withdrawalsResp); err != nil {
-		s.logger.Error("List user withdrawals handler: encode json response error", zap.Error(err))
-		helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
+		s.log(r).Error("List user withdrawals handler: encode json response error", zap.Error(err))
 		return
 	}
 }
 
+func parseWithdrawFilter(query url.Values) (*storage.WithdrawFilter, error) {
+	if query.Has("status") {
+		return nil, badRequest("status filter is not supported for withdrawals")
+	}
+	page, err := parsePageQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storage.WithdrawFilter{
+		From:  page.from,
+		To:    page.to,
+		After: page.after,
+		Limit: page.limit,
+	}, nil
+}
+
 type withdrawRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string `json:"order"`
+	Sum   amount `json:"sum"`
 }
 
 func (r withdrawRequest) validate() error {
-	if r.Sum <= 0 {
-		return ErrInvalidSum
+	if !r.Sum.value.IsPositive() {
+		return service.ErrInvalidSum
 	}
 	return nil
 }
@@ -91,56 +125,33 @@ func (s *server) createWithdrawHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
 	defer cancel()
 
-	var hErr *helper.HandlerError
-	login, err := helper.GetLoginFromJWTInContext(ctx, s.logger)
+	login, err := auth.LoginFromContext(ctx)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("Create withdraw handler: get login from context error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Create withdraw handler: get login from context error")
 		return
 	}
 
 	var withdrawReq withdrawRequest
 	err = helper.DecodeJSON(r, &withdrawReq)
 	if err != nil {
-		if errors.As(err, &hErr) {
-			helper.WriteJSONError(w, hErr.Message, hErr.Code, s.logger)
-		} else {
-			s.logger.Error("Create withdraw handler: decode withdraw request from JSON error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Create withdraw handler: decode withdraw request from JSON error")
 		return
 	}
 
 	if err := withdrawReq.validate(); err != nil {
-		helper.WriteJSONError(w, err.Error(), http.StatusBadRequest, s.logger)
+		s.writeError(w, r, err, "Create withdraw handler: validate request error")
 		return
 	}
 	number, err := strconv.ParseInt(withdrawReq.Order, 10, 64)
 	if err != nil {
-		helper.WriteJSONError(w, service.ErrInvalidOrderNumber.Error(), http.StatusUnprocessableEntity, s.logger)
+		s.writeError(w, r, service.ErrInvalidOrderNumber, "Create withdraw handler: parse order number error")
 		return
 	}
 	orderNumber := order.Number(number)
 
-	_, err = s.service.CreateWithdraw(ctx, *login, orderNumber, decimal.NewFromFloat(withdrawReq.Sum))
+	_, err = s.service.CreateWithdraw(ctx, *login, orderNumber, withdrawReq.Sum.value)
 	if err != nil {
-		switch err {
-		case service.ErrInvalidAuthData:
-			helper.WriteJSONError(w, err.Error(), http.StatusUnauthorized, s.logger)
-		case service.ErrInvalidOrderNumber:
-			helper.WriteJSONError(w, err.Error(), http.StatusUnprocessableEntity, s.logger)
-		case service.ErrInsufficientBalance:
-			helper.WriteJSONError(w, err.Error(), http.StatusPaymentRequired, s.logger)
-		case service.ErrAnotherUserOrderNumber, service.ErrReAttemptWithdraw:
-			helper.WriteJSONError(w, err.Error(), http.StatusConflict, s.logger)
-		default:
-			s.logger.Error("Create withdraw handler: create withdraw service error", zap.Error(err))
-			helper.WriteJSONError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, s.logger)
-		}
+		s.writeError(w, r, err, "Create withdraw handler: create withdraw service error")
 		return
 	}
 
@@ -0,0 +1,126 @@
+// Package metrics provides the application Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gophermart"
+
+// Registry is a registry of all the application metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts served requests by method, chi route pattern and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of served HTTP requests.",
+	}, []string{"method", "route", "code"})
+	// HTTPRequestDuration observes request latencies by method and chi route pattern.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// GRPCRequests counts served gRPC calls by full method name and status code.
+	GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of served gRPC calls.",
+	}, []string{"method", "code"})
+	// GRPCRequestDuration observes gRPC call latencies by full method name.
+	GRPCRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served gRPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// AccrualRequests counts requests to the accrual service by response status code
+	// ("error" - no response was received).
+	AccrualRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "requests_total",
+		Help:      "Number of requests to the accrual service.",
+	}, []string{"code"})
+	// AccrualRetries counts repeated attempts to get an order from the accrual service.
+	AccrualRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "retries_total",
+		Help:      "Number of repeated requests to the accrual service.",
+	})
+	// AccrualRateLimit is the current requests per second limit of the accrual service client.
+	AccrualRateLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "rate_limit",
+		Help:      "Current requests per second limit of the accrual service client.",
+	})
+	// AccrualBackoffUntil is the unix time until all requests to the accrual service wait.
+	AccrualBackoffUntil = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "backoff_until_seconds",
+		Help:      "Unix time until requests to the accrual service are postponed.",
+	})
+
+	// OrderBacklog is the number of orders waiting for processing.
+	OrderBacklog = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "backlog",
+		Help:      "Number of orders waiting for processing.",
+	})
+	// OrderProcessingDuration observes durations of order processing attempts.
+	OrderProcessingDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "processing_duration_seconds",
+		Help:      "Duration of order processing attempts.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// TransactionFailures counts failed database transactions by the failed operation: begin or commit.
+	TransactionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transaction_failures_total",
+		Help:      "Number of failed database transactions.",
+	}, []string{"operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		GRPCRequests,
+		GRPCRequestDuration,
+		AccrualRequests,
+		AccrualRetries,
+		AccrualRateLimit,
+		AccrualBackoffUntil,
+		OrderBacklog,
+		OrderProcessingDuration,
+		TransactionFailures,
+	)
+}
+
+// Handler returns a handler exposing the registry metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquireCount = prometheus.NewDesc(namespace+"_db_pool_acquire_total",
+		"Number of successful connection acquires from the pool.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(namespace+"_db_pool_acquire_duration_seconds_total",
+		"Total time spent on successful connection acquires from the pool.", nil, nil)
+	poolCanceledAcquireCount = prometheus.NewDesc(namespace+"_db_pool_canceled_acquire_total",
+		"Number of connection acquires from the pool canceled by a context.", nil, nil)
+	poolEmptyAcquireCount = prometheus.NewDesc(namespace+"_db_pool_empty_acquire_total",
+		"Number of connection acquires from the pool that waited for a connection because the pool was empty.", nil, nil)
+	poolAcquiredConns = prometheus.NewDesc(namespace+"_db_pool_acquired_connections",
+		"Number of currently acquired connections.", nil, nil)
+	poolIdleConns = prometheus.NewDesc(namespace+"_db_pool_idle_connections",
+		"Number of currently idle connections.", nil, nil)
+	poolTotalConns = prometheus.NewDesc(namespace+"_db_pool_connections",
+		"Number of connections in the pool.", nil, nil)
+	poolMaxConns = prometheus.NewDesc(namespace+"_db_pool_max_connections",
+		"Max size of the pool.", nil, nil)
+)
+
+// poolCollector collects pgxpool statistics on each scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+// RegisterPool registers a collector of the pool statistics in the registry.
+func RegisterPool(reg prometheus.Registerer, pool *pgxpool.Pool) error {
+	return reg.Register(poolCollector{pool: pool})
+}
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquireCount
+	ch <- poolAcquireDuration
+	ch <- poolCanceledAcquireCount
+	ch <- poolEmptyAcquireCount
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+}
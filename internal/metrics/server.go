@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server serves the metrics of the registry on its own address,
+// so they are not exposed on the public API listeners.
+type Server struct {
+	logger *zap.Logger
+	server *http.Server
+}
+
+// NewServer returns a server of the metrics on the address.
+func NewServer(address string, logger *zap.Logger) Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	return Server{
+		logger: logger,
+		server: &http.Server{Addr: address, Handler: mux},
+	}
+}
+
+func (s Server) Run(ctx context.Context) error {
+	s.logger.Info("Running metrics server", zap.String("address", s.server.Addr))
+
+	go func() {
+		if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+			s.logger.Fatal("Metrics server listen and serve error", zap.Error(err))
+		}
+	}()
+
+	<-ctx.Done()
+	s.logger.Info("Shutting down metrics server")
+
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return s.server.Shutdown(ctxShutdown)
+}
@@ -0,0 +1,30 @@
+package job
+
+import (
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+)
+
+// DeadLetter is a job that is not processed anymore, since its order
+// is never going to be processed by the accrual system.
+type DeadLetter struct {
+	OrderNumber order.Number
+	Attempts    int
+	Reason      string
+
+	CreatedAt      time.Time
+	DeadLetteredAt time.Time
+}
+
+// NewDeadLetter creates a new DeadLetter from the job with the reason it is not processed anymore.
+func NewDeadLetter(j Job, reason string) *DeadLetter {
+	return &DeadLetter{
+		OrderNumber: j.OrderNumber,
+		Attempts:    j.Attempts,
+		Reason:      reason,
+
+		CreatedAt:      j.CreatedAt,
+		DeadLetteredAt: time.Now().UTC(),
+	}
+}
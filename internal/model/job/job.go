@@ -0,0 +1,38 @@
+package job
+
+import (
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+)
+
+// Job is a persistent task to process an order.
+// The processing schedule (attempts, next check time and last error) is kept with the order.
+type Job struct {
+	OrderNumber order.Number
+	// Attempts is a number of consecutive failed attempts to process the order.
+	Attempts    int
+	NextCheckAt time.Time
+	LastError   string
+	// TraceParent is a W3C traceparent of the order upload, order processing spans are linked to it.
+	TraceParent string
+	// RequestID is an ID of the request the job is created by, it correlates the order processing logs with the request.
+	RequestID string
+
+	CreatedAt time.Time
+}
+
+// New creates a new Job, ready to be run as soon as possible and inserted into repository.
+func New(number order.Number) (*Job, error) {
+	if !number.Valid() {
+		return nil, order.ErrInvalidNumber
+	}
+
+	now := time.Now().UTC()
+	return &Job{
+		OrderNumber: number,
+		NextCheckAt: now,
+
+		CreatedAt: now,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid ledger entry amount: sign does not match the operation")
+	ErrEmptyReason   = errors.New("ledger adjustment reason must be non empty")
+)
+
+// Entry is an immutable record of a user balance change.
+type Entry struct {
+	ID          string
+	UserLogin   user.Login
+	OrderNumber order.Number
+	Operation   Operation
+	Amount      decimal.Decimal // signed: positive amount increases the balance, negative decreases
+	// Reason and CreatedBy explain manual adjustments: why and by which admin the balance is changed.
+	Reason    string
+	CreatedBy user.Login
+
+	CreatedAt time.Time
+}
+
+// New creates a new ledger Entry, ready to be inserted into repository.
+func New(login user.Login, number order.Number, op Operation, amount decimal.Decimal) (*Entry, error) {
+	if !login.Valid() {
+		return nil, user.ErrInvalidLogin
+	}
+	if !number.Valid() {
+		return nil, order.ErrInvalidNumber
+	}
+
+	switch op {
+	case OperationAccrual:
+		if !amount.IsPositive() {
+			return nil, ErrInvalidAmount
+		}
+	case OperationWithdrawal:
+		if !amount.IsNegative() {
+			return nil, ErrInvalidAmount
+		}
+	default:
+		if amount.IsZero() {
+			return nil, ErrInvalidAmount
+		}
+	}
+
+	return &Entry{
+		ID:          uuid.NewString(),
+		UserLogin:   login,
+		OrderNumber: number,
+		Operation:   op,
+		Amount:      amount,
+
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// NewAdjustment creates a new manual balance adjustment made by the admin, it is not related to any order.
+func NewAdjustment(login user.Login, amount decimal.Decimal, reason string, admin user.Login) (*Entry, error) {
+	if !login.Valid() || !admin.Valid() {
+		return nil, user.ErrInvalidLogin
+	}
+	if amount.IsZero() {
+		return nil, ErrInvalidAmount
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrEmptyReason
+	}
+
+	return &Entry{
+		ID:        uuid.NewString(),
+		UserLogin: login,
+		Operation: OperationAdjustment,
+		Amount:    amount,
+		Reason:    reason,
+		CreatedBy: admin,
+
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
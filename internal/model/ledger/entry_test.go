@@ -0,0 +1,113 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	const validNumber order.Number = 12345678903
+
+	tests := []struct {
+		name    string
+		login   user.Login
+		number  order.Number
+		op      Operation
+		amount  decimal.Decimal
+		wantErr error
+	}{
+		{
+			name:    "positive: accrual",
+			login:   "Jezebel",
+			number:  validNumber,
+			op:      OperationAccrual,
+			amount:  decimal.NewFromInt(500),
+			wantErr: nil,
+		},
+		{
+			name:    "positive: withdrawal",
+			login:   "Jezebel",
+			number:  validNumber,
+			op:      OperationWithdrawal,
+			amount:  decimal.NewFromInt(-500),
+			wantErr: nil,
+		},
+		{
+			name:    "positive: negative adjustment",
+			login:   "Jezebel",
+			number:  validNumber,
+			op:      OperationAdjustment,
+			amount:  decimal.NewFromInt(-10),
+			wantErr: nil,
+		},
+		{
+			name:    "negative: negative accrual",
+			login:   "Jezebel",
+			number:  validNumber,
+			op:      OperationAccrual,
+			amount:  decimal.NewFromInt(-500),
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name:    "negative: positive withdrawal",
+			login:   "Jezebel",
+			number:  validNumber,
+			op:      OperationWithdrawal,
+			amount:  decimal.NewFromInt(500),
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name:    "negative: zero reversal",
+			login:   "Jezebel",
+			number:  validNumber,
+			op:      OperationReversal,
+			amount:  decimal.Zero,
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name:    "negative: invalid order number",
+			login:   "Jezebel",
+			number:  validNumber + 1,
+			op:      OperationAccrual,
+			amount:  decimal.NewFromInt(500),
+			wantErr: order.ErrInvalidNumber,
+		},
+		{
+			name:    "negative: empty login",
+			login:   "",
+			number:  validNumber,
+			op:      OperationAccrual,
+			amount:  decimal.NewFromInt(500),
+			wantErr: user.ErrInvalidLogin,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := New(tt.login, tt.number, tt.op, tt.amount)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, entry.ID)
+			assert.True(t, tt.amount.Equal(entry.Amount))
+		})
+	}
+}
+
+func TestNewAdjustment(t *testing.T) {
+	entry, err := NewAdjustment("Jezebel", decimal.NewFromInt(-10), "compensation", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, OperationAdjustment, entry.Operation)
+	assert.Equal(t, order.Number(0), entry.OrderNumber)
+	assert.Equal(t, user.Login("admin"), entry.CreatedBy)
+
+	_, err = NewAdjustment("Jezebel", decimal.Zero, "compensation", "admin")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	_, err = NewAdjustment("Jezebel", decimal.NewFromInt(10), " ", "admin")
+	assert.ErrorIs(t, err, ErrEmptyReason)
+}
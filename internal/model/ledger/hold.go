@@ -0,0 +1,19 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/shopspring/decimal"
+)
+
+// Hold is an accrual of a processed order held instead of credited while the user is blocked,
+// it is credited when the user is unblocked.
+type Hold struct {
+	OrderNumber order.Number
+	UserLogin   user.Login
+	Amount      decimal.Decimal
+
+	CreatedAt time.Time
+}
@@ -0,0 +1,14 @@
+package ledger
+
+type Operation int8
+
+const (
+	OperationAccrual Operation = iota
+	OperationWithdrawal
+	OperationAdjustment
+	OperationReversal
+)
+
+func (o Operation) String() string {
+	return [...]string{"ACCRUAL", "WITHDRAWAL", "ADJUSTMENT", "REVERSAL"}[o]
+}
@@ -0,0 +1,75 @@
+package lockout
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+)
+
+// FailuresTTL is a time after the last failed attempt when failures are forgotten.
+const FailuresTTL = 24 * time.Hour
+
+var ErrInvalidPolicy = errors.New("invalid lockout policy: max attempts and delays must be positive, base delay must not exceed max delay")
+
+// Attempts are failed login attempts of a login or from a client IP.
+type Attempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	// LockedUntil is a time when the next attempt is allowed (zero - not locked).
+	LockedUntil time.Time
+}
+
+// RetryAfter returns the duration until the next attempt is allowed (0 - it is allowed now).
+func (a Attempts) RetryAfter(now time.Time) time.Duration {
+	if !now.Before(a.LockedUntil) {
+		return 0
+	}
+	return a.LockedUntil.Sub(now)
+}
+
+// LoginKey is a key of attempts to log in as the user.
+func LoginKey(login user.Login) string {
+	return "login:" + string(login)
+}
+
+// IPKey is a key of attempts to log in from the client IP.
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Policy is a progressive lockout: after max attempts each next failure locks
+// further attempts for twice as long as the previous one, from the base delay up to the max delay.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func NewPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) (Policy, error) {
+	if maxAttempts <= 0 || baseDelay <= 0 || maxDelay < baseDelay {
+		return Policy{}, ErrInvalidPolicy
+	}
+	return Policy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+	}, nil
+}
+
+// Lockout returns the duration further attempts are locked for after the failures count.
+func (p Policy) Lockout(failures int) time.Duration {
+	if failures < p.MaxAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.MaxAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
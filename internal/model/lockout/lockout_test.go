@@ -0,0 +1,45 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Lockout(t *testing.T) {
+	p, err := NewPolicy(3, time.Second, 10*time.Second)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Second},
+		{failures: 4, want: 2 * time.Second},
+		{failures: 6, want: 8 * time.Second},
+		{failures: 7, want: 10 * time.Second},
+		{failures: 1000, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, p.Lockout(tt.failures), tt.failures)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	_, err := NewPolicy(0, time.Second, time.Minute)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+	_, err = NewPolicy(5, 0, time.Minute)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+	_, err = NewPolicy(5, time.Minute, time.Second)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
+
+func TestAttempts_RetryAfter(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, time.Duration(0), Attempts{}.RetryAfter(now))
+	assert.Equal(t, time.Duration(0), Attempts{LockedUntil: now.Add(-time.Second)}.RetryAfter(now))
+	assert.Equal(t, time.Second, Attempts{LockedUntil: now.Add(time.Second)}.RetryAfter(now))
+}
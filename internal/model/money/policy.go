@@ -0,0 +1,82 @@
+package money
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const MaxScale = 8
+
+var (
+	ErrInvalidScale    = errors.New("invalid scale")
+	ErrInvalidRounding = errors.New("invalid rounding mode")
+	ErrInvalidSum      = errors.New("invalid sum: must be positive")
+	ErrTooPreciseSum   = errors.New("invalid sum: too many decimal places")
+)
+
+// DefaultPolicy keeps amounts in kopecks rounding half away from zero.
+var DefaultPolicy = Policy{Scale: 2, Rounding: RoundHalfUp}
+
+type Rounding int8
+
+const (
+	// RoundHalfUp rounds half away from zero.
+	RoundHalfUp Rounding = iota
+	// RoundHalfEven rounds half to the nearest even digit (bankers rounding).
+	RoundHalfEven
+	// RoundDown truncates towards zero.
+	RoundDown
+)
+
+func (r Rounding) String() string {
+	return [...]string{"half-up", "half-even", "down"}[r]
+}
+
+// ParseRounding returns the rounding mode by its name.
+func ParseRounding(s string) (Rounding, error) {
+	switch strings.ToLower(s) {
+	case "half-up":
+		return RoundHalfUp, nil
+	case "half-even":
+		return RoundHalfEven, nil
+	case "down":
+		return RoundDown, nil
+	default:
+		return 0, ErrInvalidRounding
+	}
+}
+
+// Policy is a number of decimal places and a rounding mode points amounts are kept with.
+type Policy struct {
+	Scale    int32
+	Rounding Rounding
+}
+
+func NewPolicy(scale int32, rounding Rounding) (Policy, error) {
+	if scale < 0 || scale > MaxScale {
+		return Policy{}, ErrInvalidScale
+	}
+	if rounding < RoundHalfUp || rounding > RoundDown {
+		return Policy{}, ErrInvalidRounding
+	}
+	return Policy{Scale: scale, Rounding: rounding}, nil
+}
+
+// Round returns the amount rounded according to the policy.
+func (p Policy) Round(d decimal.Decimal) decimal.Decimal {
+	switch p.Rounding {
+	case RoundHalfEven:
+		return d.RoundBank(p.Scale)
+	case RoundDown:
+		return d.Truncate(p.Scale)
+	default:
+		return d.Round(p.Scale)
+	}
+}
+
+// Exact reports whether the amount has no more decimal places than the policy scale, so it is kept as is.
+func (p Policy) Exact(d decimal.Decimal) bool {
+	return d.Equal(d.Truncate(p.Scale))
+}
@@ -0,0 +1,79 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Round(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		amount string
+		want   string
+	}{
+		{
+			name:   "half up",
+			policy: Policy{Scale: 2, Rounding: RoundHalfUp},
+			amount: "10.125",
+			want:   "10.13",
+		},
+		{
+			name:   "half up: negative",
+			policy: Policy{Scale: 2, Rounding: RoundHalfUp},
+			amount: "-10.125",
+			want:   "-10.13",
+		},
+		{
+			name:   "half even",
+			policy: Policy{Scale: 2, Rounding: RoundHalfEven},
+			amount: "10.125",
+			want:   "10.12",
+		},
+		{
+			name:   "down",
+			policy: Policy{Scale: 1, Rounding: RoundDown},
+			amount: "10.19",
+			want:   "10.1",
+		},
+		{
+			name:   "exact value is kept",
+			policy: DefaultPolicy,
+			amount: "0.1",
+			want:   "0.1",
+		},
+		{
+			name:   "integer scale",
+			policy: Policy{Scale: 0, Rounding: RoundHalfUp},
+			amount: "729.98",
+			want:   "730",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Round(decimal.RequireFromString(tt.amount))
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestPolicy_Exact(t *testing.T) {
+	policy := Policy{Scale: 2, Rounding: RoundHalfUp}
+
+	assert.True(t, policy.Exact(decimal.RequireFromString("10.1")))
+	assert.True(t, policy.Exact(decimal.RequireFromString("10.120")))
+	assert.False(t, policy.Exact(decimal.RequireFromString("10.005")))
+}
+
+func TestParseRounding(t *testing.T) {
+	for _, r := range []Rounding{RoundHalfUp, RoundHalfEven, RoundDown} {
+		got, err := ParseRounding(r.String())
+		assert.NoError(t, err)
+		assert.Equal(t, r, got)
+	}
+
+	_, err := ParseRounding("ceil")
+	assert.ErrorIs(t, err, ErrInvalidRounding)
+}
@@ -10,7 +10,7 @@ import (
 type Order struct {
 	Number    Number
 	UserLogin user.Login
-	Status    status
+	Status    Status
 	Accrual   decimal.Decimal
 
 	UploadedAt time.Time
@@ -1,14 +1,35 @@
 package order
 
-type status int8
+import "errors"
+
+var ErrInvalidStatus = errors.New("invalid order status")
+
+type Status int8
 
 const (
-	StatusNew status = iota
+	StatusNew Status = iota
 	StatusProcessing
 	StatusInvalid
 	StatusProcessed
 )
 
-func (s status) String() string {
-	return [...]string{"NEW", "PROCESSING", "INVALID", "PROCESSED"}[s]
+var statusNames = [...]string{"NEW", "PROCESSING", "INVALID", "PROCESSED"}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+// Final reports whether the order with the status needs no more processing.
+func (s Status) Final() bool {
+	return s == StatusInvalid || s == StatusProcessed
+}
+
+// ParseStatus returns the status by its name, e.g. "PROCESSED".
+func ParseStatus(name string) (Status, error) {
+	for i, n := range statusNames {
+		if n == name {
+			return Status(i), nil
+		}
+	}
+	return 0, ErrInvalidStatus
 }
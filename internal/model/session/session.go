@@ -0,0 +1,86 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/google/uuid"
+)
+
+const refreshTokenSize = 32
+
+// Session is a user login on a device. It lasts as long as its refresh token is rotated in time.
+// Only a hash of the refresh token is stored, the token itself is given to the user.
+type Session struct {
+	ID        string
+	UserLogin user.Login
+	// RefreshTokenHash is a hash of the current refresh token.
+	RefreshTokenHash string
+	// PreviousTokenHash is a hash of the rotated refresh token,
+	// its reuse means that the token was stolen.
+	PreviousTokenHash string
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// RevokedAt is a time of logout (zero - the session is not revoked).
+	RevokedAt time.Time
+}
+
+// New creates a new Session with the refresh token valid for the lifetime.
+func New(login user.Login, lifetime time.Duration) (*Session, string, error) {
+	if !login.Valid() {
+		return nil, "", user.ErrInvalidLogin
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	return &Session{
+		ID:               uuid.NewString(),
+		UserLogin:        login,
+		RefreshTokenHash: HashToken(refreshToken),
+
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}, refreshToken, nil
+}
+
+// Rotate replaces the refresh token with a new one valid for the lifetime and returns it.
+func (s *Session) Rotate(lifetime time.Duration) (string, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.PreviousTokenHash = s.RefreshTokenHash
+	s.RefreshTokenHash = HashToken(refreshToken)
+	s.ExpiresAt = time.Now().UTC().Add(lifetime)
+
+	return refreshToken, nil
+}
+
+// Active reports whether the session is neither revoked nor expired at the time.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+// HashToken returns a hash of the refresh token to be stored instead of the token.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
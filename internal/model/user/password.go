@@ -0,0 +1,148 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+
+	// MaxPasswordLength is a max length of a password in bytes, it is limited by bcrypt.
+	MaxPasswordLength = 72
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	ErrInvalidPasswordPolicy = errors.New("invalid password policy")
+	errUnknownPasswordHash   = errors.New("unknown password hash format")
+)
+
+// DefaultPasswordPolicy hashes passwords with bcrypt of the default cost.
+var DefaultPasswordPolicy = PasswordPolicy{Algorithm: HashBcrypt, BcryptCost: bcrypt.DefaultCost}
+
+// Argon2Params are argon2id parameters: iterations, memory in KiB and parallelism.
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// PasswordPolicy is an algorithm and its parameters new password hashes are made with.
+type PasswordPolicy struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+func NewPasswordPolicy(algorithm string, bcryptCost int, argon2Params Argon2Params) (PasswordPolicy, error) {
+	switch algorithm {
+	case HashBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return PasswordPolicy{}, fmt.Errorf("%w: bcrypt cost must be in [%d; %d]", ErrInvalidPasswordPolicy, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return PasswordPolicy{Algorithm: HashBcrypt, BcryptCost: bcryptCost}, nil
+	case HashArgon2id:
+		if argon2Params.Time == 0 || argon2Params.Memory < 8*uint32(argon2Params.Threads) || argon2Params.Threads == 0 {
+			return PasswordPolicy{}, fmt.Errorf("%w: argon2id time and threads must be positive, memory must be at least 8 KiB per thread", ErrInvalidPasswordPolicy)
+		}
+		return PasswordPolicy{Algorithm: HashArgon2id, Argon2: argon2Params}, nil
+	default:
+		return PasswordPolicy{}, fmt.Errorf("%w: algorithm must be one of %s, %s", ErrInvalidPasswordPolicy, HashBcrypt, HashArgon2id)
+	}
+}
+
+// Hash returns an encoded hash of the password, the encoding keeps the algorithm and its parameters.
+func (p PasswordPolicy) Hash(password string) (string, error) {
+	if len(password) == 0 || len(password) > MaxPasswordLength {
+		return "", ErrBadPassword
+	}
+
+	switch p.Algorithm {
+	case HashArgon2id:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Argon2.Time, p.Argon2.Memory, p.Argon2.Threads, argon2KeyLength)
+		return encodeArgon2(p.Argon2, salt, key), nil
+	default:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+}
+
+// NeedsRehash reports whether the hash is made with another algorithm or weaker parameters than the policy ones.
+func (p PasswordPolicy) NeedsRehash(hash string) bool {
+	switch p.Algorithm {
+	case HashArgon2id:
+		params, _, _, err := decodeArgon2(hash)
+		if err != nil {
+			return true
+		}
+		return params.Time < p.Argon2.Time || params.Memory < p.Argon2.Memory || params.Threads < p.Argon2.Threads
+	default:
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return true
+		}
+		return cost < p.BcryptCost
+	}
+}
+
+// verifyPassword compares the password with the hash of any supported algorithm.
+func verifyPassword(hash, password string) bool {
+	if !strings.HasPrefix(hash, "$"+HashArgon2id+"$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// encodeArgon2 encodes the hash in the PHC string format: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+func encodeArgon2(params Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", HashArgon2id, argon2.Version,
+		params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2(hash string) (params Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return params, nil, nil, errUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, errUnknownPasswordHash
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, errUnknownPasswordHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, errUnknownPasswordHash
+	}
+
+	return params, salt, key, nil
+}
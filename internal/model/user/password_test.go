@@ -0,0 +1,57 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordPolicy_Hash(t *testing.T) {
+	argon2Policy, err := NewPasswordPolicy(HashArgon2id, 0, Argon2Params{Time: 1, Memory: 64, Threads: 1})
+	require.NoError(t, err)
+	bcryptPolicy, err := NewPasswordPolicy(HashBcrypt, bcrypt.MinCost, Argon2Params{})
+	require.NoError(t, err)
+
+	for _, policy := range []PasswordPolicy{argon2Policy, bcryptPolicy} {
+		t.Run(policy.Algorithm, func(t *testing.T) {
+			hash, err := policy.Hash("dangerous things")
+			require.NoError(t, err)
+
+			assert.True(t, verifyPassword(hash, "dangerous things"))
+			assert.False(t, verifyPassword(hash, "ultimate survivor"))
+			assert.False(t, policy.NeedsRehash(hash))
+
+			_, err = policy.Hash("")
+			assert.ErrorIs(t, err, ErrBadPassword)
+		})
+	}
+}
+
+func TestPasswordPolicy_NeedsRehash(t *testing.T) {
+	weakBcrypt := PasswordPolicy{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost}
+	strongBcrypt := PasswordPolicy{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost + 1}
+	weakArgon2 := PasswordPolicy{Algorithm: HashArgon2id, Argon2: Argon2Params{Time: 1, Memory: 64, Threads: 1}}
+	strongArgon2 := PasswordPolicy{Algorithm: HashArgon2id, Argon2: Argon2Params{Time: 1, Memory: 128, Threads: 1}}
+
+	weakBcryptHash, err := weakBcrypt.Hash("password")
+	require.NoError(t, err)
+	weakArgon2Hash, err := weakArgon2.Hash("password")
+	require.NoError(t, err)
+
+	assert.True(t, strongBcrypt.NeedsRehash(weakBcryptHash))
+	assert.False(t, weakBcrypt.NeedsRehash(weakBcryptHash))
+	assert.True(t, strongArgon2.NeedsRehash(weakArgon2Hash))
+	assert.True(t, weakArgon2.NeedsRehash(weakBcryptHash))
+	assert.True(t, strongBcrypt.NeedsRehash(weakArgon2Hash))
+}
+
+func TestNewPasswordPolicy(t *testing.T) {
+	_, err := NewPasswordPolicy("md5", 0, Argon2Params{})
+	assert.ErrorIs(t, err, ErrInvalidPasswordPolicy)
+	_, err = NewPasswordPolicy(HashBcrypt, bcrypt.MaxCost+1, Argon2Params{})
+	assert.ErrorIs(t, err, ErrInvalidPasswordPolicy)
+	_, err = NewPasswordPolicy(HashArgon2id, 0, Argon2Params{Time: 0, Memory: 64, Threads: 1})
+	assert.ErrorIs(t, err, ErrInvalidPasswordPolicy)
+}
@@ -0,0 +1,23 @@
+package user
+
+import "errors"
+
+var ErrInvalidRole = errors.New("invalid user role")
+
+// Role grants access to the API: every user may use its own account, admins may also use the admin API.
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+// ParseRole returns the role by its name.
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleUser, RoleAdmin:
+		return r, nil
+	default:
+		return "", ErrInvalidRole
+	}
+}
@@ -2,9 +2,10 @@ package user
 
 import (
 	"errors"
+	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/money"
 	"github.com/shopspring/decimal"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -15,13 +16,14 @@ type User struct {
 	Login             Login
 	EncryptedPassword string
 	Balance           decimal.Decimal
+	Role              Role
+	// BlockedAt is a time the account was blocked at (zero - not blocked).
+	BlockedAt   time.Time
+	BlockReason string
 }
 
-func New(login Login, password string) (*User, error) {
-	if len(password) == 0 {
-		return nil, ErrBadPassword
-	}
-	encpw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func New(login Login, password string, policy PasswordPolicy) (*User, error) {
+	encpw, err := policy.Hash(password)
 	if err != nil {
 		return nil, ErrBadPassword
 	}
@@ -32,11 +34,38 @@ func New(login Login, password string) (*User, error) {
 
 	return &User{
 		Login:             login,
-		EncryptedPassword: string(encpw),
+		EncryptedPassword: encpw,
 		Balance:           decimal.Decimal{},
+		Role:              RoleUser,
 	}, nil
 }
 
 func (u User) VerifyPassword(psw string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(u.EncryptedPassword), []byte(psw)) == nil
+	return verifyPassword(u.EncryptedPassword, psw)
+}
+
+// Blocked reports whether the account is blocked by an admin.
+func (u User) Blocked() bool {
+	return !u.BlockedAt.IsZero()
+}
+
+// SetPassword replaces the password hash with a new one made by the policy.
+func (u *User) SetPassword(password string, policy PasswordPolicy) error {
+	encpw, err := policy.Hash(password)
+	if err != nil {
+		return ErrBadPassword
+	}
+	u.EncryptedPassword = encpw
+	return nil
+}
+
+// NewAccrual returns the accrual credited to a user balance rounded according to the money policy,
+// so the balance is kept with the policy scale. The accrual must not be negative, it may be zero
+// if the order has no accrual or it is less than the policy scale allows.
+func NewAccrual(amount decimal.Decimal, policy money.Policy) (decimal.Decimal, error) {
+	accrual := policy.Round(amount)
+	if accrual.IsNegative() {
+		return decimal.Decimal{}, money.ErrInvalidSum
+	}
+	return accrual, nil
 }
@@ -3,11 +3,13 @@ package user
 import (
 	"testing"
 
+	"github.com/Karzoug/loyalty_program/internal/model/money"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestUser_VerifyPassword(t *testing.T) {
-	u, err := New("Ylönen", "dangerous things")
+	u, err := New("Ylönen", "dangerous things", DefaultPasswordPolicy)
 	assert.NoError(t, err)
 
 	assert.True(t, u.VerifyPassword("dangerous things"))
@@ -15,7 +17,7 @@ func TestUser_VerifyPassword(t *testing.T) {
 }
 
 func TestNew(t *testing.T) {
-	_, err := New("Ylönen", "")
+	_, err := New("Ylönen", "", DefaultPasswordPolicy)
 	assert.Error(t, err)
 
 	tests := []struct {
@@ -51,7 +53,7 @@ func TestNew(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := New(tt.login, tt.password)
+			_, err := New(tt.login, tt.password, DefaultPasswordPolicy)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -60,3 +62,27 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+
+	_, err = ParseRole("root")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestNewAccrual(t *testing.T) {
+	policy := money.Policy{Scale: 2, Rounding: money.RoundDown}
+
+	accrual, err := NewAccrual(decimal.RequireFromString("729.987"), policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "729.98", accrual.String())
+
+	accrual, err = NewAccrual(decimal.RequireFromString("0.009"), policy)
+	assert.NoError(t, err)
+	assert.True(t, accrual.IsZero())
+
+	_, err = NewAccrual(decimal.RequireFromString("-1"), policy)
+	assert.ErrorIs(t, err, money.ErrInvalidSum)
+}
@@ -3,6 +3,7 @@ package withdraw
 import (
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/money"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/shopspring/decimal"
@@ -16,7 +17,9 @@ type Withdraw struct {
 	ProcessedAt time.Time
 }
 
-func New(login user.Login, orderNumber order.Number, sum decimal.Decimal) (*Withdraw, error) {
+// New creates a new Withdraw. The sum is not rounded, so the user is never charged more than requested:
+// a sum with more decimal places than the money policy scale is rejected.
+func New(login user.Login, orderNumber order.Number, sum decimal.Decimal, policy money.Policy) (*Withdraw, error) {
 	w := Withdraw{
 		OrderNumber: orderNumber,
 		Sum:         sum,
@@ -27,5 +30,13 @@ func New(login user.Login, orderNumber order.Number, sum decimal.Decimal) (*With
 		return nil, order.ErrInvalidNumber
 	}
 
+	if !w.Sum.IsPositive() {
+		return nil, money.ErrInvalidSum
+	}
+
+	if !policy.Exact(w.Sum) {
+		return nil, money.ErrTooPreciseSum
+	}
+
 	return &w, nil
 }
@@ -0,0 +1,80 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// numberPlaceholder in a response body is replaced with the requested order number.
+const numberPlaceholder = "{number}"
+
+// Response is a scripted response of the fake server.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+	// Delay is a duration the server waits before responding.
+	Delay time.Duration
+}
+
+// WithDelay returns a copy of the response that is sent after the delay.
+func (r Response) WithDelay(d time.Duration) Response {
+	r.Delay = d
+	return r
+}
+
+// Status returns a response with the order accrual status and no accrual.
+func Status(status string) Response {
+	return jsonResponse(fmt.Sprintf(`{"order":"%s","status":"%s"}`, numberPlaceholder, status))
+}
+
+// Processed returns a response with PROCESSED status and the accrual as is (a JSON number).
+func Processed(accrual string) Response {
+	return jsonResponse(fmt.Sprintf(`{"order":"%s","status":"PROCESSED","accrual":%s}`, numberPlaceholder, accrual))
+}
+
+// NotRegistered returns a response for an order unknown to the accrual system.
+func NotRegistered() Response {
+	return Response{StatusCode: http.StatusNoContent}
+}
+
+// TooManyRequests returns a response of the exceeded rate limit in the format of the accrual system.
+func TooManyRequests(retryAfter time.Duration, requestsPerMinute int) Response {
+	return TooManyRequestsWithBody(retryAfter,
+		fmt.Sprintf("No more than %d requests per minute allowed", requestsPerMinute))
+}
+
+// TooManyRequestsWithBody returns a response of the exceeded rate limit with an arbitrary body.
+func TooManyRequestsWithBody(retryAfter time.Duration, body string) Response {
+	return Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Content-Type": []string{"text/plain"},
+			"Retry-After":  []string{strconv.Itoa(int(retryAfter.Seconds()))},
+		},
+		Body: body,
+	}
+}
+
+// InternalError returns a response of a failed accrual system.
+func InternalError() Response {
+	return Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       http.StatusText(http.StatusInternalServerError),
+	}
+}
+
+// Malformed returns a successful response with a broken JSON body.
+func Malformed() Response {
+	return jsonResponse(`{"order":"` + numberPlaceholder + `","status":`)
+}
+
+func jsonResponse(body string) Response {
+	return Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       body,
+	}
+}
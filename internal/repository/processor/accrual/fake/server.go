@@ -0,0 +1,159 @@
+// Package fake provides a scriptable accrual system server for tests.
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const ordersPathPrefix = "/api/orders/"
+
+// Request is a request received by the fake server.
+type Request struct {
+	Number     string
+	Header     http.Header
+	ReceivedAt time.Time
+}
+
+// Server is an accrual system server returning scripted responses per order number.
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	scripts  map[string][]Response
+	fallback Response
+	requests []Request
+}
+
+// New starts a fake server that is closed when the test and all its subtests complete.
+// Orders without a script are answered as not registered.
+func New(tb testing.TB) *Server {
+	s := NewServer()
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// NewServer starts a fake server, the caller should call Close when finished.
+// Orders without a script are answered as not registered.
+func NewServer() *Server {
+	s := &Server{
+		scripts:  make(map[string][]Response),
+		fallback: NotRegistered(),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() url.URL {
+	u, _ := url.Parse(s.server.URL)
+	return *u
+}
+
+// Script appends responses the server returns for the order one by one,
+// the last response is repeated after the script is over.
+func (s *Server) Script(number string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripts[number] = append(s.scripts[number], responses...)
+}
+
+// SetFallback sets the response for orders without a script.
+func (s *Server) SetFallback(r Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fallback = r
+}
+
+// Requests returns requests received for the order in order of arrival.
+func (s *Server) Requests(number string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rs []Request
+	for _, r := range s.requests {
+		if r.Number == number {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// RequestsCount returns the number of requests received for all orders.
+func (s *Server) RequestsCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.requests)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	number, found := strings.CutPrefix(r.URL.Path, ordersPathPrefix)
+	if !found || number == "" || strings.Contains(number, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := s.next(Request{
+		Number:     number,
+		Header:     r.Header.Clone(),
+		ReceivedAt: time.Now(),
+	})
+
+	if resp.Delay > 0 {
+		timer := time.NewTimer(resp.Delay)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if resp.Body != "" {
+		fmt.Fprint(w, strings.ReplaceAll(resp.Body, numberPlaceholder, number))
+	}
+}
+
+// next records the request and returns the scripted response for it.
+func (s *Server) next(r Request) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r)
+
+	script, ok := s.scripts[r.Number]
+	if !ok || len(script) == 0 {
+		return s.fallback
+	}
+	resp := script[0]
+	if len(script) > 1 {
+		s.scripts[r.Number] = script[1:]
+	}
+	return resp
+}
@@ -14,10 +14,18 @@ import (
 
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/metrics"
 	morder "github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/repository/processor"
+	"github.com/Karzoug/loyalty_program/internal/tracing"
+	"github.com/Karzoug/loyalty_program/pkg/breaker"
 	"github.com/Karzoug/loyalty_program/pkg/e"
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
@@ -36,9 +44,13 @@ const (
 )
 
 var (
-	_ processor.Order = (*orderProcessor)(nil)
+	_ processor.Order   = (*orderProcessor)(nil)
+	_ processor.Breaker = (*orderProcessor)(nil)
+
+	tracer = tracing.Tracer("github.com/Karzoug/loyalty_program/internal/repository/processor/accrual")
 
 	errRequestNotSucceeded = errors.New("request not succeeded")
+	errTooManyRequests     = errors.New("too many requests")
 
 	// accrual service specific response regexp
 	rpmRegExp *regexp.Regexp = regexp.MustCompile("([0-9]+) (?:requests per minute allowed)")
@@ -47,6 +59,9 @@ var (
 
 type orderProcessorConfig interface {
 	AccrualSystemAddress() url.URL
+	AccrualBreakerFailureThreshold() int
+	AccrualBreakerOpenTimeout() time.Duration
+	AccrualBreakerHalfOpenRequests() int
 }
 
 type orderProcessor struct {
@@ -56,30 +71,61 @@ type orderProcessor struct {
 	client       *http.Client
 	limiter      *rate.Limiter
 	backOffUntil *atomic.Time
+	breaker      *breaker.Breaker
+
+	backoffMin time.Duration
+	backoffMax time.Duration
 }
 
 func NewOrderProcessor(cfg orderProcessorConfig, logger *zap.Logger) *orderProcessor {
+	metrics.AccrualRateLimit.Set(rateLimit)
+
 	return &orderProcessor{
 		cfg:    cfg,
 		logger: logger,
 
-		client:       &http.Client{Timeout: 3 * time.Second},
+		client: &http.Client{
+			Timeout: 3 * time.Second,
+			// propagates the W3C trace context of the order processing to the accrual service
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
 		limiter:      rate.NewLimiter(rate.Limit(rateLimit), rateBurst),
 		backOffUntil: atomic.NewTime(time.Now()),
+		breaker: breaker.New(cfg.AccrualBreakerFailureThreshold(), cfg.AccrualBreakerOpenTimeout(), cfg.AccrualBreakerHalfOpenRequests(),
+			func(from, to breaker.State) {
+				logger.Warn("Order processor: accrual service circuit breaker state changed",
+					zap.Stringer("from", from), zap.Stringer("to", to))
+			}),
+
+		backoffMin: backoffMinDuration,
+		backoffMax: backoffMaxDuration,
 	}
 }
 
+// log returns the logger of the context, that writes the request ID the order processing is caused by.
+func (p *orderProcessor) log(ctx context.Context) *zap.Logger {
+	return logctx.FromContext(ctx, p.logger)
+}
+
+// BreakerState returns the state of the accrual service circuit breaker.
+func (p *orderProcessor) BreakerState() breaker.State {
+	return p.breaker.State()
+}
+
 // Process returns order data from the server.
 func (p *orderProcessor) Process(ctx context.Context, o morder.Order) (*morder.Order, error) {
-	p.logger.Debug("Order processor: start order processing", zap.Int64("order number", int64(o.Number)))
+	ctx, span := tracer.Start(ctx, "accrual.Process", trace.WithAttributes(attribute.Int64("order.number", int64(o.Number))))
+	defer span.End()
+
+	p.log(ctx).Debug("Order processor: start order processing", zap.Int64("order number", int64(o.Number)))
 
 	accrual, err := p.getOrderAccrual(ctx, o.Number)
 	if err != nil {
-		p.logger.Debug("Order processor: accrual service returns error", zap.Int64("order number", int64(o.Number)), zap.Error(err))
+		p.log(ctx).Debug("Order processor: accrual service returns error", zap.Int64("order number", int64(o.Number)), zap.Error(err))
 		return nil, err
 	}
 
-	p.logger.Debug("Order processor: accrual service returns order status", zap.Int64("order number", int64(o.Number)), zap.String("status", string(accrual.Status)))
+	p.log(ctx).Debug("Order processor: accrual service returns order status", zap.Int64("order number", int64(o.Number)), zap.String("status", string(accrual.Status)))
 	switch accrual.Status {
 	case registered: // TODO: find out the details about this status
 		o.Status = morder.StatusNew
@@ -89,7 +135,7 @@ func (p *orderProcessor) Process(ctx context.Context, o morder.Order) (*morder.O
 		o.Status = morder.StatusProcessing
 	case processed:
 		o.Status = morder.StatusProcessed
-		o.Accrual = decimal.NewFromFloat(accrual.Accrual)
+		o.Accrual = accrual.Accrual
 	}
 	return &o, nil
 }
@@ -104,9 +150,9 @@ const (
 )
 
 type orderAccrual struct {
-	Order   string  `json:"order"`
-	Status  status  `json:"status"`
-	Accrual float64 `json:"accrual,omitempty"`
+	Order   string          `json:"order"`
+	Status  status          `json:"status"`
+	Accrual decimal.Decimal `json:"accrual,omitempty"`
 }
 
 // getOrderAccrual makes attempts to get order data from accrual service.
@@ -121,6 +167,10 @@ func (p *orderProcessor) getOrderAccrual(ctx context.Context, number morder.Numb
 			err  error
 		)
 
+		if i > 1 {
+			metrics.AccrualRetries.Inc()
+		}
+
 		// wait if the number of requests to the service was exceeded (global)
 		<-time.After(time.Until(p.backOffUntil.Load()))
 
@@ -129,18 +179,34 @@ func (p *orderProcessor) getOrderAccrual(ctx context.Context, number morder.Numb
 			return nil, err
 		}
 
-		p.logger.Debug("Order processor: do request to accrual service", zap.Int("attempt number", i), zap.String("url", url.String()))
+		// fail fast while the accrual service is considered down
+		if err := p.breaker.Allow(); err != nil {
+			return nil, processor.ErrCircuitOpen
+		}
+
+		p.log(ctx).Debug("Order processor: do request to accrual service", zap.Int("attempt number", i), zap.String("url", url.String()))
 		body, wait, err = p.doAttemptRequest(ctx, i, url)
 		if err == nil {
+			p.breaker.Success()
 			break // the request is succeeded
 		}
 		switch err {
 		case processor.ErrOrderNotRegistered:
+			p.breaker.Success()
 			return nil, processor.ErrOrderNotRegistered
+		case errTooManyRequests:
+			// the service is alive, the requests rate is already adjusted by backoff
+			p.breaker.Success()
 		case errRequestNotSucceeded:
 			// got response but status code indicates that the request was not succeeded
+			p.breaker.Failure()
 		default:
-			p.logger.Warn("Order processor: do request error", zap.Error(err))
+			p.breaker.Failure()
+			p.log(ctx).Warn("Order processor: do request error", zap.Error(err))
+		}
+
+		if i == maxAttemptNumber {
+			break // no reason to wait after the last attempt
 		}
 
 		// sleep until next attempt or context canceled
@@ -168,18 +234,23 @@ func (p *orderProcessor) getOrderAccrual(ctx context.Context, number morder.Numb
 
 // doAttemptRequest does a request to the server and returns the received data or the duration until the next request and an error.
 func (p *orderProcessor) doAttemptRequest(ctx context.Context, attemptNum int, url url.URL) ([]byte, time.Duration, error) {
-	wait := p.backoff(backoffMinDuration, backoffMaxDuration, attemptNum, nil)
+	wait := p.backoff(p.backoffMin, p.backoffMax, attemptNum, nil)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
 	if err != nil {
 		return nil, wait, err
 	}
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
 
 	resp, err := p.client.Do(req)
 	if err != nil {
+		metrics.AccrualRequests.WithLabelValues("error").Inc()
 		return nil, wait, err
 	}
 	defer resp.Body.Close()
+	metrics.AccrualRequests.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
 
 	switch resp.StatusCode {
 	case http.StatusNoContent:
@@ -187,8 +258,11 @@ func (p *orderProcessor) doAttemptRequest(ctx context.Context, attemptNum int, u
 	case http.StatusOK:
 		b, err := io.ReadAll(resp.Body)
 		return b, 0, err
+	case http.StatusTooManyRequests:
+		wait = p.backoff(p.backoffMin, p.backoffMax, attemptNum, resp)
+		return nil, wait, errTooManyRequests
 	default:
-		wait = p.backoff(backoffMinDuration, backoffMaxDuration, attemptNum, resp)
+		wait = p.backoff(p.backoffMin, p.backoffMax, attemptNum, resp)
 		return nil, wait, errRequestNotSucceeded
 	}
 }
@@ -201,13 +275,16 @@ func (p *orderProcessor) backoff(min, max time.Duration, attemptNum int, resp *h
 		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
 			if s, ok := resp.Header["Retry-After"]; ok {
 				if rps, ok := tryGetLimitFromResponse(resp); ok {
-					p.limiter.SetLimit(rate.Limit(rps)) // set new requests per second if possible
+					p.limiter.SetLimit(rps) // set new requests per second if possible
+					metrics.AccrualRateLimit.Set(float64(rps))
 				}
 
 				if sleep, err := strconv.ParseInt(s[0], 10, 64); err == nil {
 					sleepDuration := time.Second * time.Duration(sleep)
 					// make all other requests attempts wait until the end of the time returned
-					p.backOffUntil.Store(time.Now().Add(sleepDuration))
+					backOffUntil := time.Now().Add(sleepDuration)
+					p.backOffUntil.Store(backOffUntil)
+					metrics.AccrualBackoffUntil.Set(float64(backOffUntil.UnixNano()) / float64(time.Second))
 					return sleepDuration
 				}
 			}
@@ -224,7 +301,7 @@ func (p *orderProcessor) backoff(min, max time.Duration, attemptNum int, resp *h
 }
 
 // tryGetLimitFromResponse tries to find in the server response the allowed requests per second.
-func tryGetLimitFromResponse(resp *http.Response) (rps int, ok bool) {
+func tryGetLimitFromResponse(resp *http.Response) (rps rate.Limit, ok bool) {
 	if resp.Body == nil {
 		return 0, false
 	}
@@ -235,21 +312,21 @@ func tryGetLimitFromResponse(resp *http.Response) (rps int, ok bool) {
 	}
 
 	rpmStrings := rpmRegExp.FindStringSubmatch(string(body))
-	if rpmStrings != nil || len(rpmStrings) >= 2 {
-		rate, err := strconv.Atoi(rpmStrings[1])
+	if len(rpmStrings) >= 2 {
+		rpm, err := strconv.Atoi(rpmStrings[1])
 		if err != nil {
 			return 0, false
 		}
-		return 60 * rate, true
+		return rate.Limit(rpm) / 60, true
 	}
 
 	rpcStrings := rpcRegExp.FindStringSubmatch(string(body))
-	if rpcStrings != nil || len(rpcStrings) >= 2 {
-		rate, err := strconv.Atoi(rpcStrings[1])
+	if len(rpcStrings) >= 2 {
+		rps, err := strconv.Atoi(rpcStrings[1])
 		if err != nil {
 			return 0, false
 		}
-		return rate, true
+		return rate.Limit(rps), true
 	}
 
 	return 0, false
@@ -0,0 +1,292 @@
+package accrual
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	morder "github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	"github.com/Karzoug/loyalty_program/pkg/breaker"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	testOrderNumber       morder.Number = 12345678903
+	testOrderNumberString               = "12345678903"
+)
+
+type mockConfig struct {
+	address          url.URL
+	breakerFailures  int
+	breakerOpenTimer time.Duration
+}
+
+func (c mockConfig) AccrualSystemAddress() url.URL {
+	return c.address
+}
+
+func (c mockConfig) AccrualBreakerFailureThreshold() int {
+	return c.breakerFailures
+}
+
+func (c mockConfig) AccrualBreakerOpenTimeout() time.Duration {
+	return c.breakerOpenTimer
+}
+
+func (c mockConfig) AccrualBreakerHalfOpenRequests() int {
+	return 1
+}
+
+func newTestOrderProcessor(t *testing.T, srv *fake.Server) *orderProcessor {
+	t.Helper()
+
+	p := NewOrderProcessor(mockConfig{
+		address:          srv.URL(),
+		breakerFailures:  100,
+		breakerOpenTimer: time.Minute,
+	}, zap.NewNop())
+	p.backoffMin = 10 * time.Millisecond
+	p.backoffMax = 100 * time.Millisecond
+
+	return p
+}
+
+func TestOrderProcessor_Process(t *testing.T) {
+	t.Run("processed order", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.Processed("9007199254740993.01"))
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		o, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		require.NoError(t, err)
+		assert.Equal(t, morder.StatusProcessed, o.Status)
+		// the accrual is parsed exactly, not through float64
+		assert.Equal(t, "9007199254740993.01", o.Accrual.String())
+		assert.Len(t, srv.Requests(testOrderNumberString), 1)
+	})
+
+	t.Run("accrual statuses", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			status string
+			want   morder.Status
+		}{
+			{status: "REGISTERED", want: morder.StatusNew},
+			{status: "PROCESSING", want: morder.StatusProcessing},
+			{status: "INVALID", want: morder.StatusInvalid},
+		}
+
+		srv := fake.New(t)
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		for _, tt := range tests {
+			srv.SetFallback(fake.Status(tt.status))
+
+			o, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, o.Status, tt.status)
+		}
+	})
+
+	t.Run("order not registered", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		assert.ErrorIs(t, err, processor.ErrOrderNotRegistered)
+		// not registered order is a final answer for this call, no retries
+		assert.Len(t, srv.Requests(testOrderNumberString), 1)
+	})
+
+	t.Run("server errors", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.InternalError())
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		assert.ErrorIs(t, err, processor.ErrServerNotRespond)
+
+		reqs := srv.Requests(testOrderNumberString)
+		require.Len(t, reqs, maxAttemptNumber)
+		// the interval between attempts grows exponentially
+		for i := 1; i < len(reqs); i++ {
+			want := p.backoff(p.backoffMin, p.backoffMax, i, nil)
+			assert.GreaterOrEqual(t, reqs[i].ReceivedAt.Sub(reqs[i-1].ReceivedAt), want)
+		}
+	})
+
+	t.Run("server recovers after error", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.InternalError(), fake.Processed("42"))
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		o, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		require.NoError(t, err)
+		assert.Equal(t, "42", o.Accrual.String())
+		assert.Len(t, srv.Requests(testOrderNumberString), 2)
+	})
+
+	t.Run("too many requests", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.TooManyRequests(time.Second, 120), fake.Processed("10"))
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		o, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		require.NoError(t, err)
+		assert.Equal(t, morder.StatusProcessed, o.Status)
+
+		reqs := srv.Requests(testOrderNumberString)
+		require.Len(t, reqs, 2)
+		// the client waits for the Retry-After duration and adopts the rate limit from the body
+		assert.GreaterOrEqual(t, reqs[1].ReceivedAt.Sub(reqs[0].ReceivedAt), time.Second)
+		assert.Equal(t, rate.Limit(2), p.limiter.Limit())
+		assert.WithinDuration(t, reqs[0].ReceivedAt.Add(time.Second), p.backOffUntil.Load(), 100*time.Millisecond)
+	})
+
+	t.Run("too many requests without known limit", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.TooManyRequestsWithBody(0, "slow down"), fake.Processed("10"))
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		require.NoError(t, err)
+		assert.Equal(t, rate.Limit(rateLimit), p.limiter.Limit())
+	})
+
+	t.Run("slow response", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.Processed("10").WithDelay(time.Second))
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("malformed json", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.Script(testOrderNumberString, fake.Malformed())
+		p := newTestOrderProcessor(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		assert.Error(t, err)
+		assert.Len(t, srv.Requests(testOrderNumberString), 1)
+	})
+
+	t.Run("circuit breaker opens", func(t *testing.T) {
+		t.Parallel()
+
+		srv := fake.New(t)
+		srv.SetFallback(fake.InternalError())
+		p := newTestOrderProcessor(t, srv)
+		p.breaker = breaker.New(maxAttemptNumber, time.Minute, 1, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+		assert.ErrorIs(t, err, processor.ErrServerNotRespond)
+		assert.Equal(t, breaker.StateOpen, p.BreakerState())
+
+		_, err = p.Process(ctx, morder.Order{Number: testOrderNumber})
+		assert.ErrorIs(t, err, processor.ErrCircuitOpen)
+		assert.Equal(t, maxAttemptNumber, srv.RequestsCount())
+	})
+}
+
+func TestOrderProcessor_Process_traceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	srv := fake.New(t)
+	srv.Script(testOrderNumberString, fake.Processed("10"))
+	p := newTestOrderProcessor(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05, 0x06},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx = trace.ContextWithSpanContext(ctx, sc)
+
+	_, err := p.Process(ctx, morder.Order{Number: testOrderNumber})
+	require.NoError(t, err)
+
+	reqs := srv.Requests(testOrderNumberString)
+	require.Len(t, reqs, 1)
+	// the accrual service request continues the trace of the order processing
+	assert.Contains(t, reqs[0].Header.Get("Traceparent"), sc.TraceID().String())
+}
+
+func TestOrderProcessor_Process_requestID(t *testing.T) {
+	t.Parallel()
+
+	srv := fake.New(t)
+	srv.Script(testOrderNumberString, fake.Processed("10"))
+	p := newTestOrderProcessor(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := p.Process(requestid.NewContext(ctx, "req-42"), morder.Order{Number: testOrderNumber})
+	require.NoError(t, err)
+
+	reqs := srv.Requests(testOrderNumberString)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "req-42", reqs[0].Header.Get(requestid.Header))
+}
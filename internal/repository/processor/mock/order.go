@@ -2,11 +2,13 @@ package mock
 
 import (
 	"context"
+	"sync"
 
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 )
 
 type Order struct {
+	mu    sync.RWMutex
 	order *order.Order
 	err   error
 }
@@ -16,10 +18,16 @@ func NewOrder() *Order {
 }
 
 func (m *Order) SetResult(o *order.Order, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.order = o
 	m.err = err
 }
 
 func (m *Order) Process(_ context.Context, _ order.Order) (*order.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return m.order, m.err
 }
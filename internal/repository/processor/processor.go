@@ -5,13 +5,20 @@ import (
 	"errors"
 
 	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/pkg/breaker"
 )
 
 var (
 	ErrOrderNotRegistered = errors.New("order is not registered")
 	ErrServerNotRespond   = errors.New("server not respond")
+	ErrCircuitOpen        = errors.New("server circuit breaker is open")
 )
 
 type Order interface {
 	Process(context.Context, order.Order) (*order.Order, error)
 }
+
+// Breaker is an order processor guarding the server requests with a circuit breaker.
+type Breaker interface {
+	BreakerState() breaker.State
+}
@@ -6,4 +6,5 @@ var (
 	ErrRecordNotFound      = errors.New("record not found")
 	ErrNoRecordAffected    = errors.New("no record affected by query")
 	ErrRecordAlreadyExists = errors.New("record already exists")
+	ErrRecordConflict      = errors.New("record was changed concurrently")
 )
@@ -0,0 +1,102 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+var _ storage.AccrualHold = (*accrualHoldStorage)(nil)
+
+type accrualHoldStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewAccrualHoldStorage(db *sql.DB) *accrualHoldStorage {
+	return &accrualHoldStorage{
+		db: db,
+	}
+}
+
+func newAccrualHoldTxStorage(tx *sql.Tx) *accrualHoldStorage {
+	return &accrualHoldStorage{
+		tx: tx,
+	}
+}
+
+func (s accrualHoldStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+func (s accrualHoldStorage) Create(ctx context.Context, hold ledger.Hold) error {
+	res, err := s.connection().ExecContext(ctx, `INSERT INTO accrual_holds(order_number, user_login, amount, created_at) VALUES(?, ?, ?, ?)`,
+		hold.OrderNumber, hold.UserLogin, hold.Amount, hold.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), duplicateKeyErrorCode) {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s accrualHoldStorage) ListByUser(ctx context.Context, login user.Login) ([]ledger.Hold, error) {
+	rows, err := s.connection().QueryContext(ctx,
+		`SELECT order_number, amount, created_at FROM accrual_holds WHERE user_login = ? ORDER BY created_at`, login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]ledger.Hold, 0)
+	for rows.Next() {
+		hold := ledger.Hold{UserLogin: login}
+		err := rows.Scan(&hold.OrderNumber, &hold.Amount, &hold.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+func (s accrualHoldStorage) Delete(ctx context.Context, number order.Number) error {
+	res, err := s.connection().ExecContext(ctx, `DELETE FROM accrual_holds WHERE order_number = ?`, number)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
+	"testing/fstest"
 	"time"
 
 	"github.com/Karzoug/loyalty_program/migrations"
@@ -14,9 +16,16 @@ import (
 )
 
 const (
-	duplicateKeyErrorCode = "1555"
+	duplicateKeyErrorCode     = "1555"
+	uniqueConstraintErrorCode = "2067"
 )
 
+// sqliteMigrations replaces the postgresql migrations sqlite cannot apply.
+// Column types of sqlite do not limit the length of values, so the replaced migrations change nothing.
+var sqliteMigrations = map[string]string{
+	"000012_widen_users_encrypted_password.up.sql": "SELECT 1;",
+}
+
 // newDBInMemory creates connection to sqlite database in memory (for testing purposes only).
 func newDBInMemory(ctx context.Context) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", ":memory:")
@@ -25,7 +34,11 @@ func newDBInMemory(ctx context.Context) (*sql.DB, error) {
 	}
 	db.SetMaxOpenConns(1)
 
-	d, err := iofs.New(migrations.FS, ".")
+	migrationsFS, err := newSQLiteMigrationsFS()
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply migrations: %w", err)
+	}
+	d, err := iofs.New(migrationsFS, ".")
 	if err != nil {
 		return nil, fmt.Errorf("unable to apply migrations: %w", err)
 	}
@@ -52,6 +65,27 @@ func newDBInMemory(ctx context.Context) (*sql.DB, error) {
 	return db, nil
 }
 
+// newSQLiteMigrationsFS returns the migrations with the replaced ones applicable in sqlite.
+func newSQLiteMigrationsFS() (fs.FS, error) {
+	names, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsFS := make(fstest.MapFS, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(migrations.FS, name)
+		if err != nil {
+			return nil, err
+		}
+		if replacement, ok := sqliteMigrations[name]; ok {
+			data = []byte(replacement)
+		}
+		migrationsFS[name] = &fstest.MapFile{Data: data}
+	}
+	return migrationsFS, nil
+}
+
 type sqliteConnecter interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
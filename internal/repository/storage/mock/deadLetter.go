@@ -0,0 +1,101 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+var _ storage.DeadLetter = (*deadLetterStorage)(nil)
+
+type deadLetterStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewDeadLetterStorage(db *sql.DB) *deadLetterStorage {
+	return &deadLetterStorage{
+		db: db,
+	}
+}
+
+func newDeadLetterTxStorage(tx *sql.Tx) *deadLetterStorage {
+	return &deadLetterStorage{
+		tx: tx,
+	}
+}
+
+func (s deadLetterStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+func (s deadLetterStorage) Create(ctx context.Context, dl job.DeadLetter) error {
+	res, err := s.connection().ExecContext(ctx, `INSERT INTO order_dead_letters(order_number, attempts, reason, created_at, dead_lettered_at) VALUES(?, ?, ?, ?, ?)`,
+		dl.OrderNumber, dl.Attempts, dl.Reason, dl.CreatedAt, dl.DeadLetteredAt)
+	if err != nil {
+		if strings.Contains(err.Error(), duplicateKeyErrorCode) {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s deadLetterStorage) List(ctx context.Context) ([]job.DeadLetter, error) {
+	rows, err := s.connection().QueryContext(ctx,
+		`SELECT order_number, attempts, reason, created_at, dead_lettered_at FROM order_dead_letters ORDER BY dead_lettered_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dls := make([]job.DeadLetter, 0)
+	for rows.Next() {
+		var dl job.DeadLetter
+		err := rows.Scan(&dl.OrderNumber, &dl.Attempts, &dl.Reason, &dl.CreatedAt, &dl.DeadLetteredAt)
+		if err != nil {
+			return nil, err
+		}
+		dls = append(dls, dl)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return dls, nil
+}
+
+func (s deadLetterStorage) Delete(ctx context.Context, number order.Number) error {
+	res, err := s.connection().ExecContext(ctx, `DELETE FROM order_dead_letters WHERE order_number = ?`, number)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+var _ storage.Job = (*jobStorage)(nil)
+
+type jobStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewJobStorage(db *sql.DB) *jobStorage {
+	return &jobStorage{
+		db: db,
+	}
+}
+
+func newJobTxStorage(tx *sql.Tx) *jobStorage {
+	return &jobStorage{
+		tx: tx,
+	}
+}
+
+func (s jobStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+// Create inserts the job and sets the processing schedule of its order.
+// Jobs are created inside transactions, so both statements are applied together.
+func (s jobStorage) Create(ctx context.Context, job job.Job) error {
+	_, err := s.connection().ExecContext(ctx, `INSERT INTO order_jobs(order_number, trace_parent, request_id, created_at) VALUES(?, ?, ?, ?)`,
+		job.OrderNumber, job.TraceParent, job.RequestID, job.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), duplicateKeyErrorCode) {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	return s.updateOrder(ctx, `UPDATE orders SET attempts = ?, next_check_at = ?, last_error = ? WHERE number = ?`,
+		job.Attempts, job.NextCheckAt, job.LastError, job.OrderNumber)
+}
+
+func (s jobStorage) Get(ctx context.Context, number order.Number) (*job.Job, error) {
+	job := job.Job{OrderNumber: number}
+	err := s.connection().QueryRowContext(ctx,
+		`SELECT o.attempts, o.next_check_at, o.last_error, j.trace_parent, j.request_id, j.created_at
+		FROM order_jobs j JOIN orders o ON o.number = j.order_number WHERE j.order_number = ?`, number).
+		Scan(&job.Attempts, &job.NextCheckAt, &job.LastError, &job.TraceParent, &job.RequestID, &job.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ClaimUnprocessed is an equivalent of the postgresql FOR UPDATE SKIP LOCKED claiming:
+// sqlite serializes writes, so concurrent workers never claim the same job.
+// The returning clause of sqlite cannot refer to other tables, so the order schedule is read by subqueries.
+func (s jobStorage) ClaimUnprocessed(ctx context.Context, workerID string, n int, leaseTTL time.Duration) ([]job.Job, error) {
+	now := time.Now().UTC()
+	rows, err := s.connection().QueryContext(ctx,
+		`UPDATE order_jobs SET locked_by = ?, locked_until = ? WHERE order_number IN
+			(SELECT j.order_number FROM order_jobs j JOIN orders o ON o.number = j.order_number
+			WHERE o.next_check_at <= ? AND (j.locked_until IS NULL OR j.locked_until <= ?)
+			ORDER BY o.next_check_at LIMIT ?)
+		RETURNING order_number,
+			(SELECT attempts FROM orders WHERE number = order_number),
+			(SELECT next_check_at FROM orders WHERE number = order_number),
+			(SELECT last_error FROM orders WHERE number = order_number),
+			trace_parent, request_id, created_at`,
+		workerID, now.Add(leaseTTL), now, now, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]job.Job, 0)
+	for rows.Next() {
+		var job job.Job
+		err := rows.Scan(&job.OrderNumber, &job.Attempts, &job.NextCheckAt, &job.LastError, &job.TraceParent, &job.RequestID, &job.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Lease leases the job to the worker regardless of its schedule, so tests can process a job that is not due yet
+// the way the worker processes a claimed job.
+func (s jobStorage) Lease(ctx context.Context, number order.Number, workerID string, leaseTTL time.Duration) error {
+	res, err := s.connection().ExecContext(ctx, `UPDATE order_jobs SET locked_by = ?, locked_until = ? WHERE order_number = ?`,
+		workerID, time.Now().UTC().Add(leaseTTL), number)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s jobStorage) Retry(ctx context.Context, number order.Number, workerID string, nextCheckAt time.Time, lastError string) error {
+	if err := s.release(ctx, number, workerID); err != nil {
+		return err
+	}
+	return s.updateOrder(ctx, `UPDATE orders SET attempts = attempts + 1, next_check_at = ?, last_error = ? WHERE number = ?`,
+		nextCheckAt, lastError, number)
+}
+
+func (s jobStorage) Postpone(ctx context.Context, number order.Number, workerID string, nextCheckAt time.Time) error {
+	if err := s.release(ctx, number, workerID); err != nil {
+		return err
+	}
+	return s.updateOrder(ctx, `UPDATE orders SET attempts = 0, next_check_at = ?, last_error = '' WHERE number = ?`,
+		nextCheckAt, number)
+}
+
+// release releases the lease of the worker.
+func (s jobStorage) release(ctx context.Context, number order.Number, workerID string) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE order_jobs SET locked_by = '', locked_until = NULL WHERE order_number = ? AND locked_by = ?`, number, workerID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+// Delete deletes the job, the order has no next check anymore.
+func (s jobStorage) Delete(ctx context.Context, number order.Number, workerID string) error {
+	res, err := s.connection().ExecContext(ctx, `DELETE FROM order_jobs WHERE order_number = ? AND (? = '' OR locked_by = ?)`, number, workerID, workerID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return s.updateOrder(ctx, `UPDATE orders SET next_check_at = NULL WHERE number = ?`, number)
+}
+
+// updateOrder updates the processing schedule of the job order.
+func (s jobStorage) updateOrder(ctx context.Context, query string, args ...any) error {
+	res, err := s.connection().ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s jobStorage) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.connection().QueryRowContext(ctx, `SELECT COUNT(*) FROM order_jobs`).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
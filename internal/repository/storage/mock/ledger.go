@@ -0,0 +1,97 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/shopspring/decimal"
+)
+
+var _ storage.Ledger = (*ledgerStorage)(nil)
+
+type ledgerStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewLedgerStorage(db *sql.DB) *ledgerStorage {
+	return &ledgerStorage{
+		db: db,
+	}
+}
+
+func newLedgerTxStorage(tx *sql.Tx) *ledgerStorage {
+	return &ledgerStorage{
+		tx: tx,
+	}
+}
+
+func (s ledgerStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+func (s ledgerStorage) Create(ctx context.Context, entry ledger.Entry) error {
+	res, err := s.connection().ExecContext(ctx, `INSERT INTO ledger_entries(id, user_login, order_number, operation, amount, reason, created_by, created_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.UserLogin, entry.OrderNumber, entry.Operation, entry.Amount, entry.Reason, entry.CreatedBy, entry.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), duplicateKeyErrorCode) || strings.Contains(err.Error(), uniqueConstraintErrorCode) {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s ledgerStorage) GetByUser(ctx context.Context, login user.Login) ([]ledger.Entry, error) {
+	rows, err := s.connection().QueryContext(ctx,
+		`SELECT id, order_number, operation, amount, reason, created_by, created_at FROM ledger_entries WHERE user_login = ? ORDER BY created_at`, login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]ledger.Entry, 0)
+	for rows.Next() {
+		entry := ledger.Entry{UserLogin: login}
+		err := rows.Scan(&entry.ID, &entry.OrderNumber, &entry.Operation, &entry.Amount, &entry.Reason, &entry.CreatedBy, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s ledgerStorage) BalanceByUser(ctx context.Context, login user.Login) (*decimal.Decimal, error) {
+	var sum decimal.NullDecimal
+	err := s.connection().QueryRowContext(ctx,
+		`SELECT SUM(amount) FROM ledger_entries WHERE user_login = ?`, login).Scan(&sum)
+
+	if !sum.Valid {
+		return &decimal.Zero, err
+	}
+
+	return &sum.Decimal, err
+}
@@ -0,0 +1,117 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+var _ storage.LoginAttempt = (*loginAttemptStorage)(nil)
+
+type loginAttemptStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewLoginAttemptStorage(db *sql.DB) *loginAttemptStorage {
+	return &loginAttemptStorage{
+		db: db,
+	}
+}
+
+func newLoginAttemptTxStorage(tx *sql.Tx) *loginAttemptStorage {
+	return &loginAttemptStorage{
+		tx: tx,
+	}
+}
+
+func (s loginAttemptStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+func (s loginAttemptStorage) Get(ctx context.Context, key string) (*lockout.Attempts, error) {
+	return s.scan(s.connection().QueryRowContext(ctx,
+		`SELECT attempt_key, failures, last_failure_at, locked_until FROM login_attempts WHERE attempt_key = ?`, key))
+}
+
+func (s loginAttemptStorage) AddFailure(ctx context.Context, key string, at, resetBefore time.Time) (*lockout.Attempts, error) {
+	_, err := s.connection().ExecContext(ctx,
+		`DELETE FROM login_attempts WHERE last_failure_at < ? AND (locked_until IS NULL OR locked_until <= ?)`, resetBefore, at)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.scan(s.connection().QueryRowContext(ctx,
+		`INSERT INTO login_attempts(attempt_key, failures, last_failure_at) VALUES(?, 1, ?)
+			ON CONFLICT (attempt_key) DO UPDATE SET
+				failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+				last_failure_at = excluded.last_failure_at
+			WHERE login_attempts.locked_until IS NULL OR login_attempts.locked_until <= ?
+			RETURNING attempt_key, failures, last_failure_at, locked_until`, key, at, resetBefore, at))
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			// the key is locked out, so the conflicting row is not updated
+			return nil, storage.ErrRecordConflict
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s loginAttemptStorage) scan(row *sql.Row) (*lockout.Attempts, error) {
+	var (
+		a           lockout.Attempts
+		lockedUntil sql.NullTime
+	)
+	err := row.Scan(&a.Key, &a.Failures, &a.LastFailureAt, &lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		a.LockedUntil = lockedUntil.Time
+	}
+
+	return &a, nil
+}
+
+func (s loginAttemptStorage) Lock(ctx context.Context, key string, until time.Time) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE login_attempts SET locked_until = ? WHERE attempt_key = ?`, until, key)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s loginAttemptStorage) RemoveFailure(ctx context.Context, key string, lockedUntil time.Time) error {
+	_, err := s.connection().ExecContext(ctx,
+		`UPDATE login_attempts SET failures = failures - 1,
+				locked_until = CASE WHEN locked_until = ? THEN NULL ELSE locked_until END
+			WHERE attempt_key = ? AND failures > 0`, lockedUntil, key)
+	return err
+}
+
+func (s loginAttemptStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.connection().ExecContext(ctx, `DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+	return err
+}
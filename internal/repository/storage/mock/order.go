@@ -4,11 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"strings"
-	"time"
 
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage/sqlbuilder"
 )
 
 var _ storage.Order = (*orderStorage)(nil)
@@ -99,17 +99,21 @@ func (s orderStorage) GetByUser(ctx context.Context, login user.Login) ([]order.
 	return orders, nil
 }
 
-func (s orderStorage) ListUnprocessed(ctx context.Context, limit, offset int, uploadedEarlierThan time.Time) ([]order.Order, error) {
-	var (
-		rows *sql.Rows
-		err  error
-	)
-
-	if limit == -1 {
-		rows, err = s.connection().QueryContext(ctx, `SELECT number, user_login, status, accrual, uploaded_at FROM orders WHERE status NOT IN (?, ?) AND uploaded_at < ? ORDER BY uploaded_at OFFSET ?`, order.StatusInvalid, order.StatusProcessed, uploadedEarlierThan, offset)
-	} else {
-		rows, err = s.connection().QueryContext(ctx, `SELECT number, user_login, status, accrual, uploaded_at FROM orders WHERE status NOT IN (?, ?) AND uploaded_at < ? ORDER BY uploaded_at LIMIT ? OFFSET ?`, order.StatusInvalid, order.StatusProcessed, uploadedEarlierThan, limit, offset)
+func (s orderStorage) ListByUser(ctx context.Context, login user.Login, filter storage.OrderFilter) ([]order.Order, error) {
+	var where sqlbuilder.Where
+	where.Add("user_login = %s", login)
+	if len(filter.Statuses) > 0 {
+		statuses := make([]any, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, status)
+		}
+		where.AddIn("status", statuses)
 	}
+	where.AddPage("uploaded_at", "number", filter.From, filter.To, filter.After)
+
+	rows, err := s.connection().QueryContext(ctx,
+		`SELECT number, status, accrual, uploaded_at FROM orders WHERE `+where.String()+
+			` ORDER BY uploaded_at, number`+sqlbuilder.Limit(filter.Limit), where.Args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -117,8 +121,8 @@ func (s orderStorage) ListUnprocessed(ctx context.Context, limit, offset int, up
 
 	orders := make([]order.Order, 0)
 	for rows.Next() {
-		var order order.Order
-		err := rows.Scan(&order.Number, &order.UserLogin, &order.Status, &order.Accrual, &order.UploadedAt)
+		order := order.Order{UserLogin: login}
+		err := rows.Scan(&order.Number, &order.Status, &order.Accrual, &order.UploadedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -152,6 +156,34 @@ func (s orderStorage) Update(ctx context.Context, order order.Order) error {
 	return nil
 }
 
+func (s orderStorage) CompareAndUpdate(ctx context.Context, o order.Order, prevStatus order.Status) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE orders SET status = ?, accrual = ? WHERE number = ? AND status = ?`,
+		o.Status, o.Accrual, o.Number, prevStatus)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return s.compareAndUpdateMissError(ctx, o.Number)
+	}
+
+	return nil
+}
+
+// compareAndUpdateMissError tells apart a missing order and an order whose status was changed concurrently.
+func (s orderStorage) compareAndUpdateMissError(ctx context.Context, number order.Number) error {
+	_, err := s.Get(ctx, number)
+	if err != nil {
+		return err
+	}
+	return storage.ErrRecordConflict
+}
+
 func (s orderStorage) Delete(ctx context.Context, number order.Number) error {
 	res, err := s.connection().ExecContext(ctx, `DELETE FROM orders WHERE number = ?`, number)
 	if err != nil {
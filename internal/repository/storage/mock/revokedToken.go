@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+var _ storage.RevokedToken = (*revokedTokenStorage)(nil)
+
+type revokedTokenStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewRevokedTokenStorage(db *sql.DB) *revokedTokenStorage {
+	return &revokedTokenStorage{
+		db: db,
+	}
+}
+
+func newRevokedTokenTxStorage(tx *sql.Tx) *revokedTokenStorage {
+	return &revokedTokenStorage{
+		tx: tx,
+	}
+}
+
+func (s revokedTokenStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+func (s revokedTokenStorage) Create(ctx context.Context, jti string, expiresAt time.Time) error {
+	res, err := s.connection().ExecContext(ctx, `INSERT INTO revoked_tokens(jti, expires_at) VALUES(?, ?)`, jti, expiresAt)
+	if err != nil {
+		if strings.Contains(err.Error(), duplicateKeyErrorCode) {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s revokedTokenStorage) IsRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	var revoked bool
+	err := s.connection().QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)
+			OR NOT EXISTS(SELECT 1 FROM user_sessions WHERE id = ? AND revoked_at IS NULL)`, jti, sessionID).Scan(&revoked)
+
+	return revoked, err
+}
+
+func (s revokedTokenStorage) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := s.connection().ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= ?`, now)
+	return err
+}
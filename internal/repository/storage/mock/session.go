@@ -0,0 +1,137 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+var _ storage.Session = (*sessionStorage)(nil)
+
+type sessionStorage struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewSessionStorage(db *sql.DB) *sessionStorage {
+	return &sessionStorage{
+		db: db,
+	}
+}
+
+func newSessionTxStorage(tx *sql.Tx) *sessionStorage {
+	return &sessionStorage{
+		tx: tx,
+	}
+}
+
+func (s sessionStorage) connection() sqliteConnecter {
+	if s.tx == nil {
+		return s.db
+	}
+	return s.tx
+}
+
+func (s sessionStorage) Create(ctx context.Context, ss session.Session) error {
+	res, err := s.connection().ExecContext(ctx,
+		`INSERT INTO user_sessions(id, user_login, refresh_token_hash, previous_token_hash, created_at, expires_at) VALUES(?, ?, ?, ?, ?, ?)`,
+		ss.ID, ss.UserLogin, ss.RefreshTokenHash, ss.PreviousTokenHash, ss.CreatedAt, ss.ExpiresAt)
+	if err != nil {
+		if strings.Contains(err.Error(), duplicateKeyErrorCode) {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s sessionStorage) Get(ctx context.Context, id string) (*session.Session, error) {
+	return s.get(ctx, `SELECT id, user_login, refresh_token_hash, previous_token_hash, created_at, expires_at, revoked_at
+		FROM user_sessions WHERE id = ?`, id)
+}
+
+func (s sessionStorage) GetByRefreshToken(ctx context.Context, tokenHash string) (*session.Session, error) {
+	return s.get(ctx, `SELECT id, user_login, refresh_token_hash, previous_token_hash, created_at, expires_at, revoked_at
+		FROM user_sessions WHERE refresh_token_hash = ? OR previous_token_hash = ?`, tokenHash, tokenHash)
+}
+
+func (s sessionStorage) get(ctx context.Context, query string, args ...any) (*session.Session, error) {
+	var (
+		ss        session.Session
+		revokedAt sql.NullTime
+	)
+	err := s.connection().QueryRowContext(ctx, query, args...).
+		Scan(&ss.ID, &ss.UserLogin, &ss.RefreshTokenHash, &ss.PreviousTokenHash, &ss.CreatedAt, &ss.ExpiresAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if revokedAt.Valid {
+		ss.RevokedAt = revokedAt.Time
+	}
+
+	return &ss, nil
+}
+
+func (s sessionStorage) Rotate(ctx context.Context, ss session.Session) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE user_sessions SET refresh_token_hash = ?, previous_token_hash = ?, expires_at = ?
+			WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL`,
+		ss.RefreshTokenHash, ss.PreviousTokenHash, ss.ExpiresAt, ss.ID, ss.PreviousTokenHash)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := s.Get(ctx, ss.ID); err != nil {
+			return err
+		}
+		return storage.ErrRecordConflict
+	}
+
+	return nil
+}
+
+func (s sessionStorage) Revoke(ctx context.Context, id string, at time.Time) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE user_sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s sessionStorage) RevokeByUser(ctx context.Context, login user.Login, at time.Time, keepID string) error {
+	_, err := s.connection().ExecContext(ctx,
+		`UPDATE user_sessions SET revoked_at = ? WHERE user_login = ? AND id <> ? AND revoked_at IS NULL`, at, login, keepID)
+	return err
+}
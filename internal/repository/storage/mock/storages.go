@@ -3,6 +3,7 @@ package mock
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"github.com/Karzoug/loyalty_program/pkg/e"
@@ -13,9 +14,16 @@ var _ storage.TxStorages = (*storages)(nil)
 type storages struct {
 	db *sql.DB
 
-	userStorage     storage.User
-	orderStorage    storage.Order
-	withdrawStorage storage.Withdraw
+	userStorage         storage.User
+	orderStorage        storage.Order
+	withdrawStorage     storage.Withdraw
+	ledgerStorage       storage.Ledger
+	jobStorage          storage.Job
+	deadLetterStorage   storage.DeadLetter
+	sessionStorage      storage.Session
+	revokedTokenStorage storage.RevokedToken
+	loginAttemptStorage storage.LoginAttempt
+	accrualHoldStorage  storage.AccrualHold
 }
 
 // NewStorages returns a mock set of storages for a service to work with data (for testing purposes only).
@@ -26,23 +34,56 @@ func NewStorages(ctx context.Context) (*storages, error) {
 	}
 
 	return &storages{
-		db:              db,
-		userStorage:     NewUserStorage(db),
-		orderStorage:    NewOrderStorage(db),
-		withdrawStorage: NewWithdrawStorage(db),
+		db:                  db,
+		userStorage:         NewUserStorage(db),
+		orderStorage:        NewOrderStorage(db),
+		withdrawStorage:     NewWithdrawStorage(db),
+		ledgerStorage:       NewLedgerStorage(db),
+		jobStorage:          NewJobStorage(db),
+		deadLetterStorage:   NewDeadLetterStorage(db),
+		sessionStorage:      NewSessionStorage(db),
+		revokedTokenStorage: NewRevokedTokenStorage(db),
+		loginAttemptStorage: NewLoginAttemptStorage(db),
+		accrualHoldStorage:  NewAccrualHoldStorage(db),
 	}, nil
 }
 
+func (r *storages) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *storages) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
+
 func (r *storages) BeginTx(ctx context.Context) (storage.Transaction, error) {
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return &transaction{
-		tx:              tx,
-		userStorage:     newUserTxStorage(tx),
-		orderStorage:    newOrderTxStorage(tx),
-		withdrawStorage: newWithdrawTxStorage(tx),
+		tx:                  tx,
+		userStorage:         newUserTxStorage(tx),
+		orderStorage:        newOrderTxStorage(tx),
+		withdrawStorage:     newWithdrawTxStorage(tx),
+		ledgerStorage:       newLedgerTxStorage(tx),
+		jobStorage:          newJobTxStorage(tx),
+		deadLetterStorage:   newDeadLetterTxStorage(tx),
+		sessionStorage:      newSessionTxStorage(tx),
+		revokedTokenStorage: newRevokedTokenTxStorage(tx),
+		loginAttemptStorage: newLoginAttemptTxStorage(tx),
+		accrualHoldStorage:  newAccrualHoldTxStorage(tx),
 	}, nil
 }
 
@@ -61,12 +102,54 @@ func (r *storages) Withdraw() storage.Withdraw {
 	return r.withdrawStorage
 }
 
+// Ledger return ledger storage.
+func (r *storages) Ledger() storage.Ledger {
+	return r.ledgerStorage
+}
+
+// Job return order processing job storage.
+func (r *storages) Job() storage.Job {
+	return r.jobStorage
+}
+
+// DeadLetter return dead-lettered order processing job storage.
+func (r *storages) DeadLetter() storage.DeadLetter {
+	return r.deadLetterStorage
+}
+
+// Session return user session storage.
+func (r *storages) Session() storage.Session {
+	return r.sessionStorage
+}
+
+// RevokedToken return revoked access token storage.
+func (r *storages) RevokedToken() storage.RevokedToken {
+	return r.revokedTokenStorage
+}
+
+// LoginAttempt return failed login attempt storage.
+func (r *storages) LoginAttempt() storage.LoginAttempt {
+	return r.loginAttemptStorage
+}
+
+// AccrualHold return held accruals storage.
+func (r *storages) AccrualHold() storage.AccrualHold {
+	return r.accrualHoldStorage
+}
+
 type transaction struct {
 	tx *sql.Tx
 
-	userStorage     storage.User
-	orderStorage    storage.Order
-	withdrawStorage storage.Withdraw
+	userStorage         storage.User
+	orderStorage        storage.Order
+	withdrawStorage     storage.Withdraw
+	ledgerStorage       storage.Ledger
+	jobStorage          storage.Job
+	deadLetterStorage   storage.DeadLetter
+	sessionStorage      storage.Session
+	revokedTokenStorage storage.RevokedToken
+	loginAttemptStorage storage.LoginAttempt
+	accrualHoldStorage  storage.AccrualHold
 }
 
 func (t *transaction) Commit(ctx context.Context) error {
@@ -90,3 +173,38 @@ func (t *transaction) Order() storage.Order {
 func (t *transaction) Withdraw() storage.Withdraw {
 	return t.withdrawStorage
 }
+
+// Ledger return ledger storage with transaction.
+func (t *transaction) Ledger() storage.Ledger {
+	return t.ledgerStorage
+}
+
+// Job return order processing job storage with transaction.
+func (t *transaction) Job() storage.Job {
+	return t.jobStorage
+}
+
+// DeadLetter return dead-lettered order processing job storage with transaction.
+func (t *transaction) DeadLetter() storage.DeadLetter {
+	return t.deadLetterStorage
+}
+
+// Session return user session storage with transaction.
+func (t *transaction) Session() storage.Session {
+	return t.sessionStorage
+}
+
+// RevokedToken return revoked access token storage with transaction.
+func (t *transaction) RevokedToken() storage.RevokedToken {
+	return t.revokedTokenStorage
+}
+
+// LoginAttempt return failed login attempt storage with transaction.
+func (t *transaction) LoginAttempt() storage.LoginAttempt {
+	return t.loginAttemptStorage
+}
+
+// AccrualHold return held accruals storage with transaction.
+func (t *transaction) AccrualHold() storage.AccrualHold {
+	return t.accrualHoldStorage
+}
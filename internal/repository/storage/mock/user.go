@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
 
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
@@ -37,8 +38,8 @@ func (s userStorage) connection() sqliteConnecter {
 }
 
 func (s userStorage) Create(ctx context.Context, user user.User) error {
-	res, err := s.connection().ExecContext(ctx, `INSERT INTO users(login, encrypted_password, balance) VALUES(?, ?, ?)`,
-		user.Login, user.EncryptedPassword, user.Balance)
+	res, err := s.connection().ExecContext(ctx, `INSERT INTO users(login, encrypted_password, balance, role) VALUES(?, ?, ?, ?)`,
+		user.Login, user.EncryptedPassword, user.Balance, user.Role)
 	if err != nil {
 		if strings.Contains(err.Error(), duplicateKeyErrorCode) {
 			return storage.ErrRecordAlreadyExists
@@ -72,17 +73,101 @@ func (s userStorage) UpdateBalance(ctx context.Context, login user.Login, delta
 	return &balance, nil
 }
 
+func (s userStorage) UpdatePassword(ctx context.Context, login user.Login, encryptedPassword string) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE users SET encrypted_password = ? WHERE login = ?`, encryptedPassword, login)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
 func (s userStorage) Get(ctx context.Context, login user.Login) (*user.User, error) {
-	user := user.User{Login: login}
+	var (
+		user      = user.User{Login: login}
+		blockedAt sql.NullTime
+	)
 	err := s.connection().QueryRowContext(ctx,
-		`SELECT encrypted_password, balance FROM users WHERE login = ?`, login).
-		Scan(&user.EncryptedPassword, &user.Balance)
+		`SELECT encrypted_password, balance, role, blocked_at, blocked_reason FROM users WHERE login = ?`, login).
+		Scan(&user.EncryptedPassword, &user.Balance, &user.Role, &blockedAt, &user.BlockReason)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, storage.ErrRecordNotFound
 		}
 		return nil, err
 	}
+	if blockedAt.Valid {
+		user.BlockedAt = blockedAt.Time
+	}
 
 	return &user, nil
 }
+
+// GetForUpdate gets the user as Get does: sqlite has no row locks,
+// transactions are serialized by the single connection of the database.
+func (s userStorage) GetForUpdate(ctx context.Context, login user.Login) (*user.User, error) {
+	return s.Get(ctx, login)
+}
+
+func (s userStorage) UpdateRole(ctx context.Context, login user.Login, role user.Role) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE users SET role = ? WHERE login = ?`, role, login)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s userStorage) Block(ctx context.Context, login user.Login, at time.Time, reason string) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE users SET blocked_at = ?, blocked_reason = ? WHERE login = ?`, at, reason, login)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s userStorage) Unblock(ctx context.Context, login user.Login) error {
+	res, err := s.connection().ExecContext(ctx,
+		`UPDATE users SET blocked_at = NULL, blocked_reason = '' WHERE login = ?`, login)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/model/withdraw"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage/sqlbuilder"
 	"github.com/shopspring/decimal"
 )
 
@@ -100,6 +101,37 @@ func (s withdrawStorage) GetByUser(ctx context.Context, login user.Login) ([]wit
 	return withdrawals, nil
 }
 
+func (s withdrawStorage) ListByUser(ctx context.Context, login user.Login, filter storage.WithdrawFilter) ([]withdraw.Withdraw, error) {
+	var where sqlbuilder.Where
+	where.Add("user_login = %s", login)
+	where.AddPage("processed_at", "order_number", filter.From, filter.To, filter.After)
+
+	rows, err := s.connection().QueryContext(ctx,
+		`SELECT order_number, sum, processed_at FROM withdrawals WHERE `+where.String()+
+			` ORDER BY processed_at, order_number`+sqlbuilder.Limit(filter.Limit), where.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	withdrawals := make([]withdraw.Withdraw, 0)
+	for rows.Next() {
+		withdraw := withdraw.Withdraw{UserLogin: login}
+		err := rows.Scan(&withdraw.OrderNumber, &withdraw.Sum, &withdraw.ProcessedAt)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, withdraw)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return withdrawals, nil
+}
+
 func (s withdrawStorage) CountByUser(ctx context.Context, login user.Login) (int, error) {
 	var count int
 	err := s.connection().QueryRowContext(ctx,
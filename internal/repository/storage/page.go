@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+)
+
+// Cursor is a keyset pagination position: the time and the order number of the last record of the previous page.
+// Records are listed in ascending order of (time, number).
+type Cursor struct {
+	Time   time.Time
+	Number order.Number
+}
+
+// OrderFilter restricts the list of user orders.
+type OrderFilter struct {
+	// Statuses - orders with any of the statuses (empty - all statuses).
+	Statuses []order.Status
+	// From - orders uploaded at or after the time (zero - no limit).
+	From time.Time
+	// To - orders uploaded before the time (zero - no limit).
+	To time.Time
+	// After - orders after the cursor (nil - from the first one).
+	After *Cursor
+	// Limit - max count of orders (0 - no limit).
+	Limit int
+}
+
+// WithdrawFilter restricts the list of user withdrawals.
+type WithdrawFilter struct {
+	// From - withdrawals processed at or after the time (zero - no limit).
+	From time.Time
+	// To - withdrawals processed before the time (zero - no limit).
+	To time.Time
+	// After - withdrawals after the cursor (nil - from the first one).
+	After *Cursor
+	// Limit - max count of withdrawals (0 - no limit).
+	Limit int
+}
@@ -0,0 +1,96 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ storage.AccrualHold = (*accrualHoldStorage)(nil)
+
+type accrualHoldStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newAccrualHoldStorage(pool *pgxpool.Pool) *accrualHoldStorage {
+	return &accrualHoldStorage{
+		pool: pool,
+	}
+}
+
+func newAccrualHoldTxStorage(tx pgx.Tx) *accrualHoldStorage {
+	return &accrualHoldStorage{
+		tx: tx,
+	}
+}
+
+func (s accrualHoldStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+func (s accrualHoldStorage) Create(ctx context.Context, hold ledger.Hold) error {
+	tag, err := s.connection().Exec(ctx, `INSERT INTO accrual_holds(order_number, user_login, amount, created_at) VALUES($1, $2, $3, $4)`,
+		hold.OrderNumber, hold.UserLogin, hold.Amount, hold.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s accrualHoldStorage) ListByUser(ctx context.Context, login user.Login) ([]ledger.Hold, error) {
+	rows, err := s.connection().Query(ctx,
+		`SELECT order_number, amount, created_at FROM accrual_holds WHERE user_login = $1 ORDER BY created_at`, login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ledger.Hold, error) {
+		hold := ledger.Hold{UserLogin: login}
+		err := rows.Scan(&hold.OrderNumber, &hold.Amount, &hold.CreatedAt)
+		return hold, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+func (s accrualHoldStorage) Delete(ctx context.Context, number order.Number) error {
+	tag, err := s.connection().Exec(ctx, `DELETE FROM accrual_holds WHERE order_number = $1`, number)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
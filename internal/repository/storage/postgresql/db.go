@@ -11,8 +11,9 @@ import (
 )
 
 const (
-	duplicateKeyErrorCode = "23505"
-	poolCreationTimeout   = 3 * time.Second
+	duplicateKeyErrorCode   = "23505"
+	undefinedTableErrorCode = "42P01"
+	poolCreationTimeout     = 3 * time.Second
 )
 
 type configPostgreSQLStorage interface {
@@ -20,7 +21,13 @@ type configPostgreSQLStorage interface {
 }
 
 func newDBPool(ctx context.Context, cfg configPostgreSQLStorage) (*pgxpool.Pool, error) {
-	pool, err := pgxpool.New(ctx, cfg.DatabaseURI())
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURI())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse connection string: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
@@ -0,0 +1,95 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ storage.DeadLetter = (*deadLetterStorage)(nil)
+
+type deadLetterStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newDeadLetterStorage(pool *pgxpool.Pool) *deadLetterStorage {
+	return &deadLetterStorage{
+		pool: pool,
+	}
+}
+
+func newDeadLetterTxStorage(tx pgx.Tx) *deadLetterStorage {
+	return &deadLetterStorage{
+		tx: tx,
+	}
+}
+
+func (s deadLetterStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+func (s deadLetterStorage) Create(ctx context.Context, dl job.DeadLetter) error {
+	tag, err := s.connection().Exec(ctx, `INSERT INTO order_dead_letters(order_number, attempts, reason, created_at, dead_lettered_at) VALUES($1, $2, $3, $4, $5)`,
+		dl.OrderNumber, dl.Attempts, dl.Reason, dl.CreatedAt, dl.DeadLetteredAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s deadLetterStorage) List(ctx context.Context) ([]job.DeadLetter, error) {
+	rows, err := s.connection().Query(ctx,
+		`SELECT order_number, attempts, reason, created_at, dead_lettered_at FROM order_dead_letters ORDER BY dead_lettered_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dls, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (job.DeadLetter, error) {
+		var dl job.DeadLetter
+		err := rows.Scan(&dl.OrderNumber, &dl.Attempts, &dl.Reason, &dl.CreatedAt, &dl.DeadLetteredAt)
+		return dl, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return dls, nil
+}
+
+func (s deadLetterStorage) Delete(ctx context.Context, number order.Number) error {
+	tag, err := s.connection().Exec(ctx, `DELETE FROM order_dead_letters WHERE order_number = $1`, number)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ storage.Job = (*jobStorage)(nil)
+
+type jobStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newJobStorage(pool *pgxpool.Pool) *jobStorage {
+	return &jobStorage{
+		pool: pool,
+	}
+}
+
+func newJobTxStorage(tx pgx.Tx) *jobStorage {
+	return &jobStorage{
+		tx: tx,
+	}
+}
+
+func (s jobStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+// Create inserts the job and sets the processing schedule of its order.
+func (s jobStorage) Create(ctx context.Context, job job.Job) error {
+	tag, err := s.connection().Exec(ctx,
+		`WITH job AS (
+			INSERT INTO order_jobs(order_number, trace_parent, request_id, created_at) VALUES($1, $2, $3, $4) RETURNING order_number
+		)
+		UPDATE orders SET attempts = $5, next_check_at = $6, last_error = $7 FROM job WHERE orders.number = job.order_number`,
+		job.OrderNumber, job.TraceParent, job.RequestID, job.CreatedAt, job.Attempts, job.NextCheckAt, job.LastError)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s jobStorage) Get(ctx context.Context, number order.Number) (*job.Job, error) {
+	job := job.Job{OrderNumber: number}
+	err := s.connection().QueryRow(ctx,
+		`SELECT o.attempts, o.next_check_at, o.last_error, j.trace_parent, j.request_id, j.created_at
+		FROM order_jobs j JOIN orders o ON o.number = j.order_number WHERE j.order_number = $1`, number).
+		Scan(&job.Attempts, &job.NextCheckAt, &job.LastError, &job.TraceParent, &job.RequestID, &job.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (s jobStorage) ClaimUnprocessed(ctx context.Context, workerID string, n int, leaseTTL time.Duration) ([]job.Job, error) {
+	now := time.Now().UTC()
+	rows, err := s.connection().Query(ctx,
+		`UPDATE order_jobs SET locked_by = $1, locked_until = $2 FROM orders
+		WHERE orders.number = order_jobs.order_number AND order_jobs.order_number IN
+			(SELECT j.order_number FROM order_jobs j JOIN orders o ON o.number = j.order_number
+			WHERE o.next_check_at <= $3 AND (j.locked_until IS NULL OR j.locked_until <= $3)
+			ORDER BY o.next_check_at LIMIT $4 FOR UPDATE OF j SKIP LOCKED)
+		RETURNING order_jobs.order_number, orders.attempts, orders.next_check_at, orders.last_error,
+			order_jobs.trace_parent, order_jobs.request_id, order_jobs.created_at`,
+		workerID, now.Add(leaseTTL), now, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (job.Job, error) {
+		var job job.Job
+		err := rows.Scan(&job.OrderNumber, &job.Attempts, &job.NextCheckAt, &job.LastError, &job.TraceParent, &job.RequestID, &job.CreatedAt)
+		return job, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (s jobStorage) Retry(ctx context.Context, number order.Number, workerID string, nextCheckAt time.Time, lastError string) error {
+	return s.release(ctx, number, workerID, `attempts = attempts + 1, next_check_at = $3, last_error = $4`, nextCheckAt, lastError)
+}
+
+func (s jobStorage) Postpone(ctx context.Context, number order.Number, workerID string, nextCheckAt time.Time) error {
+	return s.release(ctx, number, workerID, `attempts = 0, next_check_at = $3, last_error = ''`, nextCheckAt)
+}
+
+// release releases the lease of the worker and updates the order schedule with the set clause,
+// its arguments follow the order number and the worker ID.
+func (s jobStorage) release(ctx context.Context, number order.Number, workerID, set string, args ...any) error {
+	tag, err := s.connection().Exec(ctx,
+		`WITH job AS (
+			UPDATE order_jobs SET locked_by = '', locked_until = NULL WHERE order_number = $1 AND locked_by = $2 RETURNING order_number
+		)
+		UPDATE orders SET `+set+` FROM job WHERE orders.number = job.order_number`,
+		append([]any{number, workerID}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+// Delete deletes the job, the order has no next check anymore.
+func (s jobStorage) Delete(ctx context.Context, number order.Number, workerID string) error {
+	tag, err := s.connection().Exec(ctx,
+		`WITH job AS (
+			DELETE FROM order_jobs WHERE order_number = $1 AND ($2 = '' OR locked_by = $2) RETURNING order_number
+		)
+		UPDATE orders SET next_check_at = NULL FROM job WHERE orders.number = job.order_number`, number, workerID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s jobStorage) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.connection().QueryRow(ctx, `SELECT COUNT(*) FROM order_jobs`).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,95 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+var _ storage.Ledger = (*ledgerStorage)(nil)
+
+type ledgerStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newLedgerStorage(pool *pgxpool.Pool) *ledgerStorage {
+	return &ledgerStorage{
+		pool: pool,
+	}
+}
+
+func newLedgerTxStorage(tx pgx.Tx) *ledgerStorage {
+	return &ledgerStorage{
+		tx: tx,
+	}
+}
+
+func (s ledgerStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+func (s ledgerStorage) Create(ctx context.Context, entry ledger.Entry) error {
+	tag, err := s.connection().Exec(ctx, `INSERT INTO ledger_entries(id, user_login, order_number, operation, amount, reason, created_by, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+		entry.ID, entry.UserLogin, entry.OrderNumber, entry.Operation, entry.Amount, entry.Reason, entry.CreatedBy, entry.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s ledgerStorage) GetByUser(ctx context.Context, login user.Login) ([]ledger.Entry, error) {
+	rows, err := s.connection().Query(ctx,
+		`SELECT id, order_number, operation, amount, reason, created_by, created_at FROM ledger_entries WHERE user_login = $1 ORDER BY created_at`, login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ledger.Entry, error) {
+		entry := ledger.Entry{UserLogin: login}
+		err := rows.Scan(&entry.ID, &entry.OrderNumber, &entry.Operation, &entry.Amount, &entry.Reason, &entry.CreatedBy, &entry.CreatedAt)
+		return entry, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s ledgerStorage) BalanceByUser(ctx context.Context, login user.Login) (*decimal.Decimal, error) {
+	var sum decimal.NullDecimal
+	err := s.connection().QueryRow(ctx,
+		`SELECT SUM(amount) FROM ledger_entries WHERE user_login = $1`, login).Scan(&sum)
+
+	if !sum.Valid {
+		return &decimal.Zero, err
+	}
+
+	return &sum.Decimal, err
+}
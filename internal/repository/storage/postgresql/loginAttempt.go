@@ -0,0 +1,114 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ storage.LoginAttempt = (*loginAttemptStorage)(nil)
+
+type loginAttemptStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newLoginAttemptStorage(pool *pgxpool.Pool) *loginAttemptStorage {
+	return &loginAttemptStorage{
+		pool: pool,
+	}
+}
+
+func newLoginAttemptTxStorage(tx pgx.Tx) *loginAttemptStorage {
+	return &loginAttemptStorage{
+		tx: tx,
+	}
+}
+
+func (s loginAttemptStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+func (s loginAttemptStorage) Get(ctx context.Context, key string) (*lockout.Attempts, error) {
+	return s.scan(s.connection().QueryRow(ctx,
+		`SELECT attempt_key, failures, last_failure_at, locked_until FROM login_attempts WHERE attempt_key = $1`, key))
+}
+
+func (s loginAttemptStorage) AddFailure(ctx context.Context, key string, at, resetBefore time.Time) (*lockout.Attempts, error) {
+	_, err := s.connection().Exec(ctx,
+		`DELETE FROM login_attempts WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until <= $2)`, resetBefore, at)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.scan(s.connection().QueryRow(ctx,
+		`INSERT INTO login_attempts(attempt_key, failures, last_failure_at) VALUES($1, 1, $2)
+			ON CONFLICT (attempt_key) DO UPDATE SET
+				failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+				last_failure_at = $2
+			WHERE login_attempts.locked_until IS NULL OR login_attempts.locked_until <= $2
+			RETURNING attempt_key, failures, last_failure_at, locked_until`, key, at, resetBefore))
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			// the key is locked out, so the conflicting row is not updated
+			return nil, storage.ErrRecordConflict
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s loginAttemptStorage) scan(row pgx.Row) (*lockout.Attempts, error) {
+	var (
+		a           lockout.Attempts
+		lockedUntil *time.Time
+	)
+	err := row.Scan(&a.Key, &a.Failures, &a.LastFailureAt, &lockedUntil)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if lockedUntil != nil {
+		a.LockedUntil = *lockedUntil
+	}
+
+	return &a, nil
+}
+
+func (s loginAttemptStorage) Lock(ctx context.Context, key string, until time.Time) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE login_attempts SET locked_until = $1 WHERE attempt_key = $2`, until, key)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s loginAttemptStorage) RemoveFailure(ctx context.Context, key string, lockedUntil time.Time) error {
+	_, err := s.connection().Exec(ctx,
+		`UPDATE login_attempts SET failures = failures - 1,
+				locked_until = CASE WHEN locked_until = $2 THEN NULL ELSE locked_until END
+			WHERE attempt_key = $1 AND failures > 0`, key, lockedUntil)
+	return err
+}
+
+func (s loginAttemptStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.connection().Exec(ctx, `DELETE FROM login_attempts WHERE attempt_key = $1`, key)
+	return err
+}
@@ -3,11 +3,11 @@ package postgresql
 import (
 	"context"
 	"errors"
-	"time"
 
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage/sqlbuilder"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -98,25 +98,29 @@ func (s orderStorage) GetByUser(ctx context.Context, login user.Login) ([]order.
 	return orders, nil
 }
 
-func (s orderStorage) ListUnprocessed(ctx context.Context, limit, offset int, uploadedEarlierThan time.Time) ([]order.Order, error) {
-	var (
-		rows pgx.Rows
-		err  error
-	)
-
-	if limit == -1 {
-		rows, err = s.connection().Query(ctx, `SELECT number, user_login, status, accrual, uploaded_at FROM orders WHERE status NOT IN ($1, $2) AND uploaded_at < $3 ORDER BY uploaded_at OFFSET $4`, order.StatusInvalid, order.StatusProcessed, uploadedEarlierThan, offset)
-	} else {
-		rows, err = s.connection().Query(ctx, `SELECT number, user_login, status, accrual, uploaded_at FROM orders WHERE status NOT IN ($1, $2) AND uploaded_at < $3 ORDER BY uploaded_at LIMIT $4 OFFSET $5`, order.StatusInvalid, order.StatusProcessed, uploadedEarlierThan, limit, offset)
+func (s orderStorage) ListByUser(ctx context.Context, login user.Login, filter storage.OrderFilter) ([]order.Order, error) {
+	var where sqlbuilder.Where
+	where.Add("user_login = %s", login)
+	if len(filter.Statuses) > 0 {
+		statuses := make([]any, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, status)
+		}
+		where.AddIn("status", statuses)
 	}
+	where.AddPage("uploaded_at", "number", filter.From, filter.To, filter.After)
+
+	rows, err := s.connection().Query(ctx,
+		`SELECT number, status, accrual, uploaded_at FROM orders WHERE `+where.String()+
+			` ORDER BY uploaded_at, number`+sqlbuilder.Limit(filter.Limit), where.Args()...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	orders, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (order.Order, error) {
-		var order order.Order
-		err := rows.Scan(&order.Number, &order.UserLogin, &order.Status, &order.Accrual, &order.UploadedAt)
+		order := order.Order{UserLogin: login}
+		err := rows.Scan(&order.Number, &order.Status, &order.Accrual, &order.UploadedAt)
 		return order, err
 	})
 	if err != nil {
@@ -146,6 +150,30 @@ func (s orderStorage) Update(ctx context.Context, order order.Order) error {
 	return nil
 }
 
+func (s orderStorage) CompareAndUpdate(ctx context.Context, o order.Order, prevStatus order.Status) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE orders SET status = $1, accrual = $2 WHERE number = $3 AND status = $4`,
+		o.Status, o.Accrual, o.Number, prevStatus)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return s.compareAndUpdateMissError(ctx, o.Number)
+	}
+
+	return nil
+}
+
+// compareAndUpdateMissError tells apart a missing order and an order whose status was changed concurrently.
+func (s orderStorage) compareAndUpdateMissError(ctx context.Context, number order.Number) error {
+	_, err := s.Get(ctx, number)
+	if err != nil {
+		return err
+	}
+	return storage.ErrRecordConflict
+}
+
 func (s orderStorage) Delete(ctx context.Context, number order.Number) error {
 	tag, err := s.connection().Exec(ctx, `DELETE FROM orders WHERE number = $1`, number)
 	if err != nil {
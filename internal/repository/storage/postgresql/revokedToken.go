@@ -0,0 +1,69 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ storage.RevokedToken = (*revokedTokenStorage)(nil)
+
+type revokedTokenStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newRevokedTokenStorage(pool *pgxpool.Pool) *revokedTokenStorage {
+	return &revokedTokenStorage{
+		pool: pool,
+	}
+}
+
+func newRevokedTokenTxStorage(tx pgx.Tx) *revokedTokenStorage {
+	return &revokedTokenStorage{
+		tx: tx,
+	}
+}
+
+func (s revokedTokenStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+func (s revokedTokenStorage) Create(ctx context.Context, jti string, expiresAt time.Time) error {
+	tag, err := s.connection().Exec(ctx, `INSERT INTO revoked_tokens(jti, expires_at) VALUES($1, $2)`, jti, expiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s revokedTokenStorage) IsRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	var revoked bool
+	err := s.connection().QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)
+			OR NOT EXISTS(SELECT 1 FROM user_sessions WHERE id = $2 AND revoked_at IS NULL)`, jti, sessionID).Scan(&revoked)
+
+	return revoked, err
+}
+
+func (s revokedTokenStorage) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := s.connection().Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= $1`, now)
+	return err
+}
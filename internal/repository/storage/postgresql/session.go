@@ -0,0 +1,128 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ storage.Session = (*sessionStorage)(nil)
+
+type sessionStorage struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func newSessionStorage(pool *pgxpool.Pool) *sessionStorage {
+	return &sessionStorage{
+		pool: pool,
+	}
+}
+
+func newSessionTxStorage(tx pgx.Tx) *sessionStorage {
+	return &sessionStorage{
+		tx: tx,
+	}
+}
+
+func (s sessionStorage) connection() pgConnecter {
+	if s.tx == nil {
+		return s.pool
+	}
+	return s.tx
+}
+
+func (s sessionStorage) Create(ctx context.Context, ss session.Session) error {
+	tag, err := s.connection().Exec(ctx,
+		`INSERT INTO user_sessions(id, user_login, refresh_token_hash, previous_token_hash, created_at, expires_at) VALUES($1, $2, $3, $4, $5, $6)`,
+		ss.ID, ss.UserLogin, ss.RefreshTokenHash, ss.PreviousTokenHash, ss.CreatedAt, ss.ExpiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
+			return storage.ErrRecordAlreadyExists
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s sessionStorage) Get(ctx context.Context, id string) (*session.Session, error) {
+	return s.get(ctx, `SELECT id, user_login, refresh_token_hash, previous_token_hash, created_at, expires_at, revoked_at
+		FROM user_sessions WHERE id = $1`, id)
+}
+
+func (s sessionStorage) GetByRefreshToken(ctx context.Context, tokenHash string) (*session.Session, error) {
+	return s.get(ctx, `SELECT id, user_login, refresh_token_hash, previous_token_hash, created_at, expires_at, revoked_at
+		FROM user_sessions WHERE refresh_token_hash = $1 OR previous_token_hash = $1`, tokenHash)
+}
+
+func (s sessionStorage) get(ctx context.Context, query string, arg any) (*session.Session, error) {
+	var (
+		ss        session.Session
+		revokedAt *time.Time
+	)
+	err := s.connection().QueryRow(ctx, query, arg).
+		Scan(&ss.ID, &ss.UserLogin, &ss.RefreshTokenHash, &ss.PreviousTokenHash, &ss.CreatedAt, &ss.ExpiresAt, &revokedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if revokedAt != nil {
+		ss.RevokedAt = *revokedAt
+	}
+
+	return &ss, nil
+}
+
+func (s sessionStorage) Rotate(ctx context.Context, ss session.Session) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE user_sessions SET refresh_token_hash = $1, previous_token_hash = $2, expires_at = $3
+			WHERE id = $4 AND refresh_token_hash = $2 AND revoked_at IS NULL`,
+		ss.RefreshTokenHash, ss.PreviousTokenHash, ss.ExpiresAt, ss.ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		if _, err := s.Get(ctx, ss.ID); err != nil {
+			return err
+		}
+		return storage.ErrRecordConflict
+	}
+
+	return nil
+}
+
+func (s sessionStorage) Revoke(ctx context.Context, id string, at time.Time) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE user_sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s sessionStorage) RevokeByUser(ctx context.Context, login user.Login, at time.Time, keepID string) error {
+	_, err := s.connection().Exec(ctx,
+		`UPDATE user_sessions SET revoked_at = $1 WHERE user_login = $2 AND id <> $3 AND revoked_at IS NULL`, at, login, keepID)
+	return err
+}
@@ -2,46 +2,96 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 
+	"github.com/Karzoug/loyalty_program/internal/metrics"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"github.com/Karzoug/loyalty_program/pkg/e"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type storages struct {
 	pool *pgxpool.Pool
 
-	userStorage     storage.User
-	orderStorage    storage.Order
-	withdrawStorage storage.Withdraw
+	userStorage         storage.User
+	orderStorage        storage.Order
+	withdrawStorage     storage.Withdraw
+	ledgerStorage       storage.Ledger
+	jobStorage          storage.Job
+	deadLetterStorage   storage.DeadLetter
+	sessionStorage      storage.Session
+	revokedTokenStorage storage.RevokedToken
+	loginAttemptStorage storage.LoginAttempt
+	accrualHoldStorage  storage.AccrualHold
 }
 
-// NewStorages returns a set of storages for the service to work with data.
-func NewStorages(ctx context.Context, cfg configPostgreSQLStorage) (*storages, error) {
+// NewStorages returns a set of storages for the service to work with data,
+// the connection pool statistics are registered in reg.
+func NewStorages(ctx context.Context, cfg configPostgreSQLStorage, reg prometheus.Registerer) (*storages, error) {
 	pool, err := newDBPool(ctx, cfg)
 	if err != nil {
 		return nil, e.Wrap("open postgresql db connection", err)
 	}
+	if err := metrics.RegisterPool(reg, pool); err != nil {
+		return nil, e.Wrap("register db pool metrics", err)
+	}
 
 	return &storages{
-		pool:            pool,
-		userStorage:     newUserStorage(pool),
-		orderStorage:    newOrderStorage(pool),
-		withdrawStorage: newWithdrawStorage(pool),
+		pool:                pool,
+		userStorage:         newUserStorage(pool),
+		orderStorage:        newOrderStorage(pool),
+		withdrawStorage:     newWithdrawStorage(pool),
+		ledgerStorage:       newLedgerStorage(pool),
+		jobStorage:          newJobStorage(pool),
+		deadLetterStorage:   newDeadLetterStorage(pool),
+		sessionStorage:      newSessionStorage(pool),
+		revokedTokenStorage: newRevokedTokenStorage(pool),
+		loginAttemptStorage: newLoginAttemptStorage(pool),
+		accrualHoldStorage:  newAccrualHoldStorage(pool),
 	}, nil
 }
 
+func (r *storages) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+func (r *storages) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := r.pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == undefinedTableErrorCode) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
+
 func (r *storages) BeginTx(ctx context.Context) (storage.Transaction, error) {
 	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
+		metrics.TransactionFailures.WithLabelValues("begin").Inc()
 		return nil, err
 	}
 	return &transaction{
-		tx:              tx,
-		userStorage:     newUserTxStorage(tx),
-		orderStorage:    newOrderTxStorage(tx),
-		withdrawStorage: newWithdrawTxStorage(tx),
+		tx:                  tx,
+		userStorage:         newUserTxStorage(tx),
+		orderStorage:        newOrderTxStorage(tx),
+		withdrawStorage:     newWithdrawTxStorage(tx),
+		ledgerStorage:       newLedgerTxStorage(tx),
+		jobStorage:          newJobTxStorage(tx),
+		deadLetterStorage:   newDeadLetterTxStorage(tx),
+		sessionStorage:      newSessionTxStorage(tx),
+		revokedTokenStorage: newRevokedTokenTxStorage(tx),
+		loginAttemptStorage: newLoginAttemptTxStorage(tx),
+		accrualHoldStorage:  newAccrualHoldTxStorage(tx),
 	}, nil
 }
 
@@ -60,16 +110,62 @@ func (r *storages) Withdraw() storage.Withdraw {
 	return r.withdrawStorage
 }
 
+// Ledger return ledger storage.
+func (r *storages) Ledger() storage.Ledger {
+	return r.ledgerStorage
+}
+
+// Job return order processing job storage.
+func (r *storages) Job() storage.Job {
+	return r.jobStorage
+}
+
+// DeadLetter return dead-lettered order processing job storage.
+func (r *storages) DeadLetter() storage.DeadLetter {
+	return r.deadLetterStorage
+}
+
+// Session return user session storage.
+func (r *storages) Session() storage.Session {
+	return r.sessionStorage
+}
+
+// RevokedToken return revoked access token storage.
+func (r *storages) RevokedToken() storage.RevokedToken {
+	return r.revokedTokenStorage
+}
+
+// LoginAttempt return failed login attempt storage.
+func (r *storages) LoginAttempt() storage.LoginAttempt {
+	return r.loginAttemptStorage
+}
+
+// AccrualHold return held accruals storage.
+func (r *storages) AccrualHold() storage.AccrualHold {
+	return r.accrualHoldStorage
+}
+
 type transaction struct {
 	tx pgx.Tx
 
-	userStorage     storage.User
-	orderStorage    storage.Order
-	withdrawStorage storage.Withdraw
+	userStorage         storage.User
+	orderStorage        storage.Order
+	withdrawStorage     storage.Withdraw
+	ledgerStorage       storage.Ledger
+	jobStorage          storage.Job
+	deadLetterStorage   storage.DeadLetter
+	sessionStorage      storage.Session
+	revokedTokenStorage storage.RevokedToken
+	loginAttemptStorage storage.LoginAttempt
+	accrualHoldStorage  storage.AccrualHold
 }
 
 func (t *transaction) Commit(ctx context.Context) error {
-	return t.tx.Commit(ctx)
+	err := t.tx.Commit(ctx)
+	if err != nil {
+		metrics.TransactionFailures.WithLabelValues("commit").Inc()
+	}
+	return err
 }
 func (t *transaction) Rollback(ctx context.Context) error {
 	return t.tx.Rollback(ctx)
@@ -89,3 +185,38 @@ func (t *transaction) Order() storage.Order {
 func (t *transaction) Withdraw() storage.Withdraw {
 	return t.withdrawStorage
 }
+
+// Ledger return ledger storage with transaction.
+func (t *transaction) Ledger() storage.Ledger {
+	return t.ledgerStorage
+}
+
+// Job return order processing job storage with transaction.
+func (t *transaction) Job() storage.Job {
+	return t.jobStorage
+}
+
+// DeadLetter return dead-lettered order processing job storage with transaction.
+func (t *transaction) DeadLetter() storage.DeadLetter {
+	return t.deadLetterStorage
+}
+
+// Session return user session storage with transaction.
+func (t *transaction) Session() storage.Session {
+	return t.sessionStorage
+}
+
+// RevokedToken return revoked access token storage with transaction.
+func (t *transaction) RevokedToken() storage.RevokedToken {
+	return t.revokedTokenStorage
+}
+
+// LoginAttempt return failed login attempt storage with transaction.
+func (t *transaction) LoginAttempt() storage.LoginAttempt {
+	return t.loginAttemptStorage
+}
+
+// AccrualHold return held accruals storage with transaction.
+func (t *transaction) AccrualHold() storage.AccrualHold {
+	return t.accrualHoldStorage
+}
@@ -0,0 +1,49 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Karzoug/loyalty_program/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Karzoug/loyalty_program/internal/repository/storage/postgresql"
+
+var _ pgx.QueryTracer = queryTracer{}
+
+// queryTracer traces each query of the pool connections with a span of the query context.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func newQueryTracer() queryTracer {
+	return queryTracer{tracer: tracing.Tracer(tracerName)}
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "pgx.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatement(data.SQL),
+			attribute.Int("db.args_count", len(data.Args)),
+		))
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
@@ -3,6 +3,7 @@ package postgresql
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
@@ -40,8 +41,8 @@ func (s userStorage) connection() pgConnecter {
 }
 
 func (s userStorage) Create(ctx context.Context, user user.User) error {
-	tag, err := s.connection().Exec(ctx, `INSERT INTO users(login, encrypted_password, balance) VALUES($1, $2, $3)`,
-		user.Login, user.EncryptedPassword, user.Balance)
+	tag, err := s.connection().Exec(ctx, `INSERT INTO users(login, encrypted_password, balance, role) VALUES($1, $2, $3, $4)`,
+		user.Login, user.EncryptedPassword, user.Balance, user.Role)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == duplicateKeyErrorCode {
@@ -72,17 +73,88 @@ func (s userStorage) UpdateBalance(ctx context.Context, login user.Login, delta
 	return &balance, nil
 }
 
+func (s userStorage) UpdatePassword(ctx context.Context, login user.Login, encryptedPassword string) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE users SET encrypted_password = $1 WHERE login = $2`, encryptedPassword, login)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
 func (s userStorage) Get(ctx context.Context, login user.Login) (*user.User, error) {
-	user := user.User{Login: login}
-	err := s.connection().QueryRow(ctx,
-		`SELECT encrypted_password, balance FROM users WHERE login = $1`, login).
-		Scan(&user.EncryptedPassword, &user.Balance)
+	return s.get(ctx,
+		`SELECT encrypted_password, balance, role, blocked_at, blocked_reason FROM users WHERE login = $1`, login)
+}
+
+func (s userStorage) GetForUpdate(ctx context.Context, login user.Login) (*user.User, error) {
+	return s.get(ctx,
+		`SELECT encrypted_password, balance, role, blocked_at, blocked_reason FROM users WHERE login = $1 FOR UPDATE`, login)
+}
+
+func (s userStorage) get(ctx context.Context, query string, login user.Login) (*user.User, error) {
+	var (
+		user      = user.User{Login: login}
+		blockedAt *time.Time
+	)
+	err := s.connection().QueryRow(ctx, query, login).
+		Scan(&user.EncryptedPassword, &user.Balance, &user.Role, &blockedAt, &user.BlockReason)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, storage.ErrRecordNotFound
 		}
 		return nil, err
 	}
+	if blockedAt != nil {
+		user.BlockedAt = *blockedAt
+	}
 
 	return &user, nil
 }
+
+func (s userStorage) UpdateRole(ctx context.Context, login user.Login, role user.Role) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE users SET role = $1 WHERE login = $2`, role, login)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s userStorage) Block(ctx context.Context, login user.Login, at time.Time, reason string) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE users SET blocked_at = $1, blocked_reason = $2 WHERE login = $3`, at, reason, login)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
+
+func (s userStorage) Unblock(ctx context.Context, login user.Login) error {
+	tag, err := s.connection().Exec(ctx,
+		`UPDATE users SET blocked_at = NULL, blocked_reason = '' WHERE login = $1`, login)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNoRecordAffected
+	}
+
+	return nil
+}
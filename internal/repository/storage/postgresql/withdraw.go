@@ -8,6 +8,7 @@ import (
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/model/withdraw"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage/sqlbuilder"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -94,6 +95,36 @@ func (s withdrawStorage) GetByUser(ctx context.Context, login user.Login) ([]wit
 	return withdrawals, nil
 }
 
+func (s withdrawStorage) ListByUser(ctx context.Context, login user.Login, filter storage.WithdrawFilter) ([]withdraw.Withdraw, error) {
+	var where sqlbuilder.Where
+	where.Add("user_login = %s", login)
+	where.AddPage("processed_at", "order_number", filter.From, filter.To, filter.After)
+
+	rows, err := s.connection().Query(ctx,
+		`SELECT order_number, sum, processed_at FROM withdrawals WHERE `+where.String()+
+			` ORDER BY processed_at, order_number`+sqlbuilder.Limit(filter.Limit), where.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	withdrawals, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (withdraw.Withdraw, error) {
+		withdraw := withdraw.Withdraw{UserLogin: login}
+		err := rows.Scan(&withdraw.OrderNumber, &withdraw.Sum, &withdraw.ProcessedAt)
+		return withdraw, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return withdrawals, nil
+}
+
 func (s withdrawStorage) CountByUser(ctx context.Context, login user.Login) (int, error) {
 	var count int
 	err := s.connection().QueryRow(ctx,
@@ -0,0 +1,63 @@
+// Package sqlbuilder builds the dynamic parts of the queries the storages share.
+// Placeholders are positional ($1, $2, ...), both postgres and sqlite bind them by ordinal.
+package sqlbuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+// Where collects the conditions of a WHERE clause with positional arguments.
+type Where struct {
+	conds []string
+	args  []any
+}
+
+// Add adds the condition where each %s is replaced with a placeholder of the next argument.
+func (b *Where) Add(cond string, args ...any) {
+	placeholders := make([]any, 0, len(args))
+	for _, arg := range args {
+		b.args = append(b.args, arg)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(b.args)))
+	}
+	b.conds = append(b.conds, fmt.Sprintf(cond, placeholders...))
+}
+
+// AddIn adds the condition that the column value is one of the values.
+func (b *Where) AddIn(column string, values []any) {
+	b.Add(column+" IN ("+strings.TrimSuffix(strings.Repeat("%s, ", len(values)), ", ")+")", values...)
+}
+
+// AddPage adds the conditions of the time range and the keyset position.
+func (b *Where) AddPage(timeColumn, numberColumn string, from, to time.Time, after *storage.Cursor) {
+	if !from.IsZero() {
+		b.Add(timeColumn+" >= %s", from.UTC())
+	}
+	if !to.IsZero() {
+		b.Add(timeColumn+" < %s", to.UTC())
+	}
+	if after != nil {
+		b.Add("("+timeColumn+", "+numberColumn+") > (%s, %s)", after.Time.UTC(), after.Number)
+	}
+}
+
+// Args returns the arguments of the added conditions in the placeholders order.
+func (b Where) Args() []any {
+	return b.args
+}
+
+func (b Where) String() string {
+	return strings.Join(b.conds, " AND ")
+}
+
+// Limit returns the LIMIT clause, no clause for the not positive limit.
+func Limit(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(limit)
+}
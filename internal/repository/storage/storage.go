@@ -4,7 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/session"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/model/withdraw"
 	"github.com/shopspring/decimal"
@@ -13,16 +17,27 @@ import (
 type User interface {
 	Create(context.Context, user.User) error
 	Get(context.Context, user.Login) (*user.User, error)
+	// GetForUpdate gets the user and locks the row until the end of the transaction,
+	// so the blocked state read stays valid until the transaction is committed.
+	GetForUpdate(context.Context, user.Login) (*user.User, error)
 	UpdateBalance(ctx context.Context, login user.Login, deltaBalance decimal.Decimal) (*decimal.Decimal, error)
+	UpdatePassword(ctx context.Context, login user.Login, encryptedPassword string) error
+	UpdateRole(ctx context.Context, login user.Login, role user.Role) error
+	// Block marks the user blocked at the time for the reason, Unblock clears the mark.
+	Block(ctx context.Context, login user.Login, at time.Time, reason string) error
+	Unblock(ctx context.Context, login user.Login) error
 }
 
 type Order interface {
 	Create(context.Context, order.Order) error
 	Get(context.Context, order.Number) (*order.Order, error)
 	GetByUser(context.Context, user.Login) ([]order.Order, error)
-	// ListUnprocessed returns limit (-1 is a special value: no limit) orders not yet processed.
-	ListUnprocessed(ctx context.Context, limit, offset int, uploadedEarlierThan time.Time) ([]order.Order, error)
+	// ListByUser returns a page of user orders sorted by upload time and number.
+	ListByUser(context.Context, user.Login, OrderFilter) ([]order.Order, error)
 	Update(context.Context, order.Order) error
+	// CompareAndUpdate updates the order only if its stored status is still prevStatus,
+	// otherwise ErrRecordConflict is returned.
+	CompareAndUpdate(ctx context.Context, o order.Order, prevStatus order.Status) error
 	Delete(context.Context, order.Number) error
 }
 
@@ -30,7 +45,87 @@ type Withdraw interface {
 	Create(context.Context, withdraw.Withdraw) error
 	Get(context.Context, order.Number) (*withdraw.Withdraw, error)
 	GetByUser(context.Context, user.Login) ([]withdraw.Withdraw, error)
+	// ListByUser returns a page of user withdrawals sorted by processing time and order number.
+	ListByUser(context.Context, user.Login, WithdrawFilter) ([]withdraw.Withdraw, error)
 	CountByUser(context.Context, user.Login) (int, error)
 	SumByUser(context.Context, user.Login) (*decimal.Decimal, error)
 	Update(context.Context, withdraw.Withdraw) error
 }
+
+type Ledger interface {
+	Create(context.Context, ledger.Entry) error
+	GetByUser(context.Context, user.Login) ([]ledger.Entry, error)
+	// BalanceByUser returns the sum of all user ledger entries.
+	BalanceByUser(context.Context, user.Login) (*decimal.Decimal, error)
+}
+
+type Job interface {
+	Create(context.Context, job.Job) error
+	Get(context.Context, order.Number) (*job.Job, error)
+	// ClaimUnprocessed leases up to n jobs of the orders due to be checked (next check time has come) to the worker for leaseTTL.
+	// Jobs leased by other workers are skipped, so several service instances can share the queue.
+	ClaimUnprocessed(ctx context.Context, workerID string, n int, leaseTTL time.Duration) ([]job.Job, error)
+	// Retry records the failed attempt, releases the lease of the worker and postpones the order check until nextCheckAt.
+	// It returns ErrNoRecordAffected if the worker has lost the lease, e.g. the lease expired and another worker claimed the job.
+	Retry(ctx context.Context, number order.Number, workerID string, nextCheckAt time.Time, lastError string) error
+	// Postpone releases the lease of the worker after a successful attempt, that gave no final result,
+	// and postpones the order check until nextCheckAt. The failed attempts count is reset.
+	// It returns ErrNoRecordAffected if the worker has lost the lease.
+	Postpone(ctx context.Context, number order.Number, workerID string, nextCheckAt time.Time) error
+	// Delete deletes the job leased by the worker, it returns ErrNoRecordAffected if the worker has lost the lease.
+	// An empty worker ID deletes the job regardless of its lease.
+	Delete(ctx context.Context, number order.Number, workerID string) error
+	// Count returns the number of jobs, that is the number of orders waiting for processing.
+	Count(context.Context) (int, error)
+}
+
+type DeadLetter interface {
+	Create(context.Context, job.DeadLetter) error
+	List(context.Context) ([]job.DeadLetter, error)
+	Delete(context.Context, order.Number) error
+}
+
+type Session interface {
+	Create(context.Context, session.Session) error
+	Get(ctx context.Context, id string) (*session.Session, error)
+	// GetByRefreshToken returns the session whose current or previous refresh token has the hash.
+	GetByRefreshToken(ctx context.Context, tokenHash string) (*session.Session, error)
+	// Rotate stores the rotated refresh token only if the stored one is still the previous token of the session
+	// and the session is not revoked, otherwise ErrRecordConflict is returned.
+	Rotate(context.Context, session.Session) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// RevokeByUser revokes all active sessions of the user but the kept one (empty - none is kept).
+	RevokeByUser(ctx context.Context, login user.Login, at time.Time, keepID string) error
+}
+
+// RevokedToken is a denylist of access tokens IDs (jti) revoked before their expiry.
+type RevokedToken interface {
+	Create(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsRevoked reports in one query whether the token is in the denylist
+	// or the session it is issued for is revoked or does not exist.
+	IsRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// LoginAttempt tracks failed login attempts by a key: a login or a client IP.
+type LoginAttempt interface {
+	Get(ctx context.Context, key string) (*lockout.Attempts, error)
+	// AddFailure counts a failed attempt at the time and returns the updated attempts,
+	// failures before resetBefore are forgotten. Concurrent failures are counted atomically.
+	// The failure is not counted for the key locked out at the time, ErrRecordConflict is returned then.
+	// Forgotten attempts of all keys that are not locked at the time are deleted,
+	// so attempts of arbitrary logins do not pile up.
+	AddFailure(ctx context.Context, key string, at, resetBefore time.Time) (*lockout.Attempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	// RemoveFailure takes back a counted failure of the key, the lock is removed
+	// if it is still the one set until lockedUntil.
+	RemoveFailure(ctx context.Context, key string, lockedUntil time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+// AccrualHold keeps accruals of blocked users until they are unblocked.
+type AccrualHold interface {
+	Create(context.Context, ledger.Hold) error
+	ListByUser(context.Context, user.Login) ([]ledger.Hold, error)
+	Delete(context.Context, order.Number) error
+}
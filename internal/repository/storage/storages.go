@@ -6,11 +6,23 @@ type Storages interface {
 	User() User
 	Order() Order
 	Withdraw() Withdraw
+	Ledger() Ledger
+	Job() Job
+	DeadLetter() DeadLetter
+	Session() Session
+	RevokedToken() RevokedToken
+	LoginAttempt() LoginAttempt
+	AccrualHold() AccrualHold
 }
 
 type TxStorages interface {
 	Storages
 	BeginTx(ctx context.Context) (Transaction, error)
+	// Ping checks the database connection.
+	Ping(ctx context.Context) error
+	// MigrationVersion returns the applied schema migration version (0 - no migration is applied)
+	// and whether the last migration failed.
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
 }
 
 type Transaction interface {
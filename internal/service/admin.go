@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// GetUser returns the user account, it is used by admins and to issue tokens with the current user role.
+func (s *Service) GetUser(ctx context.Context, login user.Login) (*user.User, error) {
+	ctx, span := tracer.Start(ctx, "Service.GetUser")
+	defer span.End()
+
+	u, err := s.storages.User().Get(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// AdjustUserBalance manually changes the user balance by the signed sum on behalf of the admin,
+// the reason is kept in the ledger.
+func (s *Service) AdjustUserBalance(ctx context.Context, login user.Login, sum decimal.Decimal, reason string, admin user.Login) (*ledger.Entry, error) {
+	ctx, span := tracer.Start(ctx, "Service.AdjustUserBalance")
+	defer span.End()
+
+	entry, err := ledger.NewAdjustment(login, s.cfg.MoneyPolicy().Round(sum), reason, admin)
+	if err != nil {
+		switch {
+		case errors.Is(err, ledger.ErrInvalidAmount):
+			return nil, ErrInvalidAdjustment
+		case errors.Is(err, ledger.ErrEmptyReason):
+			return nil, ErrEmptyAdjustmentReason
+		case errors.Is(err, user.ErrInvalidLogin):
+			return nil, ErrInvalidLoginFormat
+		}
+		return nil, err
+	}
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	balance, err := tx.User().UpdateBalance(ctx, login, entry.Amount)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if balance.IsNegative() {
+		return nil, ErrInsufficientBalance
+	}
+
+	err = tx.Ledger().Create(ctx, *entry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx).Info("Adjust user balance",
+		zap.String("login", string(login)),
+		zap.String("admin", string(admin)),
+		zap.Stringer("sum", entry.Amount),
+		zap.String("reason", reason))
+
+	return entry, nil
+}
+
+// ReprocessOrder puts the not processed order to the processing queue again to be processed as soon as possible,
+// a queued job of the order is restarted and a dead-lettered order is requeued.
+// An invalid order is set new again, so the accrual service is asked for it once more.
+func (s *Service) ReprocessOrder(ctx context.Context, number order.Number) error {
+	ctx, span := tracer.Start(ctx, "Service.ReprocessOrder")
+	defer span.End()
+
+	j, err := s.newJob(ctx, number)
+	if err != nil {
+		if errors.Is(err, order.ErrInvalidNumber) {
+			return ErrInvalidOrderNumber
+		}
+		return err
+	}
+
+	o, err := s.storages.Order().Get(ctx, number)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+	if o.Status == order.StatusProcessed {
+		return ErrOrderAlreadyProcessed
+	}
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if o.Status == order.StatusInvalid {
+		newOrder := *o
+		newOrder.Status = order.StatusNew
+		if err := tx.Order().CompareAndUpdate(ctx, newOrder, order.StatusInvalid); err != nil {
+			return err
+		}
+	}
+	if err := tx.DeadLetter().Delete(ctx, number); err != nil && !errors.Is(err, storage.ErrNoRecordAffected) {
+		return err
+	}
+	// the job is deleted even if it is leased: the worker loses the lease and cannot finish it
+	if err := tx.Job().Delete(ctx, number, ""); err != nil && !errors.Is(err, storage.ErrNoRecordAffected) {
+		return err
+	}
+	if err := tx.Job().Create(ctx, *j); err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.notifyJobsCreated()
+
+	return nil
+}
+
+// BlockUser blocks the user account for the reason: the user cannot log in and withdraw,
+// all user sessions are revoked, so the issued tokens stop working, and accruals are held until the user is unblocked.
+func (s *Service) BlockUser(ctx context.Context, login user.Login, reason string) error {
+	ctx, span := tracer.Start(ctx, "Service.BlockUser")
+	defer span.End()
+
+	if strings.TrimSpace(reason) == "" {
+		return ErrEmptyBlockReason
+	}
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the user row is locked first, as the order processing locks it to read whether the user is blocked
+	_, err = tx.User().GetForUpdate(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = tx.User().Block(ctx, login, now, reason)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoRecordAffected) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	err = tx.Session().RevokeByUser(ctx, login, now, "")
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UnblockUser unblocks the user account and credits the accruals held while the user was blocked.
+func (s *Service) UnblockUser(ctx context.Context, login user.Login) error {
+	ctx, span := tracer.Start(ctx, "Service.UnblockUser")
+	defer span.End()
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the user row is locked first, so the order processing that has read the user blocked
+	// commits its hold before the holds are listed
+	_, err = tx.User().GetForUpdate(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	err = tx.User().Unblock(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoRecordAffected) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	holds, err := tx.AccrualHold().ListByUser(ctx, login)
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		entry, err := ledger.New(login, hold.OrderNumber, ledger.OperationAccrual, hold.Amount)
+		if err != nil {
+			return err
+		}
+		err = tx.Ledger().Create(ctx, *entry)
+		if err != nil {
+			return err
+		}
+		_, err = tx.User().UpdateBalance(ctx, login, hold.Amount)
+		if err != nil {
+			return err
+		}
+		err = tx.AccrualHold().Delete(ctx, hold.OrderNumber)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(holds) > 0 {
+		s.log(ctx).Info("Unblock user: held accruals credited", zap.String("login", string(login)), zap.Int("count", len(holds)))
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor"
+	pmock "github.com/Karzoug/loyalty_program/internal/repository/processor/mock"
+	smock "github.com/Karzoug/loyalty_program/internal/repository/storage/mock"
+	"github.com/pioz/faker"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_syncRole(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login, lateLogin := user.Login(faker.Username()), user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err := service.RegisterUser(ctx, lateLogin, password)
+	require.NoError(t, err)
+
+	service.cfg = mockConfig{adminLogins: []string{string(login), string(lateLogin)}}
+
+	// a configured login nobody has claimed yet is not granted the role on registration
+	u, err := service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleUser, u.Role)
+
+	u, err = service.LoginUser(ctx, login, password, "")
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleAdmin, u.Role)
+
+	// the user registered before it is configured as an admin gets the role on login
+	u, err = service.LoginUser(ctx, lateLogin, password, "")
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleAdmin, u.Role)
+	u, err = service.GetUser(ctx, lateLogin)
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleAdmin, u.Role)
+
+	// the role is revoked once the login is removed from the config
+	service.cfg = mockConfig{adminLogins: []string{string(login)}}
+	u, err = service.RefreshUser(ctx, lateLogin)
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleUser, u.Role)
+	u, err = service.GetUser(ctx, lateLogin)
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleUser, u.Role)
+
+	service.cfg = mockConfig{}
+	u, err = service.LoginUser(ctx, login, password, "")
+	require.NoError(t, err)
+	assert.Equal(t, user.RoleUser, u.Role)
+
+	_, err = service.RefreshUser(ctx, user.Login(faker.Username()))
+	assert.ErrorIs(t, err, ErrInvalidAuthData)
+}
+
+func TestService_AdjustUserBalance(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login, admin := user.Login(faker.Username()), user.Login(faker.Username())
+	_, err := service.RegisterUser(ctx, login, faker.StringWithSize(15))
+	require.NoError(t, err)
+
+	t.Run("positive", func(t *testing.T) {
+		entry, err := service.AdjustUserBalance(ctx, login, decimal.RequireFromString("100.005"), "compensation", admin)
+		require.NoError(t, err)
+		assert.True(t, decimal.RequireFromString("100.01").Equal(entry.Amount))
+
+		balance, err := service.GetUserBalance(ctx, login)
+		require.NoError(t, err)
+		assert.True(t, entry.Amount.Equal(*balance))
+
+		entries, err := service.ListUserLedgerEntries(ctx, login)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, ledger.OperationAdjustment, entries[0].Operation)
+		assert.Equal(t, "compensation", entries[0].Reason)
+		assert.Equal(t, admin, entries[0].CreatedBy)
+	})
+	t.Run("negative: balance goes negative", func(t *testing.T) {
+		_, err := service.AdjustUserBalance(ctx, login, decimal.NewFromInt(-1000), "fraud", admin)
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+	})
+	t.Run("negative: no reason", func(t *testing.T) {
+		_, err := service.AdjustUserBalance(ctx, login, decimal.NewFromInt(10), "", admin)
+		assert.ErrorIs(t, err, ErrEmptyAdjustmentReason)
+	})
+	t.Run("negative: zero sum", func(t *testing.T) {
+		_, err := service.AdjustUserBalance(ctx, login, decimal.Zero, "compensation", admin)
+		assert.ErrorIs(t, err, ErrInvalidAdjustment)
+	})
+	t.Run("negative: user not exists", func(t *testing.T) {
+		_, err := service.AdjustUserBalance(ctx, user.Login(faker.Username()), decimal.NewFromInt(10), "compensation", admin)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestService_ReprocessOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+	proc.SetResult(nil, processor.ErrOrderNotRegistered)
+
+	service := New(mockConfig{deadLetterMaxAttempts: 1}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	_, err = service.RegisterUser(ctx, login, faker.StringWithSize(15))
+	require.NoError(t, err)
+
+	o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+	require.NoError(t, err)
+
+	t.Run("queued order is restarted", func(t *testing.T) {
+		require.NoError(t, service.ReprocessOrder(ctx, o.Number))
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, 0, j.Attempts)
+	})
+	t.Run("dead-lettered order is requeued", func(t *testing.T) {
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+
+		dls, err := service.ListDeadLetterOrders(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(dls))
+
+		require.NoError(t, service.ReprocessOrder(ctx, o.Number))
+
+		dls, err = service.ListDeadLetterOrders(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, dls)
+		_, err = service.storages.Job().Get(ctx, o.Number)
+		assert.NoError(t, err)
+	})
+	t.Run("invalid order is set new", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+		invalidOrder := *o
+		invalidOrder.Status = order.StatusInvalid
+		require.NoError(t, service.storages.Order().CompareAndUpdate(ctx, invalidOrder, o.Status))
+		require.NoError(t, service.storages.Job().Delete(ctx, o.Number, ""))
+
+		require.NoError(t, service.ReprocessOrder(ctx, o.Number))
+
+		storageOrder, err := service.storages.Order().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, order.StatusNew, storageOrder.Status)
+		_, err = service.storages.Job().Get(ctx, o.Number)
+		assert.NoError(t, err)
+	})
+	t.Run("negative: order not exists", func(t *testing.T) {
+		err := service.ReprocessOrder(ctx, generateOrderNumber(t))
+		assert.ErrorIs(t, err, ErrOrderNotFound)
+	})
+}
+
+func TestService_BlockUser(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+
+	service := New(mockConfig{}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+	_, err = service.AdjustUserBalance(ctx, login, decimal.NewFromInt(100), "compensation", "admin")
+	require.NoError(t, err)
+	ss, _, err := service.CreateSession(ctx, login)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.BlockUser(ctx, login, ""), ErrEmptyBlockReason)
+	require.NoError(t, service.BlockUser(ctx, login, "fraud"))
+
+	t.Run("blocked user", func(t *testing.T) {
+		u, err := service.GetUser(ctx, login)
+		require.NoError(t, err)
+		assert.True(t, u.Blocked())
+		assert.Equal(t, "fraud", u.BlockReason)
+	})
+	t.Run("sessions are revoked", func(t *testing.T) {
+		revoked, err := service.IsTokenRevoked(ctx, "jti", ss.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+	t.Run("login is rejected", func(t *testing.T) {
+		_, err := service.LoginUser(ctx, login, password, "")
+		assert.ErrorIs(t, err, ErrUserBlocked)
+		// the state is not revealed without the right password
+		_, err = service.LoginUser(ctx, login, "wrong password", "")
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+	})
+	t.Run("withdraw is rejected", func(t *testing.T) {
+		_, err := service.CreateWithdraw(ctx, login, generateOrderNumber(t), decimal.NewFromInt(10))
+		assert.ErrorIs(t, err, ErrUserBlocked)
+	})
+
+	o, err := order.New(generateOrderNumber(t), login)
+	require.NoError(t, err)
+	require.NoError(t, service.storages.Order().Create(ctx, *o))
+	procOrder := *o
+	procOrder.Status = order.StatusProcessed
+	procOrder.Accrual = decimal.NewFromInt(50)
+	proc.SetResult(&procOrder, nil)
+
+	t.Run("accrual is held", func(t *testing.T) {
+		final, err := service.processOrder(ctx, *o)
+		require.NoError(t, err)
+		assert.True(t, final)
+
+		balance, err := service.GetUserBalance(ctx, login)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(100).Equal(*balance))
+	})
+	t.Run("unblock credits held accruals", func(t *testing.T) {
+		require.NoError(t, service.UnblockUser(ctx, login))
+
+		u, err := service.GetUser(ctx, login)
+		require.NoError(t, err)
+		assert.False(t, u.Blocked())
+		assert.True(t, decimal.NewFromInt(150).Equal(u.Balance))
+
+		balance, err := service.GetUserBalance(ctx, login)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(150).Equal(*balance))
+
+		holds, err := service.storages.AccrualHold().ListByUser(ctx, login)
+		require.NoError(t, err)
+		assert.Empty(t, holds)
+
+		_, err = service.LoginUser(ctx, login, password, "")
+		assert.NoError(t, err)
+	})
+
+	assert.ErrorIs(t, service.BlockUser(ctx, user.Login(faker.Username()), "fraud"), ErrUserNotFound)
+	assert.ErrorIs(t, service.UnblockUser(ctx, user.Login(faker.Username())), ErrUserNotFound)
+}
+
+func TestService_UnblockUser_concurrentProcessOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+
+	service := New(mockConfig{}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	_, err = service.RegisterUser(ctx, login, faker.StringWithSize(15))
+	require.NoError(t, err)
+
+	// an accrual of an order processed while the user is unblocked is either held and credited
+	// by the unblock or credited by the processing, it is never left held
+	const ordersCount = 10
+	accrual := decimal.NewFromInt(10)
+	for i := 0; i < ordersCount; i++ {
+		require.NoError(t, service.BlockUser(ctx, login, "fraud"))
+
+		o, err := order.New(generateOrderNumber(t), login)
+		require.NoError(t, err)
+		require.NoError(t, service.storages.Order().Create(ctx, *o))
+		procOrder := *o
+		procOrder.Status = order.StatusProcessed
+		procOrder.Accrual = accrual
+		proc.SetResult(&procOrder, nil)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := service.processOrder(ctx, *o)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, service.UnblockUser(ctx, login))
+		}()
+		wg.Wait()
+	}
+
+	holds, err := service.storages.AccrualHold().ListByUser(ctx, login)
+	require.NoError(t, err)
+	assert.Empty(t, holds)
+
+	balance, err := service.GetUserBalance(ctx, login)
+	require.NoError(t, err)
+	assert.True(t, accrual.Mul(decimal.NewFromInt(ordersCount)).Equal(*balance), "balance %s", balance)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+func (s *Service) ListDeadLetterOrders(ctx context.Context) ([]job.DeadLetter, error) {
+	ctx, span := tracer.Start(ctx, "Service.ListDeadLetterOrders")
+	defer span.End()
+
+	dls, err := s.storages.DeadLetter().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return dls, nil
+}
+
+// RequeueDeadLetterOrder moves the dead-lettered order back to the processing queue.
+func (s *Service) RequeueDeadLetterOrder(ctx context.Context, number order.Number) error {
+	ctx, span := tracer.Start(ctx, "Service.RequeueDeadLetterOrder")
+	defer span.End()
+
+	j, err := s.newJob(ctx, number)
+	if err != nil {
+		if errors.Is(err, order.ErrInvalidNumber) {
+			return ErrInvalidOrderNumber
+		}
+		return err
+	}
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.DeadLetter().Delete(ctx, number)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoRecordAffected) {
+			return ErrOrderNotDeadLettered
+		}
+		return err
+	}
+	err = tx.Job().Create(ctx, *j)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.notifyJobsCreated()
+
+	return nil
+}
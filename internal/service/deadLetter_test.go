@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor"
+	pmock "github.com/Karzoug/loyalty_program/internal/repository/processor/mock"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	smock "github.com/Karzoug/loyalty_program/internal/repository/storage/mock"
+	"github.com/pioz/faker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_deadLetter(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+
+	service := New(mockConfig{deadLetterMaxAttempts: 2}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	t.Run("server not respond: not dead-lettered", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+
+		proc.SetResult(nil, processor.ErrServerNotRespond)
+		for i := 0; i < 3; i++ {
+			j, err := service.storages.Job().Get(ctx, o.Number)
+			require.NoError(t, err)
+			service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+		}
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, 3, j.Attempts)
+	})
+
+	t.Run("order not registered: dead-lettered and re-queued", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+
+		proc.SetResult(nil, processor.ErrOrderNotRegistered)
+		for i := 0; i < 2; i++ {
+			j, err := service.storages.Job().Get(ctx, o.Number)
+			require.NoError(t, err)
+			service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+		}
+
+		_, err = service.storages.Job().Get(ctx, o.Number)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+
+		dls, err := service.ListDeadLetterOrders(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(dls))
+		assert.Equal(t, o.Number, dls[0].OrderNumber)
+		assert.Equal(t, 2, dls[0].Attempts)
+		assert.NotEmpty(t, dls[0].Reason)
+
+		err = service.RequeueDeadLetterOrder(ctx, o.Number)
+		require.NoError(t, err)
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, 0, j.Attempts)
+
+		dls, err = service.ListDeadLetterOrders(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(dls))
+
+		err = service.RequeueDeadLetterOrder(ctx, o.Number)
+		assert.ErrorIs(t, err, ErrOrderNotDeadLettered)
+	})
+}
+
+func TestService_deadLetter_maxAge(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+	proc.SetResult(nil, processor.ErrOrderNotRegistered)
+
+	service := New(mockConfig{deadLetterMaxAge: time.Millisecond}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+	j, err := service.storages.Job().Get(ctx, o.Number)
+	require.NoError(t, err)
+	service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+
+	dls, err := service.ListDeadLetterOrders(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(dls))
+	assert.Equal(t, o.Number, dls[0].OrderNumber)
+}
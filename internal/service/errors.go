@@ -3,7 +3,9 @@ package service
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/money"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 )
 
@@ -13,8 +15,33 @@ var (
 	ErrInvalidLoginFormat    = fmt.Errorf("invalid login format: must have (0; %d] UTF-8 characters count", user.MaxRuneCountInLogin)
 	ErrInvalidPasswordFormat = errors.New("invalid password format: must have (0; 72] bytes UTF-8 characters")
 	ErrInvalidAuthData       = errors.New("invalid login/password/token")
+	ErrInvalidSum            = money.ErrInvalidSum
+	ErrTooPreciseSum         = money.ErrTooPreciseSum
+	ErrTooManyLoginAttempts  = errors.New("too many failed login attempts")
 
 	ErrInvalidOrderNumber     = errors.New("invalid order number")
 	ErrAnotherUserOrderNumber = errors.New("invalid order number: another user's order")
 	ErrReAttemptWithdraw      = errors.New("re-attempt to withdraw")
+	ErrOrderNotDeadLettered   = errors.New("order is not dead-lettered")
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrOrderAlreadyProcessed  = errors.New("order is already processed")
+
+	ErrUserNotFound          = errors.New("user not found")
+	ErrUserBlocked           = errors.New("user is blocked")
+	ErrEmptyBlockReason      = errors.New("block reason must be non empty")
+	ErrInvalidAdjustment     = errors.New("invalid adjustment: sum must be non zero")
+	ErrEmptyAdjustmentReason = errors.New("adjustment reason must be non empty")
 )
+
+// LoginLockedError is returned when login attempts are temporarily locked out, it matches ErrTooManyLoginAttempts.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrTooManyLoginAttempts
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CheckReadiness(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	components := make(map[string]Component)
+	for _, c := range service.CheckReadiness(ctx) {
+		components[c.Name] = c
+	}
+	assert.Equal(t, ComponentUp, components["database"].Status)
+	assert.Equal(t, ComponentUp, components["migrations"].Status, components["migrations"].Details)
+	// the order processing workers are not run
+	assert.Equal(t, ComponentDown, components["order processing"].Status)
+	assert.False(t, components["order processing"].Ready())
+	// the processor has no circuit breaker
+	assert.Equal(t, ComponentUp, components["accrual system"].Status)
+
+	runCtx, stopRun := context.WithCancel(ctx)
+	runErr := make(chan error)
+	go func() {
+		runErr <- service.Run(runCtx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		for _, c := range service.CheckReadiness(ctx) {
+			if !c.Ready() {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	stopRun()
+	assert.NoError(t, <-runErr)
+}
+
+// migrationVersionStorages reports the schema version migrated by another release.
+type migrationVersionStorages struct {
+	storage.TxStorages
+	version uint
+}
+
+func (s migrationVersionStorages) MigrationVersion(context.Context) (uint, bool, error) {
+	return s.version, false, nil
+}
+
+func TestService_checkMigrations(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	want, err := migrations.LatestVersion()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		version uint
+		status  string
+	}{
+		{name: "current", version: want, status: ComponentUp},
+		{name: "newer", version: want + 1, status: ComponentDown},
+		{name: "older", version: want - 1, status: ComponentDown},
+	}
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+	storages := service.storages
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.storages = migrationVersionStorages{TxStorages: storages, version: tt.version}
+
+			c := service.checkMigrations(ctx)
+			assert.Equal(t, tt.status, c.Status, c.Details)
+		})
+	}
+}
+
+func TestComponent_Ready(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, Component{Status: ComponentUp, Critical: true}.Ready())
+	assert.False(t, Component{Status: ComponentDown, Critical: true}.Ready())
+	// a not critical component does not make the service unready
+	assert.True(t, Component{Status: ComponentDown}.Ready())
+	assert.True(t, Component{Status: ComponentDegraded, Critical: true}.Ready())
+}
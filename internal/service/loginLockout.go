@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+)
+
+// loginAttempt is a key of counted login attempts with its lockout policy
+// and the lock the attempt has set (zero - none).
+type loginAttempt struct {
+	key         string
+	policy      lockout.Policy
+	lockedUntil time.Time
+}
+
+func (s *Service) loginAttempts(login user.Login, clientIP string) []loginAttempt {
+	attempts := []loginAttempt{{key: lockout.LoginKey(login), policy: s.cfg.LoginLockoutPolicy()}}
+	if clientIP != "" {
+		attempts = append(attempts, loginAttempt{key: lockout.IPKey(clientIP), policy: s.cfg.LoginIPLockoutPolicy()})
+	}
+	return attempts
+}
+
+// beginLogin counts the attempt as failed for all keys before the password is verified
+// and locks out the keys with too many failures. The failures are counted conditionally in one transaction
+// with the locks, so concurrent attempts cannot all pass the lockout before any lock is written.
+// It returns a *LoginLockedError if any of the keys is locked out now, the longest lockout is reported,
+// and the attempt is not counted then.
+func (s *Service) beginLogin(ctx context.Context, attempts []loginAttempt) error {
+	now := time.Now().UTC()
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var retryAfter time.Duration
+	for i, attempt := range attempts {
+		a, err := tx.LoginAttempt().AddFailure(ctx, attempt.key, now, now.Add(-lockout.FailuresTTL))
+		if err != nil {
+			if !errors.Is(err, storage.ErrRecordConflict) {
+				return err
+			}
+
+			// the key is locked out, the failure is not counted
+			a, err = tx.LoginAttempt().Get(ctx, attempt.key)
+			if err != nil {
+				return err
+			}
+			if d := a.RetryAfter(now); d > retryAfter {
+				retryAfter = d
+			}
+			continue
+		}
+
+		if d := attempt.policy.Lockout(a.Failures); d > 0 {
+			attempts[i].lockedUntil = now.Add(d)
+			if err := tx.LoginAttempt().Lock(ctx, attempt.key, attempts[i].lockedUntil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return tx.Commit(ctx)
+}
+
+// succeedLogin forgets the failures of the login and takes back the attempt counted by beginLogin for other keys:
+// failures from the IP are kept, a successful login to one account does not clear guessing of others.
+func (s *Service) succeedLogin(ctx context.Context, login user.Login, attempts []loginAttempt) error {
+	for _, attempt := range attempts {
+		if attempt.key == lockout.LoginKey(login) {
+			if err := s.storages.LoginAttempt().Delete(ctx, attempt.key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.storages.LoginAttempt().RemoveFailure(ctx, attempt.key, attempt.lockedUntil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -10,6 +10,9 @@ import (
 )
 
 func (s *Service) CreateOrder(ctx context.Context, login user.Login, orderNumber order.Number) (*order.Order, bool, error) {
+	ctx, span := tracer.Start(ctx, "Service.CreateOrder")
+	defer span.End()
+
 	o, err := order.New(orderNumber, login)
 	if err != nil {
 		if errors.Is(err, order.ErrInvalidNumber) {
@@ -18,8 +21,30 @@ func (s *Service) CreateOrder(ctx context.Context, login user.Login, orderNumber
 		return nil, false, err
 	}
 
-	err = s.storages.Order().Create(ctx, *o)
+	j, err := s.newJob(ctx, orderNumber)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the order and its processing job are created together,
+	// so the order is processed even if the service is restarted
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		if tx != nil {
+			tx.Rollback(ctx)
+		}
+	}()
+
+	err = tx.Order().Create(ctx, *o)
 	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, false, err
+		}
+		tx = nil
+
 		if errors.Is(err, storage.ErrRecordAlreadyExists) {
 			existedOrder, err := s.storages.Order().Get(ctx, orderNumber)
 			if err != nil {
@@ -33,12 +58,25 @@ func (s *Service) CreateOrder(ctx context.Context, login user.Login, orderNumber
 		return nil, false, err
 	}
 
-	go s.processOrder(context.Background(), *o)
+	err = tx.Job().Create(ctx, *j)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.notifyJobsCreated()
 
 	return o, false, nil
 }
 
 func (s *Service) ListUserOrders(ctx context.Context, login user.Login) ([]order.Order, error) {
+	ctx, span := tracer.Start(ctx, "Service.ListUserOrders")
+	defer span.End()
+
 	ws, err := s.storages.Order().GetByUser(ctx, login)
 	if err != nil {
 		return nil, err
@@ -46,3 +84,29 @@ func (s *Service) ListUserOrders(ctx context.Context, login user.Login) ([]order
 
 	return ws, nil
 }
+
+// ListUserOrdersPage returns a page of user orders matching the filter
+// and the cursor of the next page (nil if the page is the last one).
+func (s *Service) ListUserOrdersPage(ctx context.Context, login user.Login, filter storage.OrderFilter) ([]order.Order, *storage.Cursor, error) {
+	ctx, span := tracer.Start(ctx, "Service.ListUserOrdersPage")
+	defer span.End()
+
+	limit := filter.Limit
+	if limit > 0 {
+		// one extra order tells whether the next page exists
+		filter.Limit++
+	}
+
+	orders, err := s.storages.Order().ListByUser(ctx, login, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if limit <= 0 || len(orders) <= limit {
+		return orders, nil, nil
+	}
+	orders = orders[:limit]
+	last := orders[limit-1]
+
+	return orders, &storage.Cursor{Time: last.UploadedAt, Number: last.Number}, nil
+}
@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"github.com/pioz/faker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -126,3 +128,96 @@ func TestService_ListUserOrders(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 0, len(orders))
 }
+
+func TestService_ListUserOrdersPage(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err := service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	// five orders, two of them are uploaded at the same time
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	uploadedAt := []time.Time{start, start.Add(time.Hour), start.Add(time.Hour), start.Add(2 * time.Hour), start.Add(3 * time.Hour)}
+	statuses := []order.Status{order.StatusNew, order.StatusProcessed, order.StatusInvalid, order.StatusProcessed, order.StatusProcessing}
+	expected := make([]order.Order, 0, len(uploadedAt))
+	for i := range uploadedAt {
+		o, err := order.New(generateOrderNumber(t), login)
+		require.NoError(t, err)
+		o.UploadedAt = uploadedAt[i]
+		o.Status = statuses[i]
+		require.NoError(t, service.storages.Order().Create(ctx, *o))
+		expected = append(expected, *o)
+	}
+	sort.SliceStable(expected, func(i, j int) bool {
+		if expected[i].UploadedAt.Equal(expected[j].UploadedAt) {
+			return expected[i].Number < expected[j].Number
+		}
+		return expected[i].UploadedAt.Before(expected[j].UploadedAt)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var (
+			listed []order.Order
+			after  *storage.Cursor
+		)
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, len(expected), "too many pages")
+			page, next, err := service.ListUserOrdersPage(ctx, login, storage.OrderFilter{After: after, Limit: 2})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page), 2)
+			listed = append(listed, page...)
+			if next == nil {
+				break
+			}
+			after = next
+		}
+
+		require.Equal(t, len(expected), len(listed))
+		for i := range expected {
+			assert.Equal(t, expected[i].Number, listed[i].Number)
+		}
+	})
+
+	t.Run("last page without next cursor", func(t *testing.T) {
+		page, next, err := service.ListUserOrdersPage(ctx, login, storage.OrderFilter{Limit: len(expected)})
+		require.NoError(t, err)
+		assert.Equal(t, len(expected), len(page))
+		assert.Nil(t, next)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		page, _, err := service.ListUserOrdersPage(ctx, login, storage.OrderFilter{
+			Statuses: []order.Status{order.StatusProcessed},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, len(page))
+		for _, o := range page {
+			assert.Equal(t, order.StatusProcessed, o.Status)
+		}
+
+		page, _, err = service.ListUserOrdersPage(ctx, login, storage.OrderFilter{
+			From: start.Add(time.Hour),
+			To:   start.Add(3 * time.Hour),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, len(page))
+		for _, o := range page {
+			assert.False(t, o.UploadedAt.Before(start.Add(time.Hour)))
+			assert.True(t, o.UploadedAt.Before(start.Add(3*time.Hour)))
+		}
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		page, next, err := service.ListUserOrdersPage(ctx, user.Login(faker.Username()), storage.OrderFilter{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(page))
+		assert.Nil(t, next)
+	})
+}
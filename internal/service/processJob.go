@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/metrics"
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/tracing"
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	processJobWorkersCount  = 10
+	processJobsPollInterval = 5 * time.Second
+	// retry interval of an order still being processed by the accrual service
+	processJobRetryInterval = time.Minute
+	// the retry interval grows exponentially while the accrual service does not respond
+	// (or is considered down) or does not know the order, but never exceeds the max one
+	processJobMaxRetryInterval = 6 * time.Hour
+	// the job is not claimed again until its lease expires, even if the processing was interrupted
+	processJobLeaseDuration = 2 * processMaxWaitingDuration
+)
+
+// newJob creates a job of the order processing, that keeps the trace context and the request ID of the caller,
+// so the background processing of the order is linked to the trace and the logs of the request it is created by.
+func (s *Service) newJob(ctx context.Context, number order.Number) (*job.Job, error) {
+	j, err := job.New(number)
+	if err != nil {
+		return nil, err
+	}
+	j.TraceParent = tracing.TraceParent(ctx)
+	j.RequestID = requestid.FromContext(ctx)
+	return j, nil
+}
+
+// notifyJobsCreated wakes up the jobs dispatcher without waiting for the next poll.
+func (s *Service) notifyJobsCreated() {
+	select {
+	case s.jobsNotify <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchJobs claims due jobs from the storage and sends them to the workers until the context is canceled.
+func (s *Service) dispatchJobs(ctx context.Context, jobs chan<- job.Job) {
+	ticker := time.NewTicker(processJobsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.updateOrderBacklog(ctx)
+
+		claimed, err := s.storages.Job().ClaimUnprocessed(ctx, s.workerID, processJobWorkersCount, processJobLeaseDuration)
+		if err != nil && ctx.Err() == nil {
+			s.log(ctx).Error("Dispatch jobs: job storage: claim jobs error", zap.Error(err))
+		}
+
+		for _, j := range claimed {
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				// not dispatched jobs will be claimed again after the lease expires
+				return
+			}
+		}
+
+		// there may be more due jobs: claim them without waiting
+		if len(claimed) == processJobWorkersCount {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.jobsNotify:
+		}
+	}
+}
+
+// updateOrderBacklog sets the number of orders waiting for processing to the metrics.
+func (s *Service) updateOrderBacklog(ctx context.Context) {
+	count, err := s.storages.Job().Count(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log(ctx).Warn("Dispatch jobs: job storage: count jobs error", zap.Error(err))
+		}
+		return
+	}
+	metrics.OrderBacklog.Set(float64(count))
+}
+
+// processJob processes the job order and removes the job if the order got a final status,
+// otherwise the job is postponed.
+// The job is not bound to the service lifetime context, so the worker can finish it on shutdown.
+// Its span starts a new trace linked to the trace of the request the job is created by.
+func (s *Service) processJob(j job.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), processJobLeaseDuration)
+	defer cancel()
+
+	opts := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.Int64("order.number", int64(j.OrderNumber)),
+		attribute.Int("job.attempts", j.Attempts),
+	)}
+	if link, ok := tracing.LinkTo(j.TraceParent); ok {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	ctx, span := tracer.Start(ctx, "Service.processJob", opts...)
+	defer span.End()
+
+	if j.RequestID != "" {
+		ctx = requestid.NewContext(ctx, j.RequestID)
+		ctx = logctx.NewContext(ctx, s.logger.With(zap.String("request id", j.RequestID)))
+	}
+
+	o, err := s.storages.Order().Get(ctx, j.OrderNumber)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			s.deleteJob(ctx, j)
+			return
+		}
+		s.log(ctx).Error("Process job: order storage: get order error", zap.Error(err))
+		s.retryJob(ctx, j, err)
+		return
+	}
+
+	if o.Status.Final() {
+		s.deleteJob(ctx, j)
+		return
+	}
+
+	start := time.Now()
+	done, err := s.processOrder(ctx, *o)
+	metrics.OrderProcessingDuration.Observe(time.Since(start).Seconds())
+	if done {
+		s.deleteJob(ctx, j)
+		return
+	}
+
+	if errors.Is(err, processor.ErrOrderNotRegistered) {
+		// the current attempt is not recorded yet
+		failed := j
+		failed.Attempts++
+		if reason, ok := s.deadLetterReason(failed); ok {
+			s.deadLetterJob(ctx, failed, reason)
+			return
+		}
+	}
+	if err == nil {
+		// the accrual service has responded, but the order is still being processed
+		s.postponeJob(ctx, j)
+		return
+	}
+	s.retryJob(ctx, j, err)
+}
+
+// deadLetterReason checks the job of the order not registered in accrual system
+// against the dead letter policy and returns the reason to dead-letter it.
+func (s *Service) deadLetterReason(j job.Job) (string, bool) {
+	if maxAttempts := s.cfg.DeadLetterMaxAttempts(); maxAttempts > 0 && j.Attempts >= maxAttempts {
+		return fmt.Sprintf("order not registered in accrual system after %d attempts", j.Attempts), true
+	}
+	if maxAge := s.cfg.DeadLetterMaxAge(); maxAge > 0 && time.Since(j.CreatedAt) >= maxAge {
+		return fmt.Sprintf("order not registered in accrual system for %s", maxAge), true
+	}
+	return "", false
+}
+
+// deadLetterJob moves the job to dead letters, so the order is not processed anymore until it is re-queued.
+func (s *Service) deadLetterJob(ctx context.Context, j job.Job, reason string) {
+	s.log(ctx).Warn("Process job: order is dead-lettered", zap.Int64("order number", int64(j.OrderNumber)), zap.String("reason", reason))
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		s.log(ctx).Error("Process job: storages: begin transaction error", zap.Error(err))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.DeadLetter().Create(ctx, *job.NewDeadLetter(j, reason))
+	if err != nil {
+		s.log(ctx).Error("Process job: dead letter storage: create dead letter error", zap.Error(err))
+		return
+	}
+	err = tx.Job().Delete(ctx, j.OrderNumber, s.workerID)
+	if err != nil {
+		s.log(ctx).Error("Process job: job storage: delete job error", zap.Error(err))
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		s.log(ctx).Error("Process job: storages: commit transaction error", zap.Error(err))
+		return
+	}
+}
+
+func (s *Service) deleteJob(ctx context.Context, j job.Job) {
+	err := s.storages.Job().Delete(ctx, j.OrderNumber, s.workerID)
+	if errors.Is(err, storage.ErrNoRecordAffected) {
+		s.log(ctx).Warn("Process job: job lease is lost, the job is left to its new owner", zap.Int64("order number", int64(j.OrderNumber)))
+		return
+	}
+	if err != nil {
+		s.log(ctx).Error("Process job: job storage: delete job error", zap.Int64("order number", int64(j.OrderNumber)), zap.Error(err))
+	}
+}
+
+func (s *Service) retryJob(ctx context.Context, j job.Job, cause error) {
+	err := s.storages.Job().Retry(ctx, j.OrderNumber, s.workerID, time.Now().UTC().Add(retryInterval(j.Attempts, cause)), cause.Error())
+	if errors.Is(err, storage.ErrNoRecordAffected) {
+		s.log(ctx).Warn("Process job: job lease is lost, the job is left to its new owner", zap.Int64("order number", int64(j.OrderNumber)))
+		return
+	}
+	if err != nil {
+		s.log(ctx).Error("Process job: job storage: retry job error", zap.Int64("order number", int64(j.OrderNumber)), zap.Error(err))
+	}
+}
+
+func (s *Service) postponeJob(ctx context.Context, j job.Job) {
+	err := s.storages.Job().Postpone(ctx, j.OrderNumber, s.workerID, time.Now().UTC().Add(processJobRetryInterval))
+	if errors.Is(err, storage.ErrNoRecordAffected) {
+		s.log(ctx).Warn("Process job: job lease is lost, the job is left to its new owner", zap.Int64("order number", int64(j.OrderNumber)))
+		return
+	}
+	if err != nil {
+		s.log(ctx).Error("Process job: job storage: postpone job error", zap.Int64("order number", int64(j.OrderNumber)), zap.Error(err))
+	}
+}
+
+// retryInterval returns the duration until the next attempt to process the job
+// that has already failed the attempts number of times.
+func retryInterval(attempts int, cause error) time.Duration {
+	if !errors.Is(cause, processor.ErrServerNotRespond) &&
+		!errors.Is(cause, processor.ErrCircuitOpen) &&
+		!errors.Is(cause, processor.ErrOrderNotRegistered) {
+		return processJobRetryInterval
+	}
+
+	mult := math.Pow(2, float64(attempts)) * float64(processJobRetryInterval)
+	interval := time.Duration(mult)
+	if float64(interval) != mult || interval > processJobMaxRetryInterval {
+		interval = processJobMaxRetryInterval
+	}
+	return interval
+}
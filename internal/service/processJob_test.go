@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor"
+	pmock "github.com/Karzoug/loyalty_program/internal/repository/processor/mock"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	smock "github.com/Karzoug/loyalty_program/internal/repository/storage/mock"
+	"github.com/pioz/faker"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// blockingProcessor is an order processor that returns processed order only after it is released.
+type blockingProcessor struct {
+	accrual  decimal.Decimal
+	started  chan struct{}
+	released chan struct{}
+}
+
+func (p *blockingProcessor) Process(_ context.Context, o order.Order) (*order.Order, error) {
+	p.started <- struct{}{}
+	<-p.released
+
+	o.Status = order.StatusProcessed
+	o.Accrual = p.accrual
+	return &o, nil
+}
+
+func TestService_Run(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := &blockingProcessor{
+		accrual:  decimal.NewFromFloat(120),
+		started:  make(chan struct{}),
+		released: make(chan struct{}),
+	}
+
+	service := New(mockConfig{}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	runCtx, stopRun := context.WithCancel(ctx)
+	runErr := make(chan error)
+	go func() {
+		runErr <- service.Run(runCtx)
+	}()
+
+	o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+	require.NoError(t, err)
+
+	// the job is picked up by a worker without waiting for the next poll
+	select {
+	case <-proc.started:
+	case <-ctx.Done():
+		t.Fatal("order processing not started")
+	}
+
+	// shutdown while the job is in progress
+	stopRun()
+	select {
+	case <-runErr:
+		t.Fatal("service stopped before the job in progress finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(proc.released)
+
+	select {
+	case err := <-runErr:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("service not stopped")
+	}
+
+	storageOrder, err := service.storages.Order().Get(ctx, o.Number)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusProcessed, storageOrder.Status)
+
+	_, err = service.storages.Job().Get(ctx, o.Number)
+	assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+}
+
+func TestService_processJob(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+
+	service := New(mockConfig{}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	t.Run("no result received: job postponed", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+
+		proc.SetResult(nil, processor.ErrServerNotRespond)
+		service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+
+		j, err = service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, 1, j.Attempts)
+		assert.Equal(t, processor.ErrServerNotRespond.Error(), j.LastError)
+		assert.True(t, j.NextCheckAt.After(time.Now()))
+	})
+
+	t.Run("order not registered: retry interval grows", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+
+		proc.SetResult(nil, processor.ErrOrderNotRegistered)
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+		j, err = service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		firstInterval := time.Until(j.NextCheckAt)
+
+		service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+		j, err = service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		secondInterval := time.Until(j.NextCheckAt)
+
+		assert.Equal(t, 2, j.Attempts)
+		assert.Equal(t, processor.ErrOrderNotRegistered.Error(), j.LastError)
+		assert.Greater(t, secondInterval, firstInterval)
+	})
+
+	t.Run("order processed: job deleted", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+
+		procOrder := *o
+		procOrder.Status = order.StatusProcessed
+		procOrder.Accrual = decimal.NewFromFloat(120)
+		proc.SetResult(&procOrder, nil)
+		service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+
+		_, err = service.storages.Job().Get(ctx, o.Number)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+	})
+
+	t.Run("order still processing: job postponed", func(t *testing.T) {
+		o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+
+		j, err := service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+
+		proc.SetResult(nil, processor.ErrServerNotRespond)
+		service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+
+		// the order is healthy but slow: its polls are not failures, so they do not back off
+		procOrder := *o
+		procOrder.Status = order.StatusProcessing
+		proc.SetResult(&procOrder, nil)
+		for i := 0; i < 3; i++ {
+			service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+		}
+
+		j, err = service.storages.Job().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, 0, j.Attempts)
+		assert.Empty(t, j.LastError)
+		assert.WithinDuration(t, time.Now().Add(processJobRetryInterval), j.NextCheckAt, 10*time.Second)
+	})
+}
+
+// countingProcessor is an order processor that counts processing calls of each order.
+type countingProcessor struct {
+	mu    sync.Mutex
+	calls map[order.Number]int
+}
+
+func (p *countingProcessor) Process(_ context.Context, o order.Order) (*order.Order, error) {
+	p.mu.Lock()
+	p.calls[o.Number]++
+	p.mu.Unlock()
+
+	o.Status = order.StatusProcessed
+	o.Accrual = decimal.NewFromFloat(10)
+	return &o, nil
+}
+
+func TestService_Run_severalInstances(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := &countingProcessor{calls: make(map[order.Number]int)}
+
+	// service instances share the storages like replicas behind a balancer share the database
+	const instancesCount = 3
+	services := make([]*Service, 0, instancesCount)
+	for i := 0; i < instancesCount; i++ {
+		services = append(services, New(mockConfig{}, storages, proc, logger))
+	}
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = services[0].RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	const ordersCount = 3 * processJobWorkersCount
+	numbers := make([]order.Number, 0, ordersCount)
+	for i := 0; i < ordersCount; i++ {
+		o, _, err := services[i%instancesCount].CreateOrder(ctx, login, generateOrderNumber(t))
+		require.NoError(t, err)
+		numbers = append(numbers, o.Number)
+	}
+
+	runCtx, stopRun := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(instancesCount)
+	for _, s := range services {
+		s := s
+		go func() {
+			defer wg.Done()
+			s.Run(runCtx)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		for _, number := range numbers {
+			if _, err := storages.Job().Get(ctx, number); err == nil {
+				return false
+			}
+		}
+		return true
+	}, 4*time.Second, 50*time.Millisecond)
+
+	stopRun()
+	wg.Wait()
+
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	for _, number := range numbers {
+		assert.Equal(t, 1, proc.calls[number], "order %d processed not exactly once", number)
+	}
+
+	balance, err := services[0].GetUserBalance(ctx, login)
+	require.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromFloat(10*ordersCount)))
+}
+
+func TestService_claimUnprocessedJobs(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err := service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	o, _, err := service.CreateOrder(ctx, login, generateOrderNumber(t))
+	require.NoError(t, err)
+
+	const leaseTTL = 200 * time.Millisecond
+
+	jobs, err := service.storages.Job().ClaimUnprocessed(ctx, "first worker", 10, leaseTTL)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(jobs))
+	assert.Equal(t, o.Number, jobs[0].OrderNumber)
+
+	// the job is leased by the first worker
+	jobs, err = service.storages.Job().ClaimUnprocessed(ctx, "second worker", 10, leaseTTL)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(jobs))
+
+	// the first worker has gone away: the lease expires
+	time.Sleep(leaseTTL)
+	jobs, err = service.storages.Job().ClaimUnprocessed(ctx, "second worker", 10, leaseTTL)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(jobs))
+	assert.Equal(t, o.Number, jobs[0].OrderNumber)
+
+	// the first worker's lease has expired: it cannot finish the job claimed by the second worker
+	err = service.storages.Job().Retry(ctx, o.Number, "first worker", time.Now().UTC(), "")
+	assert.ErrorIs(t, err, storage.ErrNoRecordAffected)
+	err = service.storages.Job().Delete(ctx, o.Number, "first worker")
+	assert.ErrorIs(t, err, storage.ErrNoRecordAffected)
+	j, err := service.storages.Job().Get(ctx, o.Number)
+	require.NoError(t, err)
+	assert.Equal(t, 0, j.Attempts)
+
+	// the failed attempt releases the lease
+	err = service.storages.Job().Retry(ctx, o.Number, "second worker", time.Now().UTC(), "")
+	require.NoError(t, err)
+	jobs, err = service.storages.Job().ClaimUnprocessed(ctx, "first worker", 10, leaseTTL)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(jobs))
+
+	// only the orders due to be checked are claimed
+	err = service.storages.Job().Postpone(ctx, o.Number, "first worker", time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+	jobs, err = service.storages.Job().ClaimUnprocessed(ctx, "second worker", 10, leaseTTL)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(jobs))
+}
+
+func Test_retryInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		cause    error
+		want     time.Duration
+	}{
+		{
+			name:     "order is still processing",
+			attempts: 5,
+			cause:    nil,
+			want:     processJobRetryInterval,
+		},
+		{
+			name:     "server not respond: first attempt",
+			attempts: 0,
+			cause:    processor.ErrServerNotRespond,
+			want:     processJobRetryInterval,
+		},
+		{
+			name:     "server not respond: third attempt",
+			attempts: 2,
+			cause:    processor.ErrServerNotRespond,
+			want:     4 * processJobRetryInterval,
+		},
+		{
+			name:     "circuit breaker is open",
+			attempts: 1,
+			cause:    processor.ErrCircuitOpen,
+			want:     2 * processJobRetryInterval,
+		},
+		{
+			name:     "order not registered: wrapped error",
+			attempts: 3,
+			cause:    fmt.Errorf("process: %w", processor.ErrOrderNotRegistered),
+			want:     8 * processJobRetryInterval,
+		},
+		{
+			name:     "order not registered: max interval",
+			attempts: 100,
+			cause:    processor.ErrOrderNotRegistered,
+			want:     processJobMaxRetryInterval,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryInterval(tt.attempts, tt.cause))
+		})
+	}
+}
+
+func TestService_processJob_traceLink(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	_, err := service.RegisterUser(ctx, login, faker.StringWithSize(15))
+	require.NoError(t, err)
+
+	uploadCtx, uploadSpan := otel.Tracer("test").Start(ctx, "upload")
+	o, _, err := service.CreateOrder(uploadCtx, login, generateOrderNumber(t))
+	require.NoError(t, err)
+	uploadSpan.End()
+
+	j, err := service.storages.Job().Get(ctx, o.Number)
+	require.NoError(t, err)
+	assert.Contains(t, j.TraceParent, uploadSpan.SpanContext().TraceID().String())
+
+	service.processJob(leaseJob(ctx, t, service, j.OrderNumber))
+
+	var processSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "Service.processJob" {
+			processSpan = span
+		}
+	}
+	require.NotNil(t, processSpan, "no processing span recorded")
+	// the processing runs in its own trace linked to the upload one
+	assert.NotEqual(t, uploadSpan.SpanContext().TraceID(), processSpan.SpanContext().TraceID())
+	require.Len(t, processSpan.Links(), 1)
+	assert.Equal(t, uploadSpan.SpanContext().TraceID(), processSpan.Links()[0].SpanContext.TraceID())
+}
@@ -5,123 +5,182 @@ import (
 	"errors"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/processor"
-	"github.com/shopspring/decimal"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 const (
-	processUnprocessedOrdersGoroutineLimit = 10
-	processUnprocessedOrdersStorageLimit   = 100
-	processMaxWaitingDuration              = 90 * time.Second
+	processMaxWaitingDuration = 90 * time.Second
+)
+
+var (
+	errNegativeAccrual = errors.New("got order with negative accrual value")
 )
 
 // processOrder calls order processor to update status and accrual (if possible).
-func (s *Service) processOrder(ctx context.Context, o order.Order) {
+// It returns true if the order got a final status and needs no more processing.
+func (s *Service) processOrder(ctx context.Context, o order.Order) (bool, error) {
+	ctx, span := tracer.Start(ctx, "Service.processOrder")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, processMaxWaitingDuration)
 	defer cancel()
 
 	t1 := time.Now()
 	procOrder, err := s.orderProcessor.Process(ctx, o)
 
-	// order not found: delete (?)
+	// order not found: process later again
 	if errors.Is(err, processor.ErrOrderNotRegistered) {
-		s.logger.Warn("Process order: order not registered in accrual service", zap.Int64("order number", int64(o.Number)))
-		//s.logger.Warn("Process order: order not registered in accrual service and will be deleted", zap.Int64("order number", int64(o.Number)))
-		//err := s.storages.Order().Delete(ctx, o.Number)
-		// if err != nil {
-		// 	s.logger.Error("Process order: order storage: delete order error", zap.Error(err))
-		// }
-		return
+		s.log(ctx).Warn("Process order: order not registered in accrual service", zap.Int64("order number", int64(o.Number)))
+		return false, err
 	}
 
 	// no result received: process later again
 	if err != nil {
-		s.logger.Warn("Process order: no result received",
+		s.log(ctx).Warn("Process order: no result received",
 			zap.Int64("order number", int64(o.Number)),
-			zap.Duration("processing time", time.Since(t1)))
-		return
+			zap.Duration("processing time", time.Since(t1)),
+			zap.Error(err))
+		return false, err
 	}
 
 	// got the same result as before: process later again
 	if o.Status == procOrder.Status {
-		s.logger.Debug("Process order: no new result received, status not changed",
+		s.log(ctx).Debug("Process order: no new result received, status not changed",
 			zap.Int64("order number", int64(o.Number)),
 			zap.Duration("processing time", time.Since(t1)))
-		return
+		return false, nil
 	}
 
 	// order status not 'processed': update only status
 	if procOrder.Status != order.StatusProcessed {
-		err := s.storages.Order().Update(ctx, *procOrder)
-		if err != nil {
-			s.logger.Error("Process order: order storage: update order status error", zap.Error(err))
-		}
-		return
+		return s.updateOrderStatus(ctx, *procOrder, o.Status)
 	}
 
-	if procOrder.Accrual.LessThanOrEqual(decimal.Zero) {
-		s.logger.Error("Process order: got order with negative accrual value", zap.Float64("accrual", procOrder.Accrual.InexactFloat64()))
-		return
+	accrual, err := user.NewAccrual(procOrder.Accrual, s.cfg.MoneyPolicy())
+	if err != nil {
+		s.log(ctx).Error("Process order: got order with negative accrual value", zap.Stringer("accrual", procOrder.Accrual))
+		return false, errNegativeAccrual
+	}
+	procOrder.Accrual = accrual
+
+	// the order is processed without accrual or the accrual rounds to zero: nothing is credited to the user balance
+	if procOrder.Accrual.IsZero() {
+		return s.updateOrderStatus(ctx, *procOrder, o.Status)
 	}
 
 	// order status 'processed': update order and user balance inside transaction
 	tx, err := s.storages.BeginTx(ctx)
 	if err != nil {
-		s.logger.Error("Process order: storages: begin transaction error", zap.Error(err))
-		return
+		s.log(ctx).Error("Process order: storages: begin transaction error", zap.Error(err))
+		return false, err
 	}
 	defer tx.Rollback(ctx)
 
-	err = tx.Order().Update(ctx, *procOrder)
+	// the status transition is conditional, so only one process credits the accrual
+	err = tx.Order().CompareAndUpdate(ctx, *procOrder, o.Status)
 	if err != nil {
-		s.logger.Error("Process order: order storage: update order error", zap.Error(err))
-		return
+		if errors.Is(err, storage.ErrRecordConflict) {
+			s.log(ctx).Debug("Process order: order status already changed by another process", zap.Int64("order number", int64(o.Number)))
+			// the order is read outside the transaction
+			tx.Rollback(ctx)
+			return s.isOrderFinal(ctx, o.Number)
+		}
+		s.log(ctx).Error("Process order: order storage: update order error", zap.Error(err))
+		return false, err
+	}
+	// the user row is locked, so the user is not unblocked before the held accrual is committed:
+	// UnblockUser takes the same lock and credits all holds committed before it
+	u, err := tx.User().GetForUpdate(ctx, procOrder.UserLogin)
+	if err != nil {
+		s.log(ctx).Error("Process order: user storage: get user error", zap.Error(err))
+		return false, err
+	}
+	if u.Blocked() {
+		return s.holdAccrual(ctx, tx, *procOrder)
 	}
 	_, err = tx.User().UpdateBalance(ctx, procOrder.UserLogin, procOrder.Accrual)
 	if err != nil {
-		s.logger.Error("Process order: user storage: update user balance error", zap.Error(err))
-		return
+		s.log(ctx).Error("Process order: user storage: update user balance error", zap.Error(err))
+		return false, err
+	}
+	entry, err := ledger.New(procOrder.UserLogin, procOrder.Number, ledger.OperationAccrual, procOrder.Accrual)
+	if err != nil {
+		s.log(ctx).Error("Process order: create ledger entry error", zap.Error(err))
+		return false, err
+	}
+	err = tx.Ledger().Create(ctx, *entry)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordAlreadyExists) {
+			s.log(ctx).Debug("Process order: accrual already credited", zap.Int64("order number", int64(o.Number)))
+			return false, nil
+		}
+		s.log(ctx).Error("Process order: ledger storage: create ledger entry error", zap.Error(err))
+		return false, err
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
-		s.logger.Error("Process order: storages: commit transaction error", zap.Error(err))
-		return
+		s.log(ctx).Error("Process order: storages: commit transaction error", zap.Error(err))
+		return false, err
 	}
+
+	return true, nil
 }
 
-// processUnprocessedOrders searches for unprocessed orders in the storage and
-// calls order processor to update status and accrual (if possible).
-func (s *Service) processUnprocessedOrders(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
+// updateOrderStatus updates the order with no balance change, if it still has the previous status.
+// It returns true if the order got a final status.
+func (s *Service) updateOrderStatus(ctx context.Context, o order.Order, prevStatus order.Status) (bool, error) {
+	err := s.storages.Order().CompareAndUpdate(ctx, o, prevStatus)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordConflict) {
+			s.log(ctx).Debug("Process order: order status already changed by another process", zap.Int64("order number", int64(o.Number)))
+			return s.isOrderFinal(ctx, o.Number)
 		}
+		s.log(ctx).Error("Process order: order storage: update order status error", zap.Error(err))
+		return false, err
+	}
+	return o.Status.Final(), nil
+}
 
-		orders, err := s.storages.Order().
-			ListUnprocessed(ctx, processUnprocessedOrdersStorageLimit, 0, time.Now().UTC().Add(-processMaxWaitingDuration))
-		if err != nil {
-			s.logger.Error("Process unprocessed orders: order storage error", zap.Error(err))
-			return
-		}
-		if len(orders) == 0 {
-			return
-		}
+// isOrderFinal reloads the order, whose status has been changed by another process,
+// and reports whether it has got a final status.
+func (s *Service) isOrderFinal(ctx context.Context, number order.Number) (bool, error) {
+	o, err := s.storages.Order().Get(ctx, number)
+	if err != nil {
+		s.log(ctx).Error("Process order: order storage: get order error", zap.Error(err))
+		return false, err
+	}
+	return o.Status.Final(), nil
+}
 
-		g, _ := errgroup.WithContext(ctx)
-		g.SetLimit(processUnprocessedOrdersGoroutineLimit)
-		for _, o := range orders {
-			o := o
-			g.Go(func() error {
-				s.processOrder(ctx, o)
-				return nil
-			})
-		}
-		g.Wait()
+// holdAccrual keeps the accrual of the processed order of the blocked user instead of crediting it,
+// the transaction is committed.
+func (s *Service) holdAccrual(ctx context.Context, tx storage.Transaction, o order.Order) (bool, error) {
+	err := tx.AccrualHold().Create(ctx, ledger.Hold{
+		OrderNumber: o.Number,
+		UserLogin:   o.UserLogin,
+		Amount:      o.Accrual,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		s.log(ctx).Error("Process order: accrual hold storage: create hold error", zap.Error(err))
+		return false, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		s.log(ctx).Error("Process order: storages: commit transaction error", zap.Error(err))
+		return false, err
 	}
+
+	s.log(ctx).Info("Process order: accrual held, user is blocked",
+		zap.Int64("order number", int64(o.Number)),
+		zap.String("login", string(o.UserLogin)))
+
+	return true, nil
 }
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/processor"
@@ -31,7 +33,7 @@ func TestService_processOrder(t *testing.T) {
 
 	proc := pmock.NewOrder()
 
-	service := New(storages, proc, logger)
+	service := New(mockConfig{}, storages, proc, logger)
 
 	login := user.Login(faker.Username())
 	password := faker.StringWithSize(15)
@@ -64,6 +66,17 @@ func TestService_processOrder(t *testing.T) {
 		u, err := service.storages.User().Get(ctx, login)
 		require.NoError(t, err)
 		assert.True(t, u.Balance.RoundBank(4).Equal(accrual.RoundBank(4)))
+
+		entries, err := service.ListUserLedgerEntries(ctx, login)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, ledger.OperationAccrual, entries[0].Operation)
+		assert.Equal(t, o.Number, entries[0].OrderNumber)
+		assert.True(t, entries[0].Amount.RoundBank(4).Equal(accrual.RoundBank(4)))
+
+		balance, err := service.GetUserBalance(ctx, login)
+		require.NoError(t, err)
+		assert.True(t, balance.RoundBank(4).Equal(accrual.RoundBank(4)))
 	})
 
 	t.Run("server not respond", func(t *testing.T) {
@@ -109,6 +122,77 @@ func TestService_processOrder(t *testing.T) {
 		assert.Equal(t, o.Status, storageOrder.Status)
 	})
 
+	t.Run("processed without accrual", func(t *testing.T) {
+		login2 := user.Login(faker.Username())
+		_, err = service.RegisterUser(ctx, login2, password)
+		require.NoError(t, err)
+
+		orderNumber := generateOrderNumber(t)
+
+		o, err := order.New(orderNumber, login2)
+		require.NoError(t, err)
+
+		err = service.storages.Order().Create(ctx, *o)
+		require.NoError(t, err)
+
+		procOrder := *o
+		procOrder.Status = order.StatusProcessed
+
+		proc.SetResult(&procOrder, nil)
+		done, err := service.processOrder(ctx, *o)
+		require.NoError(t, err)
+		assert.True(t, done)
+
+		storageOrder, err := service.storages.Order().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, order.StatusProcessed, storageOrder.Status)
+		assert.True(t, storageOrder.Accrual.IsZero())
+
+		balance, err := service.GetUserBalance(ctx, login2)
+		require.NoError(t, err)
+		assert.True(t, balance.IsZero())
+
+		entries, err := service.ListUserLedgerEntries(ctx, login2)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("processed with accrual rounded to zero", func(t *testing.T) {
+		login2 := user.Login(faker.Username())
+		_, err = service.RegisterUser(ctx, login2, password)
+		require.NoError(t, err)
+
+		orderNumber := generateOrderNumber(t)
+
+		o, err := order.New(orderNumber, login2)
+		require.NoError(t, err)
+
+		err = service.storages.Order().Create(ctx, *o)
+		require.NoError(t, err)
+
+		procOrder := *o
+		procOrder.Status = order.StatusProcessed
+		procOrder.Accrual = decimal.RequireFromString("0.001")
+
+		proc.SetResult(&procOrder, nil)
+		done, err := service.processOrder(ctx, *o)
+		require.NoError(t, err)
+		assert.True(t, done)
+
+		storageOrder, err := service.storages.Order().Get(ctx, o.Number)
+		require.NoError(t, err)
+		assert.Equal(t, order.StatusProcessed, storageOrder.Status)
+		assert.True(t, storageOrder.Accrual.IsZero())
+
+		balance, err := service.GetUserBalance(ctx, login2)
+		require.NoError(t, err)
+		assert.True(t, balance.IsZero())
+
+		entries, err := service.ListUserLedgerEntries(ctx, login2)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
 	t.Run("order status is invalid", func(t *testing.T) {
 		orderNumber := generateOrderNumber(t)
 
@@ -151,3 +235,69 @@ func TestService_processOrder(t *testing.T) {
 		assert.Equal(t, procOrder.Status, storageOrder.Status)
 	})
 }
+
+func TestService_processOrder_concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	config := zap.NewDevelopmentConfig()
+	logger, _ := config.Build()
+
+	storages, err := smock.NewStorages(ctx)
+	require.NoError(t, err)
+
+	proc := pmock.NewOrder()
+
+	service := New(mockConfig{}, storages, proc, logger)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err = service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	o, err := order.New(generateOrderNumber(t), login)
+	require.NoError(t, err)
+	err = service.storages.Order().Create(ctx, *o)
+	require.NoError(t, err)
+
+	accrual := decimal.NewFromFloat(120)
+	procOrder := *o
+	procOrder.Status = order.StatusProcessed
+	procOrder.Accrual = accrual
+	proc.SetResult(&procOrder, nil)
+
+	// the same order is picked up by several processes at the same moment
+	const processesCount = 10
+	var wg sync.WaitGroup
+	wg.Add(processesCount)
+	dones := make([]bool, processesCount)
+	errs := make([]error, processesCount)
+	for i := 0; i < processesCount; i++ {
+		i := i
+		go func() {
+			defer wg.Done()
+			dones[i], errs[i] = service.processOrder(ctx, *o)
+		}()
+	}
+	wg.Wait()
+
+	// the processes losing the race see the order finalized by the winner, so their jobs are done too
+	for i := 0; i < processesCount; i++ {
+		assert.NoError(t, errs[i])
+		assert.True(t, dones[i])
+	}
+
+	storageOrder, err := service.storages.Order().Get(ctx, o.Number)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusProcessed, storageOrder.Status)
+
+	u, err := service.storages.User().Get(ctx, login)
+	require.NoError(t, err)
+	assert.True(t, u.Balance.RoundBank(4).Equal(accrual.RoundBank(4)))
+
+	entries, err := service.ListUserLedgerEntries(ctx, login)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
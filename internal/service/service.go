@@ -2,46 +2,93 @@ package service
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
+	"github.com/Karzoug/loyalty_program/internal/model/money"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/processor"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/Karzoug/loyalty_program/internal/tracing"
+	"github.com/Karzoug/loyalty_program/pkg/logctx"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-const (
-	processUnprocessedOrdersInterval = 10 * time.Minute
-)
+// tracer traces the service methods, its spans are children of the request ones.
+var tracer = tracing.Tracer("github.com/Karzoug/loyalty_program/internal/service")
+
+type serviceConfig interface {
+	DeadLetterMaxAttempts() int
+	DeadLetterMaxAge() time.Duration
+	MoneyPolicy() money.Policy
+	RefreshTokenLifetime() time.Duration
+	LoginLockoutPolicy() lockout.Policy
+	LoginIPLockoutPolicy() lockout.Policy
+	PasswordPolicy() user.PasswordPolicy
+	AdminLogins() []string
+}
 
 type Service struct {
+	cfg            serviceConfig
 	storages       storage.TxStorages
 	orderProcessor processor.Order
 	logger         *zap.Logger
+
+	// workerID identifies the service instance that leases order processing jobs
+	workerID string
+	// jobsNotify wakes up the jobs dispatcher when a new job is created
+	jobsNotify chan struct{}
+	// running reports whether the order processing workers are running
+	running atomic.Bool
 }
 
-func New(storages storage.TxStorages, proc processor.Order, logger *zap.Logger) *Service {
+func New(cfg serviceConfig, storages storage.TxStorages, proc processor.Order, logger *zap.Logger) *Service {
 	return &Service{
+		cfg:            cfg,
 		storages:       storages,
 		orderProcessor: proc,
 		logger:         logger,
+
+		workerID:   uuid.NewString(),
+		jobsNotify: make(chan struct{}, 1),
 	}
 }
 
+// log returns the logger of the context, that writes the request ID the work is caused by.
+func (s *Service) log(ctx context.Context) *zap.Logger {
+	return logctx.FromContext(ctx, s.logger)
+}
+
+// Run runs order processing workers until the context is canceled.
+// After that it waits for the workers to finish the jobs in progress.
 func (s *Service) Run(ctx context.Context) error {
-	ticker := time.NewTicker(processUnprocessedOrdersInterval)
-
-	for {
-		select {
-		case <-ticker.C:
-			go func() {
-				ctx, cancel := context.WithTimeout(ctx, processUnprocessedOrdersInterval)
-				defer cancel()
-
-				s.processUnprocessedOrders(ctx)
-			}()
-		case <-ctx.Done():
-			ticker.Stop()
-			return nil
-		}
+	s.logger.Info("Running order processing workers", zap.String("worker id", s.workerID))
+
+	s.running.Store(true)
+	defer s.running.Store(false)
+
+	jobs := make(chan job.Job)
+
+	var wg sync.WaitGroup
+	wg.Add(processJobWorkersCount)
+	for i := 0; i < processJobWorkersCount; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				s.processJob(j)
+			}
+		}()
 	}
+
+	s.dispatchJobs(ctx, jobs)
+
+	s.logger.Info("Waiting for order processing jobs in progress to finish")
+	close(jobs)
+	wg.Wait()
+
+	return nil
 }
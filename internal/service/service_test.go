@@ -8,14 +8,20 @@ import (
 	"math"
 	mathrand "math/rand"
 	"testing"
+	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/job"
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
+	"github.com/Karzoug/loyalty_program/internal/model/money"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/processor"
 	pmock "github.com/Karzoug/loyalty_program/internal/repository/processor/mock"
 	smock "github.com/Karzoug/loyalty_program/internal/repository/storage/mock"
 	"github.com/Karzoug/loyalty_program/pkg/luhn"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var rnd = func() *mathrand.Rand {
@@ -28,6 +34,48 @@ var rnd = func() *mathrand.Rand {
 	return mathrand.New(src)
 }()
 
+type mockConfig struct {
+	deadLetterMaxAttempts int
+	deadLetterMaxAge      time.Duration
+	passwordPolicy        *user.PasswordPolicy
+	adminLogins           []string
+}
+
+func (c mockConfig) DeadLetterMaxAttempts() int {
+	return c.deadLetterMaxAttempts
+}
+
+func (c mockConfig) DeadLetterMaxAge() time.Duration {
+	return c.deadLetterMaxAge
+}
+
+func (c mockConfig) MoneyPolicy() money.Policy {
+	return money.DefaultPolicy
+}
+
+func (c mockConfig) RefreshTokenLifetime() time.Duration {
+	return time.Hour
+}
+
+func (c mockConfig) LoginLockoutPolicy() lockout.Policy {
+	return lockout.Policy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+}
+
+func (c mockConfig) LoginIPLockoutPolicy() lockout.Policy {
+	return lockout.Policy{MaxAttempts: 10, BaseDelay: time.Minute, MaxDelay: time.Hour}
+}
+
+func (c mockConfig) AdminLogins() []string {
+	return c.adminLogins
+}
+
+func (c mockConfig) PasswordPolicy() user.PasswordPolicy {
+	if c.passwordPolicy != nil {
+		return *c.passwordPolicy
+	}
+	return user.PasswordPolicy{Algorithm: user.HashBcrypt, BcryptCost: bcrypt.MinCost}
+}
+
 func newMockServiceWithEmptyProcessor(ctx context.Context, t *testing.T) *Service {
 	t.Helper()
 
@@ -40,7 +88,22 @@ func newMockServiceWithEmptyProcessor(ctx context.Context, t *testing.T) *Servic
 	proc := pmock.NewOrder()
 	proc.SetResult(nil, processor.ErrServerNotRespond)
 
-	return New(storages, proc, logger)
+	return New(mockConfig{}, storages, proc, logger)
+}
+
+// leaseJob leases the job of the order to the service worker and returns it, as if the worker claimed it.
+func leaseJob(ctx context.Context, t *testing.T, s *Service, number order.Number) job.Job {
+	t.Helper()
+
+	leaser, ok := s.storages.Job().(interface {
+		Lease(context.Context, order.Number, string, time.Duration) error
+	})
+	require.True(t, ok, "job storage cannot lease jobs")
+	require.NoError(t, leaser.Lease(ctx, number, s.workerID, processJobLeaseDuration))
+
+	j, err := s.storages.Job().Get(ctx, number)
+	require.NoError(t, err)
+	return *j
 }
 
 func generateOrderNumber(t *testing.T) order.Number {
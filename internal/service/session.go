@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/session"
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"go.uber.org/zap"
+)
+
+// CreateSession starts a new user session and returns it with its refresh token.
+func (s *Service) CreateSession(ctx context.Context, login user.Login) (*session.Session, string, error) {
+	ctx, span := tracer.Start(ctx, "Service.CreateSession")
+	defer span.End()
+
+	ss, refreshToken, err := session.New(login, s.cfg.RefreshTokenLifetime())
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.storages.Session().Create(ctx, *ss)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordAlreadyExists) {
+			return nil, "", ErrInvalidAuthData
+		}
+		return nil, "", err
+	}
+
+	return ss, refreshToken, nil
+}
+
+// RefreshSession replaces the refresh token of the session with a new one.
+// A reuse of a replaced refresh token means that it was stolen, so the whole session is revoked.
+func (s *Service) RefreshSession(ctx context.Context, refreshToken string) (*session.Session, string, error) {
+	ctx, span := tracer.Start(ctx, "Service.RefreshSession")
+	defer span.End()
+
+	tokenHash := session.HashToken(refreshToken)
+	ss, err := s.storages.Session().GetByRefreshToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, "", ErrInvalidAuthData
+		}
+		return nil, "", err
+	}
+
+	if ss.RefreshTokenHash != tokenHash {
+		s.log(ctx).Warn("Refresh session: reuse of a rotated refresh token, the session is revoked",
+			zap.String("login", string(ss.UserLogin)),
+			zap.String("session id", ss.ID))
+		err := s.storages.Session().Revoke(ctx, ss.ID, time.Now().UTC())
+		if err != nil && !errors.Is(err, storage.ErrNoRecordAffected) {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidAuthData
+	}
+	if !ss.Active(time.Now().UTC()) {
+		return nil, "", ErrInvalidAuthData
+	}
+
+	newRefreshToken, err := ss.Rotate(s.cfg.RefreshTokenLifetime())
+	if err != nil {
+		return nil, "", err
+	}
+	err = s.storages.Session().Rotate(ctx, *ss)
+	if err != nil {
+		// the session is refreshed or revoked concurrently
+		if errors.Is(err, storage.ErrRecordConflict) || errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, "", ErrInvalidAuthData
+		}
+		return nil, "", err
+	}
+
+	return ss, newRefreshToken, nil
+}
+
+// Logout revokes the user session and the access token used to log out.
+func (s *Service) Logout(ctx context.Context, login user.Login, sessionID, jti string, tokenExpiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "Service.Logout")
+	defer span.End()
+
+	ss, err := s.storages.Session().Get(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return ErrInvalidAuthData
+		}
+		return err
+	}
+	if ss.UserLogin != login {
+		return ErrInvalidAuthData
+	}
+
+	err = s.storages.Session().Revoke(ctx, sessionID, time.Now().UTC())
+	if err != nil && !errors.Is(err, storage.ErrNoRecordAffected) {
+		return err
+	}
+
+	return s.RevokeToken(ctx, jti, tokenExpiresAt)
+}
+
+// RevokeUserSessions logs out all sessions of the user,
+// access tokens issued for them are rejected as well.
+func (s *Service) RevokeUserSessions(ctx context.Context, login user.Login) error {
+	ctx, span := tracer.Start(ctx, "Service.RevokeUserSessions")
+	defer span.End()
+
+	return s.storages.Session().RevokeByUser(ctx, login, time.Now().UTC(), "")
+}
+
+// RevokeToken rejects the access token until it expires.
+func (s *Service) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "Service.RevokeToken")
+	defer span.End()
+
+	now := time.Now().UTC()
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	err := s.storages.RevokedToken().Create(ctx, jti, expiresAt.UTC())
+	if err != nil && !errors.Is(err, storage.ErrRecordAlreadyExists) {
+		return err
+	}
+
+	// expired tokens are rejected anyway, so they are not kept in the denylist
+	return s.storages.RevokedToken().DeleteExpired(ctx, now)
+}
+
+// IsTokenRevoked reports whether the access token is revoked by itself or by logout of its session.
+func (s *Service) IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "Service.IsTokenRevoked")
+	defer span.End()
+
+	return s.storages.RevokedToken().IsRevoked(ctx, jti, sessionID)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/google/uuid"
+	"github.com/pioz/faker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_RefreshSession(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	_, err := service.RegisterUser(ctx, login, faker.StringWithSize(15))
+	require.NoError(t, err)
+
+	t.Run("positive: rotation", func(t *testing.T) {
+		ss, refreshToken, err := service.CreateSession(ctx, login)
+		require.NoError(t, err)
+
+		refreshed, newRefreshToken, err := service.RefreshSession(ctx, refreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, ss.ID, refreshed.ID)
+		assert.Equal(t, login, refreshed.UserLogin)
+		assert.NotEqual(t, refreshToken, newRefreshToken)
+
+		_, _, err = service.RefreshSession(ctx, newRefreshToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("negative: unknown token", func(t *testing.T) {
+		_, _, err := service.RefreshSession(ctx, faker.StringWithSize(43))
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+	})
+
+	t.Run("negative: reuse of rotated token revokes the session", func(t *testing.T) {
+		ss, refreshToken, err := service.CreateSession(ctx, login)
+		require.NoError(t, err)
+		_, newRefreshToken, err := service.RefreshSession(ctx, refreshToken)
+		require.NoError(t, err)
+
+		_, _, err = service.RefreshSession(ctx, refreshToken)
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+
+		_, _, err = service.RefreshSession(ctx, newRefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+		revoked, err := service.IsTokenRevoked(ctx, uuid.NewString(), ss.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("negative: revoked session", func(t *testing.T) {
+		ss, refreshToken, err := service.CreateSession(ctx, login)
+		require.NoError(t, err)
+		require.NoError(t, service.Logout(ctx, login, ss.ID, uuid.NewString(), time.Now().Add(time.Minute)))
+
+		_, _, err = service.RefreshSession(ctx, refreshToken)
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+	})
+}
+
+func TestService_IsTokenRevoked(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	_, err := service.RegisterUser(ctx, login, faker.StringWithSize(15))
+	require.NoError(t, err)
+
+	ss1, _, err := service.CreateSession(ctx, login)
+	require.NoError(t, err)
+	ss2, _, err := service.CreateSession(ctx, login)
+	require.NoError(t, err)
+
+	jti1, jti2 := uuid.NewString(), uuid.NewString()
+	revoked, err := service.IsTokenRevoked(ctx, jti1, ss1.ID)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	t.Run("token", func(t *testing.T) {
+		require.NoError(t, service.RevokeToken(ctx, jti1, time.Now().Add(time.Minute)))
+		revoked, err := service.IsTokenRevoked(ctx, jti1, ss1.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		// another token of the same session is still valid
+		revoked, err = service.IsTokenRevoked(ctx, jti2, ss1.ID)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		revoked, err := service.IsTokenRevoked(ctx, jti2, uuid.NewString())
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("another user logout", func(t *testing.T) {
+		err := service.Logout(ctx, user.Login(faker.Username()), ss1.ID, jti2, time.Now().Add(time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+	})
+
+	t.Run("all user sessions", func(t *testing.T) {
+		require.NoError(t, service.RevokeUserSessions(ctx, login))
+		for _, id := range []string{ss1.ID, ss2.ID} {
+			revoked, err := service.IsTokenRevoked(ctx, jti2, id)
+			require.NoError(t, err)
+			assert.True(t, revoked)
+		}
+	})
+}
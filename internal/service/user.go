@@ -3,14 +3,20 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/repository/storage"
 	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 )
 
 func (s *Service) RegisterUser(ctx context.Context, login user.Login, password string) (*user.User, error) {
-	u, err := user.New(login, password)
+	ctx, span := tracer.Start(ctx, "Service.RegisterUser")
+	defer span.End()
+
+	u, err := user.New(login, password, s.cfg.PasswordPolicy())
 	if err != nil {
 		switch {
 		case errors.Is(err, user.ErrBadPassword):
@@ -21,7 +27,6 @@ func (s *Service) RegisterUser(ctx context.Context, login user.Login, password s
 			return nil, err
 		}
 	}
-
 	err = s.storages.User().Create(ctx, *u)
 	if err != nil {
 		if errors.Is(err, storage.ErrRecordAlreadyExists) {
@@ -32,7 +37,18 @@ func (s *Service) RegisterUser(ctx context.Context, login user.Login, password s
 	return u, nil
 }
 
-func (s *Service) LoginUser(ctx context.Context, login user.Login, password string) (*user.User, error) {
+// LoginUser checks the user password. Failed attempts are counted by the login and by the client IP (if it is not empty),
+// too many failures lock further attempts out for a while with a *LoginLockedError.
+func (s *Service) LoginUser(ctx context.Context, login user.Login, password, clientIP string) (*user.User, error) {
+	ctx, span := tracer.Start(ctx, "Service.LoginUser")
+	defer span.End()
+
+	// the attempt is counted as failed until the password is verified
+	attempts := s.loginAttempts(login, clientIP)
+	if err := s.beginLogin(ctx, attempts); err != nil {
+		return nil, err
+	}
+
 	u, err := s.storages.User().Get(ctx, login)
 	if err != nil {
 		if errors.Is(err, storage.ErrRecordNotFound) {
@@ -44,10 +60,154 @@ func (s *Service) LoginUser(ctx context.Context, login user.Login, password stri
 		return nil, ErrInvalidAuthData
 	}
 
+	if err := s.succeedLogin(ctx, login, attempts); err != nil {
+		return nil, err
+	}
+
+	if u.Blocked() {
+		return nil, ErrUserBlocked
+	}
+
+	s.rehashPassword(ctx, u, password)
+
+	if err := s.syncRole(ctx, u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// RefreshUser returns the user a refreshed access token is issued for, with the role synced with the config.
+func (s *Service) RefreshUser(ctx context.Context, login user.Login) (*user.User, error) {
+	ctx, span := tracer.Start(ctx, "Service.RefreshUser")
+	defer span.End()
+
+	u, err := s.storages.User().Get(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			// the user of a valid session is deleted: the token is not valid anymore
+			return nil, ErrInvalidAuthData
+		}
+		return nil, err
+	}
+
+	if err := s.syncRole(ctx, u); err != nil {
+		return nil, err
+	}
+
 	return u, nil
 }
 
+// syncRole grants the admin role to the user with a configured admin login and revokes it from the others.
+// It is called whenever a token is issued but on registration: removing a login from the config revokes
+// the role with the next token, and registering a configured login nobody has claimed yet grants nothing.
+func (s *Service) syncRole(ctx context.Context, u *user.User) error {
+	role := user.RoleUser
+	if s.isConfiguredAdmin(u.Login) {
+		role = user.RoleAdmin
+	}
+	if u.Role == role {
+		return nil
+	}
+
+	if err := s.storages.User().UpdateRole(ctx, u.Login, role); err != nil {
+		return err
+	}
+	s.log(ctx).Info("Sync user role with config", zap.String("login", string(u.Login)), zap.String("role", string(role)))
+	u.Role = role
+
+	return nil
+}
+
+// isConfiguredAdmin reports whether the login is configured to have the admin role.
+func (s *Service) isConfiguredAdmin(login user.Login) bool {
+	for _, adminLogin := range s.cfg.AdminLogins() {
+		if adminLogin == string(login) {
+			return true
+		}
+	}
+	return false
+}
+
+// rehashPassword upgrades the user password hash made with another algorithm or weaker parameters
+// than the current policy ones. The login does not fail if the upgrade fails, it is tried again next time.
+func (s *Service) rehashPassword(ctx context.Context, u *user.User, password string) {
+	policy := s.cfg.PasswordPolicy()
+	if !policy.NeedsRehash(u.EncryptedPassword) {
+		return
+	}
+
+	if err := u.SetPassword(password, policy); err != nil {
+		s.log(ctx).Warn("Login user: rehash password error", zap.String("login", string(u.Login)), zap.Error(err))
+		return
+	}
+	if err := s.storages.User().UpdatePassword(ctx, u.Login, u.EncryptedPassword); err != nil {
+		s.log(ctx).Warn("Login user: update password hash error", zap.String("login", string(u.Login)), zap.Error(err))
+	}
+}
+
+// ChangePassword replaces the user password after the old one is verified and revokes the other sessions
+// of the user, so a stolen session does not survive the change. Wrong old passwords are counted
+// by the login lockout like failed logins.
+func (s *Service) ChangePassword(ctx context.Context, login user.Login, sessionID, oldPassword, newPassword, clientIP string) error {
+	ctx, span := tracer.Start(ctx, "Service.ChangePassword")
+	defer span.End()
+
+	attempts := s.loginAttempts(login, clientIP)
+	if err := s.beginLogin(ctx, attempts); err != nil {
+		return err
+	}
+
+	u, err := s.storages.User().Get(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return ErrInvalidAuthData
+		}
+		return err
+	}
+	if !u.VerifyPassword(oldPassword) {
+		return ErrInvalidAuthData
+	}
+
+	if err := s.succeedLogin(ctx, login, attempts); err != nil {
+		return err
+	}
+
+	if err := u.SetPassword(newPassword, s.cfg.PasswordPolicy()); err != nil {
+		if errors.Is(err, user.ErrBadPassword) {
+			return ErrInvalidPasswordFormat
+		}
+		return err
+	}
+
+	tx, err := s.storages.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.User().UpdatePassword(ctx, login, u.EncryptedPassword)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoRecordAffected) {
+			return ErrInvalidAuthData
+		}
+		return err
+	}
+
+	err = tx.Session().RevokeByUser(ctx, login, time.Now().UTC(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetUserBalance returns the user balance derived from the ledger.
+// The balance stored with the user is checked against it, a mismatch is logged.
 func (s *Service) GetUserBalance(ctx context.Context, login user.Login) (*decimal.Decimal, error) {
+	ctx, span := tracer.Start(ctx, "Service.GetUserBalance")
+	defer span.End()
+
 	u, err := s.storages.User().Get(ctx, login)
 	if err != nil {
 		if errors.Is(err, storage.ErrRecordNotFound) {
@@ -56,5 +216,29 @@ func (s *Service) GetUserBalance(ctx context.Context, login user.Login) (*decima
 		return nil, err
 	}
 
-	return &u.Balance, nil
+	balance, err := s.storages.Ledger().BalanceByUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if !balance.Equal(u.Balance) {
+		s.log(ctx).Error("Get user balance: user balance does not match the ledger",
+			zap.String("login", string(login)),
+			zap.String("user balance", u.Balance.String()),
+			zap.String("ledger balance", balance.String()))
+	}
+
+	return balance, nil
+}
+
+// ListUserLedgerEntries returns all user balance changes.
+func (s *Service) ListUserLedgerEntries(ctx context.Context, login user.Login) ([]ledger.Entry, error) {
+	ctx, span := tracer.Start(ctx, "Service.ListUserLedgerEntries")
+	defer span.End()
+
+	entries, err := s.storages.Ledger().GetByUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
 }
@@ -2,10 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/lockout"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
+	"github.com/Karzoug/loyalty_program/internal/repository/storage"
+	"github.com/google/uuid"
 	"github.com/pioz/faker"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -77,23 +82,206 @@ func TestService_LoginUser(t *testing.T) {
 	assert.True(t, ru.VerifyPassword(password))
 
 	t.Run("positive", func(t *testing.T) {
-		lu, err := service.LoginUser(ctx, login, password)
+		lu, err := service.LoginUser(ctx, login, password, "")
 		require.NoError(t, err)
 		assert.Equal(t, login, lu.Login)
 		assert.True(t, lu.VerifyPassword(password))
 	})
 	t.Run("negative: invalid password", func(t *testing.T) {
 		password2 := faker.StringWithSize(15)
-		_, err = service.LoginUser(ctx, login, password2)
+		_, err = service.LoginUser(ctx, login, password2, "")
 		assert.ErrorIs(t, err, ErrInvalidAuthData)
 	})
 	t.Run("negative: user not exists", func(t *testing.T) {
 		login2 := user.Login(faker.Username())
-		_, err = service.LoginUser(ctx, login2, password)
+		_, err = service.LoginUser(ctx, login2, password, "")
 		assert.ErrorIs(t, err, ErrInvalidAuthData)
 	})
 }
 
+func TestService_LoginUserLockout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+	policy := service.cfg.LoginLockoutPolicy()
+
+	password := faker.StringWithSize(15)
+	register := func() user.Login {
+		login := user.Login(faker.Username())
+		_, err := service.RegisterUser(ctx, login, password)
+		require.NoError(t, err)
+		return login
+	}
+
+	t.Run("login is locked out after max attempts", func(t *testing.T) {
+		login := register()
+		for i := 0; i < policy.MaxAttempts; i++ {
+			_, err := service.LoginUser(ctx, login, "wrong password", "")
+			require.ErrorIs(t, err, ErrInvalidAuthData)
+		}
+
+		_, err := service.LoginUser(ctx, login, password, "")
+		require.ErrorIs(t, err, ErrTooManyLoginAttempts)
+		var lockErr *LoginLockedError
+		require.ErrorAs(t, err, &lockErr)
+		assert.True(t, lockErr.RetryAfter > 0 && lockErr.RetryAfter <= policy.BaseDelay)
+	})
+	t.Run("success resets login failures", func(t *testing.T) {
+		login := register()
+		for i := 0; i < policy.MaxAttempts-1; i++ {
+			_, err := service.LoginUser(ctx, login, "wrong password", "")
+			require.ErrorIs(t, err, ErrInvalidAuthData)
+		}
+		_, err := service.LoginUser(ctx, login, password, "")
+		require.NoError(t, err)
+
+		_, err = service.LoginUser(ctx, login, "wrong password", "")
+		require.ErrorIs(t, err, ErrInvalidAuthData)
+		_, err = service.LoginUser(ctx, login, password, "")
+		require.NoError(t, err)
+	})
+	t.Run("parallel attempts do not pass the lockout together", func(t *testing.T) {
+		login := register()
+
+		const attemptsCount = 20
+		errs := make(chan error, attemptsCount)
+		var wg sync.WaitGroup
+		wg.Add(attemptsCount)
+		for i := 0; i < attemptsCount; i++ {
+			go func() {
+				defer wg.Done()
+				_, err := service.LoginUser(ctx, login, "wrong password", "")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// only the attempts counted before the lockout verify the password
+		var verified int
+		for err := range errs {
+			if errors.Is(err, ErrInvalidAuthData) {
+				verified++
+				continue
+			}
+			require.ErrorIs(t, err, ErrTooManyLoginAttempts)
+		}
+		assert.Equal(t, policy.MaxAttempts, verified)
+	})
+	t.Run("client IP is locked out across logins", func(t *testing.T) {
+		ip := "192.0.2.1"
+		for i := 0; i < service.cfg.LoginIPLockoutPolicy().MaxAttempts; i++ {
+			_, err := service.LoginUser(ctx, user.Login(faker.Username()), "wrong password", ip)
+			require.ErrorIs(t, err, ErrInvalidAuthData)
+		}
+
+		login := register()
+		_, err := service.LoginUser(ctx, login, password, ip)
+		require.ErrorIs(t, err, ErrTooManyLoginAttempts)
+
+		_, err = service.LoginUser(ctx, login, password, "192.0.2.2")
+		require.NoError(t, err)
+	})
+	t.Run("forgotten attempts are deleted", func(t *testing.T) {
+		key := lockout.LoginKey(user.Login(faker.Username()))
+		at := time.Now().UTC().Add(-lockout.FailuresTTL - time.Minute)
+		_, err := service.storages.LoginAttempt().AddFailure(ctx, key, at, at.Add(-lockout.FailuresTTL))
+		require.NoError(t, err)
+
+		_, err = service.LoginUser(ctx, user.Login(faker.Username()), "wrong password", "")
+		require.ErrorIs(t, err, ErrInvalidAuthData)
+
+		_, err = service.storages.LoginAttempt().Get(ctx, key)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+	})
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err := service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+	current, _, err := service.CreateSession(ctx, login)
+	require.NoError(t, err)
+	other, _, err := service.CreateSession(ctx, login)
+	require.NoError(t, err)
+
+	t.Run("negative: invalid old password", func(t *testing.T) {
+		err := service.ChangePassword(ctx, login, current.ID, "wrong password", faker.StringWithSize(15), "")
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+	})
+	t.Run("negative: invalid new password", func(t *testing.T) {
+		err := service.ChangePassword(ctx, login, current.ID, password, faker.StringWithSize(100), "")
+		assert.ErrorIs(t, err, ErrInvalidPasswordFormat)
+	})
+	t.Run("positive", func(t *testing.T) {
+		newPassword := faker.StringWithSize(15)
+		require.NoError(t, service.ChangePassword(ctx, login, current.ID, password, newPassword, ""))
+
+		_, err := service.LoginUser(ctx, login, password, "")
+		assert.ErrorIs(t, err, ErrInvalidAuthData)
+		_, err = service.LoginUser(ctx, login, newPassword, "")
+		assert.NoError(t, err)
+
+		// the other sessions are revoked, the current one is kept
+		revoked, err := service.IsTokenRevoked(ctx, uuid.NewString(), other.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		revoked, err = service.IsTokenRevoked(ctx, uuid.NewString(), current.ID)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+	t.Run("wrong old passwords are locked out", func(t *testing.T) {
+		login := user.Login(faker.Username())
+		_, err := service.RegisterUser(ctx, login, password)
+		require.NoError(t, err)
+		ss, _, err := service.CreateSession(ctx, login)
+		require.NoError(t, err)
+
+		for i := 0; i < service.cfg.LoginLockoutPolicy().MaxAttempts; i++ {
+			err := service.ChangePassword(ctx, login, ss.ID, "wrong password", faker.StringWithSize(15), "")
+			require.ErrorIs(t, err, ErrInvalidAuthData)
+		}
+		err = service.ChangePassword(ctx, login, ss.ID, password, faker.StringWithSize(15), "")
+		assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+	})
+}
+
+func TestService_LoginUserRehashPassword(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelCtx()
+
+	service := newMockServiceWithEmptyProcessor(ctx, t)
+
+	login := user.Login(faker.Username())
+	password := faker.StringWithSize(15)
+	_, err := service.RegisterUser(ctx, login, password)
+	require.NoError(t, err)
+
+	policy := user.PasswordPolicy{Algorithm: user.HashArgon2id, Argon2: user.Argon2Params{Time: 1, Memory: 64, Threads: 1}}
+	service.cfg = mockConfig{passwordPolicy: &policy}
+
+	_, err = service.LoginUser(ctx, login, password, "")
+	require.NoError(t, err)
+
+	u, err := service.storages.User().Get(ctx, login)
+	require.NoError(t, err)
+	assert.False(t, policy.NeedsRehash(u.EncryptedPassword))
+	assert.True(t, u.VerifyPassword(password))
+}
+
 func TestService_GetUserBalance(t *testing.T) {
 	t.Parallel()
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
 	"github.com/Karzoug/loyalty_program/internal/model/order"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/Karzoug/loyalty_program/internal/model/withdraw"
@@ -13,9 +14,13 @@ import (
 )
 
 func (s *Service) CreateWithdraw(ctx context.Context, login user.Login, orderNumber order.Number, sum decimal.Decimal) (*withdraw.Withdraw, error) {
-	w, err := withdraw.New(login, orderNumber, sum)
+	ctx, span := tracer.Start(ctx, "Service.CreateWithdraw")
+	defer span.End()
+
+	w, err := withdraw.New(login, orderNumber, sum, s.cfg.MoneyPolicy())
 	if err != nil {
-		if errors.Is(err, order.ErrInvalidNumber) {
+		switch {
+		case errors.Is(err, order.ErrInvalidNumber):
 			return nil, ErrInvalidOrderNumber
 		}
 		return nil, err
@@ -35,7 +40,18 @@ func (s *Service) CreateWithdraw(ctx context.Context, login user.Login, orderNum
 		}
 	}()
 
-	result, err := tx.User().UpdateBalance(ctx, login, sum.Neg())
+	u, err := tx.User().GetForUpdate(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, ErrInvalidAuthData
+		}
+		return nil, err
+	}
+	if u.Blocked() {
+		return nil, ErrUserBlocked
+	}
+
+	result, err := tx.User().UpdateBalance(ctx, login, w.Sum.Neg())
 	if err != nil {
 		if errors.Is(err, storage.ErrRecordNotFound) {
 			return nil, ErrInvalidAuthData
@@ -67,6 +83,15 @@ func (s *Service) CreateWithdraw(ctx context.Context, login user.Login, orderNum
 		return nil, err
 	}
 
+	entry, err := ledger.New(login, orderNumber, ledger.OperationWithdrawal, w.Sum.Neg())
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Ledger().Create(ctx, *entry)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
@@ -75,6 +100,9 @@ func (s *Service) CreateWithdraw(ctx context.Context, login user.Login, orderNum
 }
 
 func (s *Service) ListUserWithdrawals(ctx context.Context, login user.Login) ([]withdraw.Withdraw, error) {
+	ctx, span := tracer.Start(ctx, "Service.ListUserWithdrawals")
+	defer span.End()
+
 	ws, err := s.storages.Withdraw().GetByUser(ctx, login)
 	if err != nil {
 		return nil, err
@@ -83,7 +111,36 @@ func (s *Service) ListUserWithdrawals(ctx context.Context, login user.Login) ([]
 	return ws, nil
 }
 
+// ListUserWithdrawalsPage returns a page of user withdrawals matching the filter
+// and the cursor of the next page (nil if the page is the last one).
+func (s *Service) ListUserWithdrawalsPage(ctx context.Context, login user.Login, filter storage.WithdrawFilter) ([]withdraw.Withdraw, *storage.Cursor, error) {
+	ctx, span := tracer.Start(ctx, "Service.ListUserWithdrawalsPage")
+	defer span.End()
+
+	limit := filter.Limit
+	if limit > 0 {
+		// one extra withdrawal tells whether the next page exists
+		filter.Limit++
+	}
+
+	ws, err := s.storages.Withdraw().ListByUser(ctx, login, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if limit <= 0 || len(ws) <= limit {
+		return ws, nil, nil
+	}
+	ws = ws[:limit]
+	last := ws[limit-1]
+
+	return ws, &storage.Cursor{Time: last.ProcessedAt, Number: last.OrderNumber}, nil
+}
+
 func (s *Service) SumUserWithdrawals(ctx context.Context, login user.Login) (*decimal.Decimal, error) {
+	ctx, span := tracer.Start(ctx, "Service.SumUserWithdrawals")
+	defer span.End()
+
 	sum, err := s.storages.Withdraw().SumByUser(ctx, login)
 	if err != nil {
 		return nil, err
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Karzoug/loyalty_program/internal/model/ledger"
 	"github.com/Karzoug/loyalty_program/internal/model/user"
 	"github.com/pioz/faker"
 	"github.com/shopspring/decimal"
@@ -43,6 +44,13 @@ func TestService_CreateWithdraw(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, login, u.Login)
 		assert.True(t, u.Balance.RoundBank(4).Equal(balance.Sub(withdrawSum.RoundBank(4))))
+
+		entries, err := service.ListUserLedgerEntries(ctx, login)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, ledger.OperationWithdrawal, entries[0].Operation)
+		assert.Equal(t, orderNumber, entries[0].OrderNumber)
+		assert.True(t, entries[0].Amount.RoundBank(4).Equal(withdrawSum.Neg().RoundBank(4)))
 	})
 
 	t.Run("negative: invalid order number", func(t *testing.T) {
@@ -93,6 +101,25 @@ func TestService_CreateWithdraw(t *testing.T) {
 		assert.ErrorIs(t, err, ErrInsufficientBalance)
 	})
 
+	t.Run("sum more precise than money policy is rejected", func(t *testing.T) {
+		login := user.Login(faker.Username())
+		password := faker.StringWithSize(15)
+		_, err := service.RegisterUser(ctx, login, password)
+		require.NoError(t, err)
+		_, err = service.storages.User().UpdateBalance(ctx, login, decimal.NewFromInt(20))
+		require.NoError(t, err)
+
+		_, err = service.CreateWithdraw(ctx, login, generateOrderNumber(t), decimal.RequireFromString("10.005"))
+		assert.ErrorIs(t, err, ErrTooPreciseSum)
+
+		w, err := service.CreateWithdraw(ctx, login, generateOrderNumber(t), decimal.RequireFromString("10.01"))
+		require.NoError(t, err)
+		assert.Equal(t, "10.01", w.Sum.String())
+
+		u, err := service.storages.User().Get(ctx, login)
+		require.NoError(t, err)
+		assert.Equal(t, "9.99", u.Balance.String())
+	})
 }
 
 func TestService_SumUserWithdrawals(t *testing.T) {
@@ -115,19 +142,19 @@ func TestService_SumUserWithdrawals(t *testing.T) {
 
 	// first withdraw
 	orderNumber := generateOrderNumber(t)
-	withdrawSum1 := decimal.NewFromFloat(faker.Float64InRange(100, 400))
+	withdrawSum1 := decimal.NewFromFloat(faker.Float64InRange(100, 400)).Round(2)
 	_, err = service.CreateWithdraw(ctx, login, orderNumber, withdrawSum1)
 	require.NoError(t, err)
 
 	// second withdraw
 	orderNumber = generateOrderNumber(t)
-	withdrawSum2 := decimal.NewFromFloat(faker.Float64InRange(1, 50))
+	withdrawSum2 := decimal.NewFromFloat(faker.Float64InRange(1, 50)).Round(2)
 	_, err = service.CreateWithdraw(ctx, login, orderNumber, withdrawSum2)
 	require.NoError(t, err)
 
 	// third withdraw
 	orderNumber = generateOrderNumber(t)
-	withdrawSum3 := decimal.NewFromFloat(faker.Float64InRange(50, 200))
+	withdrawSum3 := decimal.NewFromFloat(faker.Float64InRange(50, 200)).Round(2)
 	_, err = service.CreateWithdraw(ctx, login, orderNumber, withdrawSum3)
 	require.NoError(t, err)
 
@@ -168,13 +195,13 @@ func TestService_ListUserWithdrawals(t *testing.T) {
 
 	// first user, first withdraw
 	orderNumber := generateOrderNumber(t)
-	withdrawSum1 := decimal.NewFromFloat(faker.Float64InRange(100, 400))
+	withdrawSum1 := decimal.NewFromFloat(faker.Float64InRange(100, 400)).Round(2)
 	_, err = service.CreateWithdraw(ctx, login, orderNumber, withdrawSum1)
 	require.NoError(t, err)
 
 	// first user, second withdraw
 	orderNumber = generateOrderNumber(t)
-	withdrawSum2 := decimal.NewFromFloat(faker.Float64InRange(1, 50))
+	withdrawSum2 := decimal.NewFromFloat(faker.Float64InRange(1, 50)).Round(2)
 	_, err = service.CreateWithdraw(ctx, login, orderNumber, withdrawSum2)
 	require.NoError(t, err)
 
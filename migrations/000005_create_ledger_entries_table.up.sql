@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "ledger_entries" (
+    "id" varchar(36) PRIMARY KEY,
+	"user_login" varchar(100) NOT NULL REFERENCES users (login),
+	"order_number" bigint NOT NULL,
+	"operation" smallint NOT NULL,
+	"amount" numeric NOT NULL,
+	"created_at" timestamp NOT NULL);
+CREATE INDEX ledger_entries_user_login_index ON ledger_entries (user_login);
+CREATE UNIQUE INDEX ledger_entries_order_operation_index ON ledger_entries (order_number, operation) WHERE operation IN (0, 1);
+INSERT INTO ledger_entries (id, user_login, order_number, operation, amount, created_at)
+    SELECT 'accrual-' || number, user_login, number, 0, accrual, uploaded_at FROM orders WHERE status = 3 AND accrual > 0;
+INSERT INTO ledger_entries (id, user_login, order_number, operation, amount, created_at)
+    SELECT 'withdrawal-' || order_number, user_login, order_number, 1, -sum, processed_at FROM withdrawals;
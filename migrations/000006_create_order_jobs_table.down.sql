@@ -0,0 +1 @@
+DROP TABLE "order_jobs";
\ No newline at end of file
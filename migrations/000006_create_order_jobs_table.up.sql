@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "order_jobs" (
+    "order_number" bigint PRIMARY KEY REFERENCES orders (number),
+	"attempts" integer NOT NULL DEFAULT 0,
+	"next_run_at" timestamp NOT NULL,
+	"last_error" text NOT NULL DEFAULT '',
+	"created_at" timestamp NOT NULL);
+CREATE INDEX order_jobs_next_run_at_index ON order_jobs (next_run_at);
+INSERT INTO order_jobs (order_number, next_run_at, created_at)
+    SELECT number, uploaded_at, uploaded_at FROM orders WHERE status NOT IN (2, 3);
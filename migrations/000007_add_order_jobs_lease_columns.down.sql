@@ -0,0 +1,2 @@
+ALTER TABLE "order_jobs" DROP COLUMN "locked_until";
+ALTER TABLE "order_jobs" DROP COLUMN "locked_by";
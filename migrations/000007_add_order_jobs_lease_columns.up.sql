@@ -0,0 +1,2 @@
+ALTER TABLE "order_jobs" ADD COLUMN "locked_by" varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE "order_jobs" ADD COLUMN "locked_until" timestamp;
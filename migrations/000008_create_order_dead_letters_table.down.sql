@@ -0,0 +1 @@
+DROP TABLE "order_dead_letters";
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS "order_dead_letters" (
+    "order_number" bigint PRIMARY KEY REFERENCES orders (number),
+	"attempts" integer NOT NULL,
+	"reason" text NOT NULL,
+	"created_at" timestamp NOT NULL,
+	"dead_lettered_at" timestamp NOT NULL);
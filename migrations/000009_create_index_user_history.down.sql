@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_user_uploaded_index;
+DROP INDEX IF EXISTS withdrawals_user_processed_index;
\ No newline at end of file
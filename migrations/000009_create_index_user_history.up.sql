@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS orders_user_uploaded_index ON orders (user_login, uploaded_at, number);
+CREATE INDEX IF NOT EXISTS withdrawals_user_processed_index ON withdrawals (user_login, processed_at, order_number);
\ No newline at end of file
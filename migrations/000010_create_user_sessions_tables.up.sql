@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "user_sessions" (
+    "id" varchar(36) PRIMARY KEY,
+	"user_login" varchar(100) NOT NULL REFERENCES users (login),
+	"refresh_token_hash" varchar(64) NOT NULL UNIQUE,
+	"previous_token_hash" varchar(64) NOT NULL DEFAULT '',
+	"created_at" timestamp NOT NULL,
+	"expires_at" timestamp NOT NULL,
+	"revoked_at" timestamp);
+CREATE INDEX user_sessions_user_login_index ON user_sessions (user_login);
+CREATE INDEX user_sessions_previous_token_hash_index ON user_sessions (previous_token_hash);
+CREATE TABLE IF NOT EXISTS "revoked_tokens" (
+    "jti" varchar(36) PRIMARY KEY,
+	"expires_at" timestamp NOT NULL);
\ No newline at end of file
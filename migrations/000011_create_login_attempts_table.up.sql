@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS "login_attempts" (
+    "attempt_key" text PRIMARY KEY,
+	"failures" integer NOT NULL,
+	"last_failure_at" timestamp NOT NULL,
+	"locked_until" timestamp);
\ No newline at end of file
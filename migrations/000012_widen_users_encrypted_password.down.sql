@@ -0,0 +1,2 @@
+-- Fails while argon2id hashes longer than 60 characters are stored: the users have to change their passwords first.
+ALTER TABLE "users" ALTER COLUMN "encrypted_password" TYPE varchar(60);
\ No newline at end of file
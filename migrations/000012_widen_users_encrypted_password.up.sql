@@ -0,0 +1 @@
+ALTER TABLE "users" ALTER COLUMN "encrypted_password" TYPE text;
\ No newline at end of file
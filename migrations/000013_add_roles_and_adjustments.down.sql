@@ -0,0 +1,3 @@
+ALTER TABLE "ledger_entries" DROP COLUMN "created_by";
+ALTER TABLE "ledger_entries" DROP COLUMN "reason";
+ALTER TABLE "users" DROP COLUMN "role";
\ No newline at end of file
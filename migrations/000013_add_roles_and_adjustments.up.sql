@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar(20) NOT NULL DEFAULT 'user';
+ALTER TABLE "ledger_entries" ADD COLUMN "reason" text NOT NULL DEFAULT '';
+ALTER TABLE "ledger_entries" ADD COLUMN "created_by" varchar(100) NOT NULL DEFAULT '';
\ No newline at end of file
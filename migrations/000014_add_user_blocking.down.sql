@@ -0,0 +1,3 @@
+DROP TABLE "accrual_holds";
+ALTER TABLE "users" DROP COLUMN "blocked_reason";
+ALTER TABLE "users" DROP COLUMN "blocked_at";
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE "users" ADD COLUMN "blocked_at" timestamp;
+ALTER TABLE "users" ADD COLUMN "blocked_reason" text NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS "accrual_holds" (
+    "order_number" bigint PRIMARY KEY,
+	"user_login" varchar(100) NOT NULL REFERENCES users (login),
+	"amount" numeric NOT NULL,
+	"created_at" timestamp NOT NULL);
+CREATE INDEX accrual_holds_user_login_index ON accrual_holds (user_login);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "order_jobs" DROP COLUMN "trace_parent";
\ No newline at end of file
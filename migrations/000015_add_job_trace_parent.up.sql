@@ -0,0 +1 @@
+ALTER TABLE "order_jobs" ADD COLUMN "trace_parent" text NOT NULL DEFAULT '';
\ No newline at end of file
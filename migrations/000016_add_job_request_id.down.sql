@@ -0,0 +1 @@
+ALTER TABLE "order_jobs" DROP COLUMN "request_id";
\ No newline at end of file
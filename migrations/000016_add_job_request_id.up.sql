@@ -0,0 +1 @@
+ALTER TABLE "order_jobs" ADD COLUMN "request_id" text NOT NULL DEFAULT '';
\ No newline at end of file
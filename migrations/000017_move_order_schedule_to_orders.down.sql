@@ -0,0 +1,10 @@
+ALTER TABLE "order_jobs" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
+ALTER TABLE "order_jobs" ADD COLUMN "next_run_at" timestamp;
+ALTER TABLE "order_jobs" ADD COLUMN "last_error" text NOT NULL DEFAULT '';
+UPDATE order_jobs SET attempts = orders.attempts, next_run_at = COALESCE(orders.next_check_at, order_jobs.created_at), last_error = orders.last_error
+    FROM orders WHERE orders.number = order_jobs.order_number;
+CREATE INDEX order_jobs_next_run_at_index ON order_jobs (next_run_at);
+DROP INDEX orders_next_check_at_index;
+ALTER TABLE "orders" DROP COLUMN "attempts";
+ALTER TABLE "orders" DROP COLUMN "next_check_at";
+ALTER TABLE "orders" DROP COLUMN "last_error";
@@ -0,0 +1,10 @@
+ALTER TABLE "orders" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
+ALTER TABLE "orders" ADD COLUMN "next_check_at" timestamp;
+ALTER TABLE "orders" ADD COLUMN "last_error" text NOT NULL DEFAULT '';
+UPDATE orders SET attempts = order_jobs.attempts, next_check_at = order_jobs.next_run_at, last_error = order_jobs.last_error
+    FROM order_jobs WHERE order_jobs.order_number = orders.number;
+CREATE INDEX orders_next_check_at_index ON orders (next_check_at) WHERE next_check_at IS NOT NULL;
+DROP INDEX order_jobs_next_run_at_index;
+ALTER TABLE "order_jobs" DROP COLUMN "attempts";
+ALTER TABLE "order_jobs" DROP COLUMN "next_run_at";
+ALTER TABLE "order_jobs" DROP COLUMN "last_error";
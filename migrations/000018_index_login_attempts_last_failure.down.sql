@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "login_attempts_last_failure_at_index";
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS "login_attempts_last_failure_at_index" ON "login_attempts" ("last_failure_at");
\ No newline at end of file
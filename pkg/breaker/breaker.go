@@ -0,0 +1,132 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int8
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	return [...]string{"CLOSED", "OPEN", "HALF-OPEN"}[s]
+}
+
+// Breaker is a circuit breaker: it opens after failureThreshold consecutive failures
+// and rejects requests for openTimeout. Then it becomes half-open and lets through
+// up to halfOpenMaxRequests trial requests: it closes after all of them succeed
+// and opens again after any of them fails.
+type Breaker struct {
+	failureThreshold    int
+	openTimeout         time.Duration
+	halfOpenMaxRequests int
+	onStateChange       func(from, to State)
+
+	mu               sync.Mutex
+	state            State
+	failures         int
+	halfOpenRequests int
+	halfOpenSuccess  int
+	openedAt         time.Time
+}
+
+// New creates a closed Breaker. The onStateChange function (if not nil) is called on each state change.
+func New(failureThreshold int, openTimeout time.Duration, halfOpenMaxRequests int, onStateChange func(from, to State)) *Breaker {
+	return &Breaker{
+		failureThreshold:    failureThreshold,
+		openTimeout:         openTimeout,
+		halfOpenMaxRequests: halfOpenMaxRequests,
+		onStateChange:       onStateChange,
+	}
+}
+
+// State returns the current breaker state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	return b.state
+}
+
+// Allow checks whether a request may be done and returns ErrOpen if not.
+// Each allowed request must be followed by a call of Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	switch b.state {
+	case StateOpen:
+		return ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenRequests >= b.halfOpenMaxRequests {
+			return ErrOpen
+		}
+		b.halfOpenRequests++
+	}
+	return nil
+}
+
+// Success records a succeeded request.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+	case StateHalfOpen:
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.halfOpenMaxRequests {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// Failure records a failed request.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		b.setState(StateOpen)
+	}
+}
+
+// refresh makes the open breaker half-open after the open timeout.
+func (b *Breaker) refresh() {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+
+	b.state = state
+	b.failures = 0
+	b.halfOpenRequests = 0
+	b.halfOpenSuccess = 0
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+
+	if b.onStateChange != nil && from != state {
+		b.onStateChange(from, state)
+	}
+}
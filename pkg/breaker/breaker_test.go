@@ -0,0 +1,56 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	const openTimeout = 50 * time.Millisecond
+
+	var changes []State
+	b := New(3, openTimeout, 2, func(from, to State) {
+		changes = append(changes, to)
+	})
+
+	// closed: failures below the threshold and success resets them
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.NoError(t, b.Allow())
+	b.Success()
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	// open: the threshold is reached
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	// half-open: only limited trial requests are allowed, any failure opens the breaker again
+	time.Sleep(openTimeout)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.NoError(t, b.Allow())
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+	b.Success()
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+
+	// closed: all trial requests succeeded
+	time.Sleep(openTimeout)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Success()
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}, changes)
+}
@@ -0,0 +1,319 @@
+// Package jwtkeys signs and verifies JWTs with a set of keys:
+// one key signs new tokens and all keys of the set verify tokens by their kid header.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// keyFileExt is an extension of PEM key files in a key directory, a file name without it is a key ID.
+	keyFileExt = ".pem"
+)
+
+var (
+	ErrUnsupportedAlg = errors.New("unsupported signing algorithm")
+	ErrNoSigningKey   = errors.New("no signing key")
+	ErrUnknownKeyID   = errors.New("unknown key id")
+)
+
+// KeySet is a set of keys of the same algorithm to sign and verify JWTs.
+type KeySet struct {
+	alg        jwa.SignatureAlgorithm
+	signKeyID  string
+	signKey    interface{}
+	verifyKeys map[string]interface{}
+	jwks       []byte
+}
+
+// NewHMAC returns a key set of the single HS256 secret. Tokens are signed without kid.
+func NewHMAC(secret []byte) (*KeySet, error) {
+	if len(secret) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	return &KeySet{
+		alg:        jwa.HS256,
+		signKey:    secret,
+		verifyKeys: map[string]interface{}{"": secret},
+		// secrets are never published
+		jwks: []byte(`{"keys":[]}`),
+	}, nil
+}
+
+// LoadDir returns a key set of the RS256 or EdDSA keys from *.pem files of the directory,
+// the file name is the key ID. Files contain PKCS#8 private keys or PKIX public keys:
+// all of them verify tokens, private ones may sign tokens.
+// The key with the signingKeyID signs tokens, if the ID is empty, the private key with the greatest ID is used:
+// IDs are compared naturally, so the key "10" follows "9".
+func LoadDir(alg, dir, signingKeyID string) (*KeySet, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, alg)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+keyFileExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return kidLess(keyID(files[i]), keyID(files[j]))
+	})
+
+	ks := KeySet{
+		alg:        jwa.SignatureAlgorithm(alg),
+		verifyKeys: make(map[string]interface{}, len(files)),
+	}
+	jwks := jwk.NewSet()
+	for _, file := range files {
+		kid := keyID(file)
+
+		private, public, err := readKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read key %s: %w", kid, err)
+		}
+		if !matchAlg(alg, public) {
+			return nil, fmt.Errorf("key %s: %T key is not suitable for %s", kid, public, alg)
+		}
+
+		ks.verifyKeys[kid] = public
+		if private != nil && (signingKeyID == "" || signingKeyID == kid) {
+			ks.signKeyID, ks.signKey = kid, private
+		}
+
+		key, err := newPublicJWK(kid, alg, public)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+		jwks.Add(key)
+	}
+
+	if ks.signKey == nil {
+		if signingKeyID != "" {
+			return nil, fmt.Errorf("%w: no private key %s in %s", ErrNoSigningKey, signingKeyID, dir)
+		}
+		return nil, fmt.Errorf("%w: no private keys in %s", ErrNoSigningKey, dir)
+	}
+
+	ks.jwks, err = json.Marshal(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ks, nil
+}
+
+// keyID returns the key ID of the key file.
+func keyID(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), keyFileExt)
+}
+
+// kidLess orders key IDs naturally: runs of digits are compared as numbers,
+// so "10" follows "9" and "2023-10" follows "2023-9".
+func kidLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da == "" || db == "" {
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+		// equal numbers with different leading zeros are ordered as strings
+		if da != db {
+			return da < db
+		}
+		a, b = a[len(da):], b[len(db):]
+	}
+	return len(a) < len(b)
+}
+
+// leadingDigits returns the run of ASCII digits the string starts with.
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// Alg is the signing algorithm of the key set.
+func (ks *KeySet) Alg() string {
+	return ks.alg.String()
+}
+
+// SigningKeyID is an ID of the key signing new tokens (empty for HS256).
+func (ks *KeySet) SigningKeyID() string {
+	return ks.signKeyID
+}
+
+// JWKS returns the JSON Web Key Set of the public verification keys.
+func (ks *KeySet) JWKS() []byte {
+	return ks.jwks
+}
+
+// Encode signs a new token with the claims.
+func (ks *KeySet) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	t := jwt.New()
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	hdrs := jws.NewHeaders()
+	if ks.signKeyID != "" {
+		if err := hdrs.Set(jws.KeyIDKey, ks.signKeyID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	payload, err := jwt.Sign(t, ks.alg, ks.signKey, jwt.WithHeaders(hdrs))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, string(payload), nil
+}
+
+// Decode verifies the token signature with the key of the token kid and parses the token.
+func (ks *KeySet) Decode(tokenString string) (jwt.Token, error) {
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, jwtauth.ErrUnauthorized
+	}
+
+	hdrs := msg.Signatures()[0].ProtectedHeaders()
+	if hdrs.Algorithm() != ks.alg {
+		return nil, jwtauth.ErrAlgoInvalid
+	}
+	key, ok := ks.verifyKeys[hdrs.KeyID()]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	return jwt.ParseString(tokenString, jwt.WithVerify(ks.alg, key))
+}
+
+// Verify is a middleware like jwtauth.Verify: it verifies a token found in the request
+// and puts the token and the verification error into the request context for jwtauth.Authenticator.
+func (ks *KeySet) Verify(findTokenFns ...func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := ks.verifyRequest(r, findTokenFns...)
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (ks *KeySet) verifyRequest(r *http.Request, findTokenFns ...func(r *http.Request) string) (jwt.Token, error) {
+	var tokenString string
+	for _, fn := range findTokenFns {
+		if tokenString = fn(r); tokenString != "" {
+			break
+		}
+	}
+	if tokenString == "" {
+		return nil, jwtauth.ErrNoTokenFound
+	}
+
+	token, err := ks.Decode(tokenString)
+	if err != nil {
+		return nil, jwtauth.ErrorReason(err)
+	}
+	if err := jwt.Validate(token); err != nil {
+		return token, jwtauth.ErrorReason(err)
+	}
+
+	return token, nil
+}
+
+func readKeyFile(file string) (private crypto.Signer, public crypto.PublicKey, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, signer.Public(), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, key, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+func matchAlg(alg string, public crypto.PublicKey) bool {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return alg == AlgRS256
+	case ed25519.PublicKey:
+		return alg == AlgEdDSA
+	default:
+		return false
+	}
+}
+
+func newPublicJWK(kid, alg string, public crypto.PublicKey) (jwk.Key, error) {
+	key, err := jwk.New(public)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range map[string]string{jwk.KeyIDKey: kid, jwk.AlgorithmKey: alg, jwk.KeyUsageKey: "sig"} {
+		if err := key.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,184 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T, dir, kid string, key crypto.Signer) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, kid string, key crypto.PublicKey) {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	writePEM(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+keyFileExt), data, 0o600))
+}
+
+func newClaims() map[string]interface{} {
+	claims := map[string]interface{}{"sub": "user"}
+	jwtauth.SetExpiryIn(claims, time.Minute)
+	return claims
+}
+
+func TestLoadDir(t *testing.T) {
+	_, edKey1, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, edKey2, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	t.Run("rotation", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivateKey(t, dir, "2023-01", edKey1)
+		old, err := LoadDir(AlgEdDSA, dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, "2023-01", old.SigningKeyID())
+		_, oldToken, err := old.Encode(newClaims())
+		require.NoError(t, err)
+
+		// a new key signs tokens, the old one still verifies tokens issued before
+		writePrivateKey(t, dir, "2023-02", edKey2)
+		ks, err := LoadDir(AlgEdDSA, dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, "2023-02", ks.SigningKeyID())
+		assert.Equal(t, AlgEdDSA, ks.Alg())
+
+		_, token, err := ks.Encode(newClaims())
+		require.NoError(t, err)
+		for _, tokenString := range []string{oldToken, token} {
+			parsed, err := ks.Decode(tokenString)
+			require.NoError(t, err)
+			assert.Equal(t, "user", parsed.Subject())
+		}
+
+		// the old instance does not know the new key
+		_, err = old.Decode(token)
+		assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+		var jwks struct {
+			Keys []map[string]string `json:"keys"`
+		}
+		require.NoError(t, json.Unmarshal(ks.JWKS(), &jwks))
+		require.Len(t, jwks.Keys, 2)
+		for _, key := range jwks.Keys {
+			assert.Equal(t, "OKP", key["kty"])
+			assert.Equal(t, AlgEdDSA, key["alg"])
+			assert.Equal(t, "sig", key["use"])
+			assert.Empty(t, key["d"], "private part is published")
+		}
+	})
+
+	t.Run("numeric key ids", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivateKey(t, dir, "9", edKey1)
+		writePrivateKey(t, dir, "10", edKey2)
+
+		ks, err := LoadDir(AlgEdDSA, dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, "10", ks.SigningKeyID())
+	})
+
+	t.Run("signing key id and public keys", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivateKey(t, dir, "a", rsaKey)
+		writePublicKey(t, dir, "z", &rsaKey.PublicKey)
+
+		ks, err := LoadDir(AlgRS256, dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, "a", ks.SigningKeyID())
+
+		_, err = LoadDir(AlgRS256, dir, "z")
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		_, err := LoadDir(AlgRS256, t.TempDir(), "")
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+
+		_, err = LoadDir(AlgHS256, t.TempDir(), "")
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+
+		dir := t.TempDir()
+		writePrivateKey(t, dir, "rsa", rsaKey)
+		_, err = LoadDir(AlgEdDSA, dir, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestKeySet_Decode(t *testing.T) {
+	hmac, err := NewHMAC([]byte("secret"))
+	require.NoError(t, err)
+	_, token, err := hmac.Encode(newClaims())
+	require.NoError(t, err)
+	parsed, err := hmac.Decode(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user", parsed.Subject())
+	assert.JSONEq(t, `{"keys":[]}`, string(hmac.JWKS()))
+
+	another, err := NewHMAC([]byte("another secret"))
+	require.NoError(t, err)
+	_, err = another.Decode(token)
+	assert.Error(t, err)
+
+	// a token of another algorithm is rejected
+	dir := t.TempDir()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "ed", edKey)
+	ed, err := LoadDir(AlgEdDSA, dir, "")
+	require.NoError(t, err)
+	_, err = ed.Decode(token)
+	assert.ErrorIs(t, err, jwtauth.ErrAlgoInvalid)
+
+	_, err = hmac.Decode("not-a-token")
+	assert.Error(t, err)
+}
+
+func TestKidLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"9", "10", true},
+		{"10", "9", false},
+		{"2023-9", "2023-10", true},
+		{"2023-01", "2023-02", true},
+		{"key-2", "key-10", true},
+		{"a", "z", true},
+		{"a", "a1", true},
+		{"a1", "a1", false},
+		{"01", "1", true},
+		{"1", "01", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, kidLess(tt.a, tt.b), "%s < %s", tt.a, tt.b)
+	}
+}
@@ -0,0 +1,24 @@
+// Package logctx keeps a zap logger in the context, so the logger fields like the request ID
+// follow the work through the layers of the application.
+package logctx
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// NewContext returns a copy of the context with the logger.
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the logger of the context or the fallback logger if the context has no logger.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
@@ -0,0 +1,92 @@
+// Package realip resolves the IP of a client that connects through trusted proxies.
+package realip
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedForHeader is a header proxies append the address of the connected peer to.
+const ForwardedForHeader = "X-Forwarded-For"
+
+// ParseTrusted parses comma separated IPs and CIDRs of trusted proxies.
+func ParseTrusted(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range strings.Split(s, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ClientIP returns the IP of the client connected from the remote address (host:port).
+// If the remote peer is a trusted proxy, the forwarded for addresses are walked from the nearest one:
+// the first address that is not of a trusted proxy is the client one. Only trusted proxies append
+// addresses reliably, so the addresses before it, set by the client, are ignored.
+func ClientIP(remoteAddr string, forwardedFor []string, trusted []netip.Prefix) string {
+	client := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		client = host
+	}
+	addr, ok := parseAddr(client)
+	if !ok || !isTrusted(addr, trusted) {
+		return client
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			// a malformed address can not be trusted, the last proxy is the client then
+			break
+		}
+		client = addr.String()
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// parseAddr parses an IP address optionally with a port.
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		addrPort, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addrPort.Addr()
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
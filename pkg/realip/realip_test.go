@@ -0,0 +1,91 @@
+package realip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrusted(t *testing.T) {
+	prefixes, err := ParseTrusted(" 10.0.0.0/8, 192.0.2.1,,2001:db8::/32")
+	require.NoError(t, err)
+	require.Len(t, prefixes, 3)
+	assert.Equal(t, "10.0.0.0/8", prefixes[0].String())
+	assert.Equal(t, "192.0.2.1/32", prefixes[1].String())
+	assert.Equal(t, "2001:db8::/32", prefixes[2].String())
+
+	prefixes, err = ParseTrusted("")
+	require.NoError(t, err)
+	assert.Empty(t, prefixes)
+
+	_, err = ParseTrusted("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = ParseTrusted("proxy")
+	assert.Error(t, err)
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrusted("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "198.51.100.7:1234",
+			want:       "198.51.100.7",
+		},
+		{
+			name:         "forwarded for from an untrusted peer is ignored",
+			remoteAddr:   "198.51.100.7:1234",
+			forwardedFor: []string{"203.0.113.1"},
+			want:         "198.51.100.7",
+		},
+		{
+			name:         "trusted proxy",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1"},
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "addresses set by the client are ignored",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"192.0.2.66, 203.0.113.1", "10.0.0.2"},
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "malformed address",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1, unknown"},
+			want:         "10.0.0.1",
+		},
+		{
+			name:         "only trusted proxies",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			want:         "10.0.0.3",
+		},
+		{
+			name:         "trusted proxy without forwarded for",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: nil,
+			want:         "10.0.0.1",
+		},
+		{
+			name:         "address with port",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1:5678"},
+			want:         "203.0.113.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClientIP(tt.remoteAddr, tt.forwardedFor, trusted))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package requestid correlates work caused by a request by the request ID kept in the context.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is a header the request ID is received and sent with.
+const Header = "X-Request-ID"
+
+// maxLength limits the length of a received request ID, so it does not bloat logs.
+const maxLength = 128
+
+type ctxKey struct{}
+
+// New generates a new request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether the received request ID can be used: it is not empty,
+// not too long and consists of printable ASCII characters only.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of the context with the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID of the context (empty - there is no request ID).
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
@@ -0,0 +1,35 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "uuid", id: New(), want: true},
+		{name: "custom", id: "req-42_abc.def", want: true},
+		{name: "empty", id: "", want: false},
+		{name: "too long", id: strings.Repeat("a", maxLength+1), want: false},
+		{name: "space", id: "req 42", want: false},
+		{name: "new line", id: "req\n42", want: false},
+		{name: "not ascii", id: "запрос", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Valid(tt.id))
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	assert.Equal(t, "req-42", FromContext(NewContext(context.Background(), "req-42")))
+}
@@ -0,0 +1,135 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type adminUserResponse struct {
+	Login       string  `json:"login"`
+	Role        string  `json:"role"`
+	Current     float64 `json:"current"`
+	Withdrawn   float64 `json:"withdrawn"`
+	Blocked     bool    `json:"blocked"`
+	BlockReason string  `json:"block_reason"`
+}
+
+func getUser(t *testing.T, token, login string) adminUserResponse {
+	t.Helper()
+
+	resp := do(t, http.MethodGet, "/api/admin/users/"+login, token, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	var u adminUserResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &u))
+	return u
+}
+
+func TestAdmin(t *testing.T) {
+	t.Parallel()
+
+	// the admin role is granted on login, not on registration
+	registerToken := registerUser(t, adminLogin, "password")
+	resp := do(t, http.MethodGet, "/api/admin/users/"+adminLogin, registerToken, "", "")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode, resp.Body)
+	adminToken, _ := login(t, adminLogin, "password")
+
+	name := newLogin()
+	token := registerUser(t, name, "password")
+
+	t.Run("forbidden for users", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/admin/users/"+name, token, "", "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp = do(t, http.MethodGet, "/api/admin/users/"+name, "", "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("user lookup", func(t *testing.T) {
+		assert.Equal(t, adminUserResponse{Login: name, Role: "user"}, getUser(t, adminToken, name))
+		assert.Equal(t, "admin", getUser(t, adminToken, adminLogin).Role)
+
+		resp := do(t, http.MethodGet, "/api/admin/users/"+newLogin(), adminToken, "", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("balance adjustment", func(t *testing.T) {
+		path := "/api/admin/users/" + name + "/balance/adjustments"
+
+		resp := do(t, http.MethodPost, path, adminToken, jsonContentType, `{"sum":100}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, resp.Body)
+		resp = do(t, http.MethodPost, path, adminToken, jsonContentType, `{"sum":-1000,"reason":"fraud"}`)
+		assert.Equal(t, http.StatusPaymentRequired, resp.StatusCode, resp.Body)
+
+		resp = do(t, http.MethodPost, path, adminToken, jsonContentType, `{"sum":100,"reason":"compensation"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, resp.Body)
+		assert.Contains(t, resp.Body, fmt.Sprintf(`"created_by":%q`, adminLogin))
+
+		assert.Equal(t, balanceResponse{Current: 100}, getBalance(t, token))
+	})
+
+	t.Run("orders and reprocessing", func(t *testing.T) {
+		// the order is unknown to the accrual system, so it is retried later
+		number := newOrderNumber()
+		resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, number)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+		require.True(t, eventually(t, processTimeout, func() bool {
+			return len(stack.accrual.Requests(number)) > 0
+		}))
+
+		resp = do(t, http.MethodGet, "/api/admin/users/"+name+"/orders", adminToken, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		assert.Contains(t, resp.Body, number)
+		resp = do(t, http.MethodGet, "/api/admin/users/"+name+"/withdrawals", adminToken, "", "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode, resp.Body)
+
+		stack.accrual.Script(number, fake.Processed("10"))
+		resp = do(t, http.MethodPost, "/api/admin/orders/"+number+"/reprocess", adminToken, "", "")
+		require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+
+		processed := eventually(t, processTimeout, func() bool {
+			orders := listOrders(t, token)
+			return len(orders) == 1 && orders[0].Status == "PROCESSED"
+		})
+		require.True(t, processed)
+		assert.Equal(t, balanceResponse{Current: 110}, getBalance(t, token))
+
+		resp = do(t, http.MethodPost, "/api/admin/orders/"+number+"/reprocess", adminToken, "", "")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, resp.Body)
+		resp = do(t, http.MethodPost, "/api/admin/orders/"+newOrderNumber()+"/reprocess", adminToken, "", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, resp.Body)
+	})
+
+	t.Run("block and unblock", func(t *testing.T) {
+		path := "/api/admin/users/" + name
+
+		resp := do(t, http.MethodPost, path+"/block", adminToken, jsonContentType, `{}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, resp.Body)
+
+		resp = do(t, http.MethodPost, path+"/block", adminToken, jsonContentType, `{"reason":"fraud"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		blocked := getUser(t, adminToken, name)
+		assert.True(t, blocked.Blocked)
+		assert.Equal(t, "fraud", blocked.BlockReason)
+
+		// issued tokens stop working and the user cannot log in
+		resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, resp.Body)
+		resp = do(t, http.MethodPost, "/api/user/login", "", jsonContentType,
+			fmt.Sprintf(`{"login":%q,"password":"password"}`, name))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, resp.Body)
+
+		resp = do(t, http.MethodPost, path+"/unblock", adminToken, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		assert.False(t, getUser(t, adminToken, name).Blocked)
+		login(t, name, "password")
+
+		resp = do(t, http.MethodPost, "/api/admin/users/"+newLogin()+"/block", adminToken, jsonContentType, `{"reason":"fraud"}`)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, resp.Body)
+	})
+}
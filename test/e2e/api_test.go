@@ -0,0 +1,327 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	jsonContentType = "application/json"
+	textContentType = "text/plain"
+	processTimeout  = 5 * time.Second
+)
+
+type orderResponse struct {
+	Number     string    `json:"number"`
+	Status     string    `json:"status"`
+	Accrual    float64   `json:"accrual"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+type balanceResponse struct {
+	Current   float64 `json:"current"`
+	Withdrawn float64 `json:"withdrawn"`
+}
+
+type withdrawResponse struct {
+	Order       string    `json:"order"`
+	Sum         float64   `json:"sum"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// registerUser registers a new user and returns its auth token.
+func registerUser(t *testing.T, login, password string) string {
+	t.Helper()
+
+	resp := do(t, http.MethodPost, "/api/user/register", "", jsonContentType,
+		fmt.Sprintf(`{"login":%q,"password":%q}`, login, password))
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+	token := resp.Header.Get(authHeaderKey)
+	require.NotEmpty(t, token)
+
+	return token
+}
+
+func listOrders(t *testing.T, token string) []orderResponse {
+	t.Helper()
+
+	resp := do(t, http.MethodGet, "/api/user/orders", token, "", "")
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	var orders []orderResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &orders))
+	return orders
+}
+
+func getBalance(t *testing.T, token string) balanceResponse {
+	t.Helper()
+
+	resp := do(t, http.MethodGet, "/api/user/balance", token, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	var balance balanceResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &balance))
+	return balance
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	login, password := newLogin(), "secret-password"
+	registerUser(t, login, password)
+
+	t.Run("login already exists", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/user/register", "", jsonContentType,
+			fmt.Sprintf(`{"login":%q,"password":"another"}`, login))
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/user/register", "", jsonContentType, `{"login":""}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp = do(t, http.MethodPost, "/api/user/login", "", textContentType, login)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/user/login", "", jsonContentType,
+			fmt.Sprintf(`{"login":%q,"password":"wrong"}`, login))
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("login", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/user/login", "", jsonContentType,
+			fmt.Sprintf(`{"login":%q,"password":%q}`, login, password))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		token := resp.Header.Get(authHeaderKey)
+		require.NotEmpty(t, token)
+
+		resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		for _, path := range []string{"/api/user/orders", "/api/user/balance", "/api/user/withdrawals"} {
+			resp := do(t, http.MethodGet, path, "", "", "")
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, path)
+			resp = do(t, http.MethodGet, path, "BEARER not-a-token", "", "")
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, path)
+		}
+	})
+}
+
+func TestOrders(t *testing.T) {
+	t.Parallel()
+
+	token := registerUser(t, newLogin(), "password")
+
+	assert.Empty(t, listOrders(t, token))
+
+	processedNumber, invalidNumber := newOrderNumber(), newOrderNumber()
+	stack.accrual.Script(processedNumber, fake.Processed("729.98"))
+	stack.accrual.Script(invalidNumber, fake.Status("INVALID"))
+
+	resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, processedNumber)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+	resp = do(t, http.MethodPost, "/api/user/orders", token, textContentType, invalidNumber)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+
+	t.Run("already uploaded", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, processedNumber)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		anotherToken := registerUser(t, newLogin(), "password")
+		resp = do(t, http.MethodPost, "/api/user/orders", anotherToken, textContentType, processedNumber)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, "12345678901")
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("processing", func(t *testing.T) {
+		var orders []orderResponse
+		processed := eventually(t, processTimeout, func() bool {
+			orders = listOrders(t, token)
+			for _, o := range orders {
+				if o.Status != "PROCESSED" && o.Status != "INVALID" {
+					return false
+				}
+			}
+			return len(orders) == 2
+		})
+		require.True(t, processed, "orders are not processed: %+v", orders)
+
+		// orders are sorted by upload time from the oldest
+		assert.Equal(t, processedNumber, orders[0].Number)
+		assert.Equal(t, "PROCESSED", orders[0].Status)
+		assert.Equal(t, 729.98, orders[0].Accrual)
+		assert.Equal(t, invalidNumber, orders[1].Number)
+		assert.Equal(t, "INVALID", orders[1].Status)
+		assert.False(t, orders[1].UploadedAt.Before(orders[0].UploadedAt))
+
+		assert.Equal(t, balanceResponse{Current: 729.98}, getBalance(t, token))
+	})
+}
+
+func TestWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	token := registerUser(t, newLogin(), "password")
+
+	number := newOrderNumber()
+	stack.accrual.Script(number, fake.Processed("500"))
+	resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, number)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+	require.True(t, eventually(t, processTimeout, func() bool {
+		return getBalance(t, token).Current == 500
+	}), "order accrual is not credited")
+
+	resp = do(t, http.MethodGet, "/api/user/withdrawals", token, "", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	withdrawNumber := newOrderNumber()
+	resp = do(t, http.MethodPost, "/api/user/balance/withdraw", token, jsonContentType,
+		fmt.Sprintf(`{"order":%q,"sum":751}`, withdrawNumber))
+	assert.Equal(t, http.StatusPaymentRequired, resp.StatusCode)
+
+	resp = do(t, http.MethodPost, "/api/user/balance/withdraw", token, jsonContentType,
+		`{"order":"12345678901","sum":1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp = do(t, http.MethodPost, "/api/user/balance/withdraw", token, jsonContentType,
+		fmt.Sprintf(`{"order":%q,"sum":120.5}`, withdrawNumber))
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	assert.Equal(t, balanceResponse{Current: 379.5, Withdrawn: 120.5}, getBalance(t, token))
+
+	// amounts are exact decimals: a sum may be sent as a string and no float rounding errors appear
+	resp = do(t, http.MethodPost, "/api/user/balance/withdraw", token, jsonContentType,
+		fmt.Sprintf(`{"order":%q,"sum":"0.1"}`, newOrderNumber()))
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+	resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+	assert.JSONEq(t, `{"current":379.4,"withdrawn":120.6}`, resp.Body)
+
+	for _, sum := range []string{`true`, `"abc"`, `null`, `0`, `-1`, `1e-100`} {
+		resp = do(t, http.MethodPost, "/api/user/balance/withdraw", token, jsonContentType,
+			fmt.Sprintf(`{"order":%q,"sum":%s}`, newOrderNumber(), sum))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, sum)
+	}
+
+	resp = do(t, http.MethodGet, "/api/user/withdrawals", token, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+	var withdrawals []withdrawResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &withdrawals))
+	require.Len(t, withdrawals, 2)
+	assert.Equal(t, withdrawNumber, withdrawals[0].Order)
+	assert.Equal(t, 120.5, withdrawals[0].Sum)
+	assert.WithinDuration(t, time.Now(), withdrawals[0].ProcessedAt, time.Minute)
+}
+
+func TestHistoryPagination(t *testing.T) {
+	t.Parallel()
+
+	token := registerUser(t, newLogin(), "password")
+
+	numbers := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		number := newOrderNumber()
+		stack.accrual.Script(number, fake.Processed("10"))
+		resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, number)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+		numbers[number] = true
+	}
+	require.True(t, eventually(t, processTimeout, func() bool {
+		return getBalance(t, token).Current == 50
+	}), "order accruals are not credited")
+
+	t.Run("orders by pages", func(t *testing.T) {
+		listed := make(map[string]bool)
+		path := "/api/user/orders?limit=2"
+		for pages := 1; ; pages++ {
+			require.LessOrEqual(t, pages, 3, "too many pages")
+			resp := do(t, http.MethodGet, path, token, "", "")
+			require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+			var orders []orderResponse
+			require.NoError(t, json.Unmarshal([]byte(resp.Body), &orders))
+			assert.LessOrEqual(t, len(orders), 2)
+			for _, o := range orders {
+				assert.False(t, listed[o.Number], "order listed twice: %s", o.Number)
+				listed[o.Number] = true
+			}
+
+			cursor := resp.Header.Get("X-Next-Cursor")
+			if cursor == "" {
+				break
+			}
+			path = "/api/user/orders?limit=2&cursor=" + cursor
+		}
+		assert.Equal(t, numbers, listed)
+	})
+
+	t.Run("orders filters", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/user/orders?status=PROCESSED", token, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		var orders []orderResponse
+		require.NoError(t, json.Unmarshal([]byte(resp.Body), &orders))
+		assert.Len(t, orders, 5)
+
+		resp = do(t, http.MethodGet, "/api/user/orders?status=NEW,PROCESSING", token, "", "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp = do(t, http.MethodGet, "/api/user/orders?from="+time.Now().Add(time.Hour).UTC().Format(time.RFC3339), token, "", "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("withdrawals by pages", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			resp := do(t, http.MethodPost, "/api/user/balance/withdraw", token, jsonContentType,
+				fmt.Sprintf(`{"order":%q,"sum":1}`, newOrderNumber()))
+			require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		}
+
+		resp := do(t, http.MethodGet, "/api/user/withdrawals?limit=2", token, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		var withdrawals []withdrawResponse
+		require.NoError(t, json.Unmarshal([]byte(resp.Body), &withdrawals))
+		assert.Len(t, withdrawals, 2)
+		cursor := resp.Header.Get("X-Next-Cursor")
+		require.NotEmpty(t, cursor)
+
+		resp = do(t, http.MethodGet, "/api/user/withdrawals?limit=2&cursor="+cursor, token, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		require.NoError(t, json.Unmarshal([]byte(resp.Body), &withdrawals))
+		assert.Len(t, withdrawals, 1)
+		assert.Empty(t, resp.Header.Get("X-Next-Cursor"))
+	})
+
+	t.Run("bad query", func(t *testing.T) {
+		for _, path := range []string{
+			"/api/user/orders?limit=0",
+			"/api/user/orders?limit=abc",
+			"/api/user/orders?limit=100000",
+			"/api/user/orders?cursor=not-a-cursor",
+			"/api/user/orders?status=UNKNOWN",
+			"/api/user/orders?from=yesterday",
+			"/api/user/orders?from=2023-05-02T00:00:00Z&to=2023-05-01T00:00:00Z",
+			"/api/user/withdrawals?status=PROCESSED",
+			"/api/user/withdrawals?to=2023-05-01",
+		} {
+			resp := do(t, http.MethodGet, path, token, "", "")
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// withToken returns a context of a call authenticated with the access token.
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func requireCode(t *testing.T, code codes.Code, err error) {
+	t.Helper()
+	require.Equal(t, code, status.Code(err), err)
+}
+
+func TestGRPC(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	login := newLogin()
+	_, err := stack.grpc.Register(ctx, &pb.AuthRequest{Login: login})
+	requireCode(t, codes.InvalidArgument, err)
+
+	auth, err := stack.grpc.Register(ctx, &pb.AuthRequest{Login: login, Password: "password"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", auth.TokenType)
+	assert.NotEmpty(t, auth.RefreshToken)
+
+	_, err = stack.grpc.Register(ctx, &pb.AuthRequest{Login: login, Password: "password"})
+	requireCode(t, codes.AlreadyExists, err)
+	_, err = stack.grpc.Login(ctx, &pb.AuthRequest{Login: login, Password: "wrong"})
+	requireCode(t, codes.Unauthenticated, err)
+
+	auth, err = stack.grpc.Login(ctx, &pb.AuthRequest{Login: login, Password: "password"})
+	require.NoError(t, err)
+	authCtx := withToken(ctx, auth.AccessToken)
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := stack.grpc.GetBalance(ctx, &pb.GetBalanceRequest{})
+		requireCode(t, codes.Unauthenticated, err)
+		_, err = stack.grpc.GetBalance(withToken(ctx, "not a token"), &pb.GetBalanceRequest{})
+		requireCode(t, codes.Unauthenticated, err)
+	})
+
+	number, err := strconv.ParseInt(newOrderNumber(), 10, 64)
+	require.NoError(t, err)
+	stack.accrual.Script(strconv.FormatInt(number, 10), fake.Processed("500"))
+
+	_, err = stack.grpc.UploadOrder(authCtx, &pb.UploadOrderRequest{Number: 12345678901})
+	requireCode(t, codes.InvalidArgument, err)
+	uploaded, err := stack.grpc.UploadOrder(authCtx, &pb.UploadOrderRequest{Number: number})
+	require.NoError(t, err)
+	assert.False(t, uploaded.AlreadyUploaded)
+	uploaded, err = stack.grpc.UploadOrder(authCtx, &pb.UploadOrderRequest{Number: number})
+	require.NoError(t, err)
+	assert.True(t, uploaded.AlreadyUploaded)
+
+	require.True(t, eventually(t, processTimeout, func() bool {
+		balance, err := stack.grpc.GetBalance(authCtx, &pb.GetBalanceRequest{})
+		return err == nil && balance.Current == "500"
+	}), "order accrual is not credited")
+
+	orders, err := stack.grpc.ListOrders(authCtx, &pb.ListOrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, orders.Orders, 1)
+	assert.Equal(t, number, orders.Orders[0].Number)
+	assert.Equal(t, pb.OrderStatus_ORDER_STATUS_PROCESSED, orders.Orders[0].Status)
+	assert.Equal(t, "500", orders.Orders[0].Accrual)
+	assert.WithinDuration(t, time.Now(), orders.Orders[0].UploadedAt.AsTime(), time.Minute)
+
+	withdrawNumber, err := strconv.ParseInt(newOrderNumber(), 10, 64)
+	require.NoError(t, err)
+	_, err = stack.grpc.Withdraw(authCtx, &pb.WithdrawRequest{Order: withdrawNumber, Sum: "500.01"})
+	requireCode(t, codes.FailedPrecondition, err)
+	for _, sum := range []string{"", "abc", "0", "-1", "1e-100"} {
+		_, err = stack.grpc.Withdraw(authCtx, &pb.WithdrawRequest{Order: withdrawNumber, Sum: sum})
+		requireCode(t, codes.InvalidArgument, err)
+	}
+	_, err = stack.grpc.Withdraw(authCtx, &pb.WithdrawRequest{Order: withdrawNumber, Sum: "120.5"})
+	require.NoError(t, err)
+
+	balance, err := stack.grpc.GetBalance(authCtx, &pb.GetBalanceRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "379.5", balance.Current)
+	assert.Equal(t, "120.5", balance.Withdrawn)
+
+	withdrawals, err := stack.grpc.ListWithdrawals(authCtx, &pb.ListWithdrawalsRequest{})
+	require.NoError(t, err)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	assert.Equal(t, withdrawNumber, withdrawals.Withdrawals[0].Order)
+	assert.Equal(t, "120.5", withdrawals.Withdrawals[0].Sum)
+
+	t.Run("refresh and logout", func(t *testing.T) {
+		auth, err := stack.grpc.Login(ctx, &pb.AuthRequest{Login: login, Password: "password"})
+		require.NoError(t, err)
+
+		_, err = stack.grpc.Refresh(ctx, &pb.RefreshRequest{})
+		requireCode(t, codes.InvalidArgument, err)
+		refreshed, err := stack.grpc.Refresh(ctx, &pb.RefreshRequest{RefreshToken: auth.RefreshToken})
+		require.NoError(t, err)
+		assert.NotEqual(t, auth.RefreshToken, refreshed.RefreshToken)
+		refreshedCtx := withToken(ctx, refreshed.AccessToken)
+		_, err = stack.grpc.GetBalance(refreshedCtx, &pb.GetBalanceRequest{})
+		require.NoError(t, err)
+
+		_, err = stack.grpc.Logout(refreshedCtx, &pb.LogoutRequest{})
+		require.NoError(t, err)
+		_, err = stack.grpc.GetBalance(refreshedCtx, &pb.GetBalanceRequest{})
+		requireCode(t, codes.Unauthenticated, err)
+		_, err = stack.grpc.Refresh(ctx, &pb.RefreshRequest{RefreshToken: refreshed.RefreshToken})
+		requireCode(t, codes.Unauthenticated, err)
+
+		// the session of the test is not revoked
+		_, err = stack.grpc.GetBalance(authCtx, &pb.GetBalanceRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("rest token", func(t *testing.T) {
+		// the APIs share the users and the tokens
+		resp := do(t, http.MethodPost, "/api/user/login", "", jsonContentType, `{"login":"`+login+`","password":"password"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		restCtx := metadata.AppendToOutgoingContext(ctx, "authorization", resp.Header.Get(authHeaderKey))
+
+		balance, err := stack.grpc.GetBalance(restCtx, &pb.GetBalanceRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "379.5", balance.Current)
+
+		resp = do(t, http.MethodPost, "/api/user/logout", resp.Header.Get(authHeaderKey), "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		_, err = stack.grpc.GetBalance(restCtx, &pb.GetBalanceRequest{})
+		requireCode(t, codes.Unauthenticated, err)
+	})
+}
@@ -0,0 +1,58 @@
+package e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/migrations"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readinessResponse struct {
+	Status     string `json:"status"`
+	Components []struct {
+		Name     string `json:"name"`
+		Status   string `json:"status"`
+		Critical bool   `json:"critical"`
+		Details  string `json:"details"`
+	} `json:"components"`
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("liveness", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/healthz", "", "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		assert.JSONEq(t, `{"status":"ok"}`, resp.Body)
+	})
+
+	t.Run("readiness", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/readyz", "", "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+		var readiness readinessResponse
+		require.NoError(t, json.Unmarshal([]byte(resp.Body), &readiness))
+		assert.Equal(t, "ready", readiness.Status)
+
+		statuses := make(map[string]string)
+		for _, c := range readiness.Components {
+			statuses[c.Name] = c.Status
+		}
+		for _, name := range []string{"http server", "database", "migrations", "order processing"} {
+			assert.Equal(t, "up", statuses[name], "component %s", name)
+		}
+		assert.Contains(t, statuses, "accrual system")
+
+		version, err := migrations.LatestVersion()
+		require.NoError(t, err)
+		for _, c := range readiness.Components {
+			if c.Name == "migrations" {
+				assert.Contains(t, c.Details, "schema version "+strconv.FormatUint(uint64(version), 10))
+			}
+		}
+	})
+}
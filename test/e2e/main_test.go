@@ -0,0 +1,263 @@
+package e2e
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/loyalty_program/internal/config"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc"
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/delivery/rest"
+	"github.com/Karzoug/loyalty_program/internal/metrics"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	smock "github.com/Karzoug/loyalty_program/internal/repository/storage/mock"
+	"github.com/Karzoug/loyalty_program/internal/service"
+	"github.com/Karzoug/loyalty_program/pkg/luhn"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	authHeaderKey = "Authorization"
+	startTimeout  = 5 * time.Second
+	// adminLogin is a login granted the admin role on login
+	adminLogin = "e2e-admin"
+)
+
+// stack is the whole gophermart application running in-process
+// with the sqlite backed storages and the fake accrual system.
+var stack struct {
+	baseURL    string
+	metricsURL string
+	accrual    *fake.Server
+	client     *http.Client
+	grpc       pb.GophermartClient
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	code, err := run(m)
+	if err != nil {
+		log.Fatalf("Run e2e tests error: %s", err)
+	}
+	os.Exit(code)
+}
+
+func run(m *testing.M) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stack.accrual = fake.NewServer()
+	defer stack.accrual.Close()
+
+	runAddress, err := freeAddress()
+	if err != nil {
+		return 0, err
+	}
+	grpcAddress, err := freeAddress()
+	if err != nil {
+		return 0, err
+	}
+	metricsAddress, err := freeAddress()
+	if err != nil {
+		return 0, err
+	}
+	accrualURL := stack.accrual.URL()
+	envs := map[string]string{
+		"RUN_ADDRESS":                        runAddress,
+		"GRPC_ADDRESS":                       grpcAddress,
+		"METRICS_ADDRESS":                    metricsAddress,
+		"ACCRUAL_SYSTEM_ADDRESS":             accrualURL.String(),
+		"DATABASE_URI":                       "sqlite://memory",
+		"SECRET_KEY":                         "e2e-secret-key",
+		"ACCRUAL_BREAKER_FAILURE_THRESHOLD":  "100",
+		"ACCRUAL_BREAKER_OPEN_TIMEOUT":       "1s",
+		"ACCRUAL_BREAKER_HALF_OPEN_REQUESTS": "1",
+		"MONEY_SCALE":                        "2",
+		"MONEY_ROUNDING":                     "half-up",
+		"ACCESS_TOKEN_LIFETIME":              "15m",
+		"REFRESH_TOKEN_LIFETIME":             "24h",
+		"JWT_ALGORITHM":                      "HS256",
+		"LOGIN_MAX_ATTEMPTS":                 "3",
+		"LOGIN_IP_MAX_ATTEMPTS":              "1000",
+		"LOGIN_LOCKOUT_BASE":                 "1s",
+		"LOGIN_LOCKOUT_MAX":                  "2s",
+		"PASSWORD_HASH":                      "argon2id",
+		"BCRYPT_COST":                        "4",
+		"ARGON2_TIME":                        "1",
+		"ARGON2_MEMORY":                      "64",
+		"ARGON2_THREADS":                     "1",
+		"ADMIN_LOGINS":                       adminLogin,
+		"TRACE_EXPORTER":                     "none",
+		"SHUTDOWN_DELAY":                     "0s",
+		"TRUSTED_PROXIES":                    "",
+		"OPENAPI_VALIDATION":                 "false",
+	}
+	for k, v := range envs {
+		if err := os.Setenv(k, v); err != nil {
+			return 0, err
+		}
+	}
+
+	cfg, err := config.Read()
+	if err != nil {
+		return 0, fmt.Errorf("read config: %w", err)
+	}
+
+	logger := zap.NewNop()
+	if testing.Verbose() {
+		logger, _ = zap.NewDevelopment()
+	}
+
+	storages, err := smock.NewStorages(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("create storages: %w", err)
+	}
+
+	proc := accrual.NewOrderProcessor(cfg, logger)
+	srv := service.New(cfg, storages, proc, logger)
+	server := rest.New(cfg, srv, logger)
+	grpcServer := grpc.New(cfg, srv, logger)
+	metricsServer := metrics.NewServer(cfg.MetricsAddress(), logger)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error { return server.Run(gCtx) })
+	g.Go(func() error { return grpcServer.Run(gCtx) })
+	g.Go(func() error { return metricsServer.Run(gCtx) })
+	g.Go(func() error { return srv.Run(gCtx) })
+
+	stack.baseURL = "http://" + runAddress
+	stack.metricsURL = "http://" + metricsAddress + "/metrics"
+	stack.client = &http.Client{Timeout: 5 * time.Second}
+	if err := waitForServer(stack.baseURL); err != nil {
+		cancel()
+		return 0, err
+	}
+
+	conn, err := grpcgo.Dial(grpcAddress, grpcgo.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		cancel()
+		return 0, fmt.Errorf("dial grpc server: %w", err)
+	}
+	defer conn.Close()
+	stack.grpc = pb.NewGophermartClient(conn)
+
+	code := m.Run()
+
+	cancel()
+	if err := g.Wait(); err != nil {
+		return 0, fmt.Errorf("stop application: %w", err)
+	}
+
+	return code, nil
+}
+
+// freeAddress returns a local address with a port free at the moment.
+func freeAddress() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+func waitForServer(baseURL string) error {
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(baseURL + "/api/user/balance")
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return fmt.Errorf("server %s is not started in %s", baseURL, startTimeout)
+}
+
+// response is a received response with the read body.
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// do sends a request to the application API, token may be empty for anonymous requests.
+func do(t *testing.T, method, path, token, contentType, body string) response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, stack.baseURL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("create request: %s", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set(authHeaderKey, token)
+	}
+
+	resp, err := stack.client.Do(req)
+	if err != nil {
+		t.Fatalf("do request %s %s: %s", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response body: %s", err)
+	}
+
+	return response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(b),
+	}
+}
+
+// eventually polls the condition until it is true or the timeout expires.
+func eventually(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return condition()
+}
+
+var orderNumberSeq atomic.Int64
+
+// newOrderNumber returns a unique valid (by Luhn algorithm) order number.
+func newOrderNumber() string {
+	ds := time.Now().UnixNano()/1000 + orderNumberSeq.Add(1)
+	for d := int64(0); d < 10; d++ {
+		if luhn.Valid(ds*10 + d) {
+			return strconv.FormatInt(ds*10+d, 10)
+		}
+	}
+	panic("unreachable")
+}
+
+// newLogin returns a unique login.
+func newLogin() string {
+	return fmt.Sprintf("user%d", time.Now().UnixNano()+orderNumberSeq.Add(1))
+}
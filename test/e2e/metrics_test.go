@@ -0,0 +1,65 @@
+package e2e
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/internal/delivery/grpc/pb"
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	token := registerUser(t, newLogin(), "password")
+
+	number := newOrderNumber()
+	stack.accrual.Script(number, fake.Processed("10"))
+	resp := do(t, http.MethodPost, "/api/user/orders", token, textContentType, number)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+
+	processed := eventually(t, processTimeout, func() bool {
+		orders := listOrders(t, token)
+		return len(orders) == 1 && orders[0].Status == "PROCESSED"
+	})
+	require.True(t, processed, "order is not processed")
+
+	resp = do(t, http.MethodGet, "/api/admin/users/"+newLogin(), token, "", "")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode, resp.Body)
+
+	_, err := stack.grpc.GetBalance(context.Background(), &pb.GetBalanceRequest{})
+	require.Error(t, err)
+
+	// the metrics are not exposed on the public API listener
+	resp = do(t, http.MethodGet, "/metrics", "", "", "")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, resp.Body)
+
+	mresp, err := stack.client.Get(stack.metricsURL)
+	require.NoError(t, err)
+	defer mresp.Body.Close()
+	require.Equal(t, http.StatusOK, mresp.StatusCode)
+	b, err := io.ReadAll(mresp.Body)
+	require.NoError(t, err)
+	body := string(b)
+
+	for _, line := range []string{
+		`gophermart_http_requests_total{code="202",method="POST",route="/api/user/orders"}`,
+		// requests are labeled by the route pattern, not by the path:
+		// the request is rejected before the admin subrouter matches its route
+		`gophermart_http_requests_total{code="403",method="GET",route="/api/admin/*"}`,
+		`gophermart_http_request_duration_seconds_count{method="POST",route="/api/user/register"}`,
+		`gophermart_grpc_requests_total{code="Unauthenticated",method="/gophermart.v1.Gophermart/GetBalance"}`,
+		`gophermart_grpc_request_duration_seconds_count{method="/gophermart.v1.Gophermart/GetBalance"}`,
+		`gophermart_accrual_requests_total{code="200"}`,
+		`gophermart_accrual_rate_limit `,
+		`gophermart_orders_backlog `,
+		`gophermart_orders_processing_duration_seconds_count `,
+	} {
+		assert.True(t, strings.Contains(body, line), "metrics have no %s", line)
+	}
+}
@@ -0,0 +1,29 @@
+package e2e
+
+import (
+	// go-json corrupts memory decoding the large document into raw messages
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	resp := do(t, http.MethodGet, "/api/openapi.json", "", "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+	assert.Equal(t, jsonContentType, resp.Header.Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	for _, path := range []string{"/api/user/orders", "/api/user/balance/withdraw", "/api/openapi.json"} {
+		assert.Contains(t, doc.Paths, path)
+	}
+}
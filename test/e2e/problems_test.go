@@ -0,0 +1,121 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const problemContentType = "application/problem+json"
+
+type problemResponse struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	RequestID string `json:"request_id"`
+}
+
+func TestProblems(t *testing.T) {
+	t.Parallel()
+
+	token := registerUser(t, newLogin(), "secret-password")
+	anotherToken := registerUser(t, newLogin(), "secret-password")
+
+	number := newOrderNumber()
+	resp := do(t, http.MethodPost, "/api/user/orders", anotherToken, textContentType, number)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, resp.Body)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		token       string
+		contentType string
+		body        string
+		wantStatus  int
+		wantType    string
+	}{
+		{
+			name:   "unauthorized",
+			method: http.MethodGet, path: "/api/user/balance",
+			wantStatus: http.StatusUnauthorized, wantType: "/problems/invalid-auth-data",
+		},
+		{
+			name:   "forbidden",
+			method: http.MethodGet, path: "/api/admin/orders/dead-letters", token: token,
+			wantStatus: http.StatusForbidden, wantType: "/problems/forbidden",
+		},
+		{
+			name:   "unsupported content type",
+			method: http.MethodPost, path: "/api/user/login", contentType: textContentType, body: "login",
+			wantStatus: http.StatusBadRequest, wantType: "/problems/unsupported-content-type",
+		},
+		{
+			name:   "invalid request body",
+			method: http.MethodPost, path: "/api/user/login", contentType: jsonContentType, body: `{"login":`,
+			wantStatus: http.StatusBadRequest, wantType: "/problems/invalid-request-body",
+		},
+		{
+			name:   "empty login",
+			method: http.MethodPost, path: "/api/user/register", contentType: jsonContentType, body: `{"password":"p"}`,
+			wantStatus: http.StatusBadRequest, wantType: "/problems/empty-login",
+		},
+		{
+			name:   "empty order number",
+			method: http.MethodPost, path: "/api/user/orders", token: token, contentType: textContentType,
+			wantStatus: http.StatusBadRequest, wantType: "/problems/empty-order-number",
+		},
+		{
+			name:   "invalid order number",
+			method: http.MethodPost, path: "/api/user/orders", token: token, contentType: textContentType, body: "12345",
+			wantStatus: http.StatusUnprocessableEntity, wantType: "/problems/invalid-order-number",
+		},
+		{
+			name:   "another user order number",
+			method: http.MethodPost, path: "/api/user/orders", token: token, contentType: textContentType, body: number,
+			wantStatus: http.StatusConflict, wantType: "/problems/another-user-order-number",
+		},
+		{
+			name:   "insufficient balance",
+			method: http.MethodPost, path: "/api/user/balance/withdraw", token: token, contentType: jsonContentType,
+			body:       fmt.Sprintf(`{"order":%q,"sum":100}`, newOrderNumber()),
+			wantStatus: http.StatusPaymentRequired, wantType: "/problems/insufficient-balance",
+		},
+		{
+			name:   "invalid query",
+			method: http.MethodGet, path: "/api/user/orders?limit=0", token: token,
+			wantStatus: http.StatusBadRequest, wantType: "/problems/invalid-query",
+		},
+		{
+			name:   "route not found",
+			method: http.MethodGet, path: "/api/unknown",
+			wantStatus: http.StatusNotFound, wantType: "about:blank",
+		},
+		{
+			name:   "method not allowed",
+			method: http.MethodDelete, path: "/api/user/login",
+			wantStatus: http.StatusMethodNotAllowed, wantType: "about:blank",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(t, tt.method, tt.path, tt.token, tt.contentType, tt.body)
+			require.Equal(t, tt.wantStatus, resp.StatusCode, resp.Body)
+			assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+
+			var problem problemResponse
+			require.NoError(t, json.Unmarshal([]byte(resp.Body), &problem), resp.Body)
+			assert.Equal(t, tt.wantType, problem.Type)
+			assert.Equal(t, tt.wantStatus, problem.Status)
+			assert.NotEmpty(t, problem.Title)
+			assert.Equal(t, resp.Header.Get(requestid.Header), problem.RequestID)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package e2e
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Karzoug/loyalty_program/internal/repository/processor/accrual/fake"
+	"github.com/Karzoug/loyalty_program/pkg/requestid"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doWithRequestID sends a request to the application API with the request ID header.
+func doWithRequestID(t *testing.T, method, path, token, contentType, body, id string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, stack.baseURL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set(authHeaderKey, token)
+	}
+	req.Header.Set(requestid.Header, id)
+
+	resp, err := stack.client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generated", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/healthz", "", "", "")
+		assert.True(t, requestid.Valid(resp.Header.Get(requestid.Header)))
+	})
+
+	t.Run("not valid replaced", func(t *testing.T) {
+		resp := doWithRequestID(t, http.MethodGet, "/healthz", "", "", "", strings.Repeat("a", 200))
+		id := resp.Header.Get(requestid.Header)
+		assert.True(t, requestid.Valid(id))
+		assert.NotEqual(t, strings.Repeat("a", 200), id)
+	})
+
+	t.Run("echoed in error response", func(t *testing.T) {
+		resp := doWithRequestID(t, http.MethodPost, "/api/user/login", "", jsonContentType, `{"login":`, "e2e-bad-request")
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "e2e-bad-request", resp.Header.Get(requestid.Header))
+
+		var body struct {
+			RequestID string `json:"request_id"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "e2e-bad-request", body.RequestID)
+	})
+
+	t.Run("forwarded to accrual system", func(t *testing.T) {
+		token := registerUser(t, newLogin(), "password")
+
+		number := newOrderNumber()
+		stack.accrual.Script(number, fake.Processed("10"))
+		id := "e2e-upload-" + number
+		resp := doWithRequestID(t, http.MethodPost, "/api/user/orders", token, textContentType, number, id)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, id, resp.Header.Get(requestid.Header))
+
+		forwarded := eventually(t, processTimeout, func() bool {
+			return len(stack.accrual.Requests(number)) > 0
+		})
+		require.True(t, forwarded, "order is not requested from accrual system")
+		// the background processing keeps the ID of the upload request
+		assert.Equal(t, id, stack.accrual.Requests(number)[0].Header.Get(requestid.Header))
+	})
+}
@@ -0,0 +1,176 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type authResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// login logs in the user and returns the auth header value and the refresh token.
+func login(t *testing.T, login, password string) (string, string) {
+	t.Helper()
+
+	resp := do(t, http.MethodPost, "/api/user/login", "", jsonContentType,
+		fmt.Sprintf(`{"login":%q,"password":%q}`, login, password))
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+	return parseAuthResponse(t, resp)
+}
+
+func refresh(t *testing.T, refreshToken string) response {
+	t.Helper()
+
+	return do(t, http.MethodPost, "/api/user/token/refresh", "", jsonContentType,
+		fmt.Sprintf(`{"refresh_token":%q}`, refreshToken))
+}
+
+func parseAuthResponse(t *testing.T, resp response) (string, string) {
+	t.Helper()
+
+	var auth authResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &auth))
+	require.NotEmpty(t, auth.AccessToken)
+	require.NotEmpty(t, auth.RefreshToken)
+	assert.Equal(t, "Bearer", auth.TokenType)
+	assert.Positive(t, auth.ExpiresIn)
+	assert.Equal(t, "BEARER "+auth.AccessToken, resp.Header.Get(authHeaderKey))
+
+	return resp.Header.Get(authHeaderKey), auth.RefreshToken
+}
+
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	name, password := newLogin(), "password"
+	registerUser(t, name, password)
+
+	t.Run("refresh", func(t *testing.T) {
+		_, refreshToken := login(t, name, password)
+
+		resp := refresh(t, refreshToken)
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		token, newRefreshToken := parseAuthResponse(t, resp)
+		assert.NotEqual(t, refreshToken, newRefreshToken)
+
+		resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// the rotated refresh token is reused: it is stolen, so the session is revoked
+		resp = refresh(t, refreshToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp = refresh(t, newRefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("bad refresh request", func(t *testing.T) {
+		resp := refresh(t, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp = refresh(t, "not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		token, refreshToken := login(t, name, password)
+		anotherToken, _ := login(t, name, password)
+
+		resp := do(t, http.MethodPost, "/api/user/logout", token, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+		resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp = refresh(t, refreshToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		// other sessions are alive
+		resp = do(t, http.MethodGet, "/api/user/balance", anotherToken, "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("logout all sessions", func(t *testing.T) {
+		token, _ := login(t, name, password)
+		anotherToken, anotherRefreshToken := login(t, name, password)
+
+		resp := do(t, http.MethodPost, "/api/user/logout?all=true", token, "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+		for _, token := range []string{token, anotherToken} {
+			resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+		resp = refresh(t, anotherRefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("jwks", func(t *testing.T) {
+		// the stack signs tokens with HS256, its secret is never published
+		resp := do(t, http.MethodGet, "/.well-known/jwks.json", "", "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+		assert.JSONEq(t, `{"keys":[]}`, resp.Body)
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	t.Parallel()
+
+	name, password := newLogin(), "password"
+	registerUser(t, name, password)
+
+	wrong := fmt.Sprintf(`{"login":%q,"password":"wrong"}`, name)
+	for i := 0; i < 3; i++ {
+		resp := do(t, http.MethodPost, "/api/user/login", "", jsonContentType, wrong)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode, resp.Body)
+	}
+
+	// the right password is rejected too while the login is locked out
+	resp := do(t, http.MethodPost, "/api/user/login", "", jsonContentType,
+		fmt.Sprintf(`{"login":%q,"password":%q}`, name, password))
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode, resp.Body)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	time.Sleep(time.Second)
+	login(t, name, password)
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+
+	name, password := newLogin(), "password"
+	registerUser(t, name, password)
+	token, _ := login(t, name, password)
+
+	resp := do(t, http.MethodPost, "/api/user/password", token, jsonContentType,
+		`{"old_password":"wrong","new_password":"new password"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, resp.Body)
+	resp = do(t, http.MethodPost, "/api/user/password", token, jsonContentType,
+		`{"old_password":"password"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, resp.Body)
+	resp = do(t, http.MethodPost, "/api/user/password", "", jsonContentType,
+		`{"old_password":"password","new_password":"new password"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, resp.Body)
+
+	otherToken, _ := login(t, name, password)
+	resp = do(t, http.MethodPost, "/api/user/password", token, jsonContentType,
+		`{"old_password":"password","new_password":"new password"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	// the other sessions are revoked, the current one is kept
+	resp = do(t, http.MethodGet, "/api/user/balance", otherToken, "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, resp.Body)
+	resp = do(t, http.MethodGet, "/api/user/balance", token, "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	login(t, name, "new password")
+}